@@ -23,8 +23,11 @@ func main() {
 	r.Handle("/play", middleware.AuthMiddleware(http.HandlerFunc(handlers.NewPlay))).Methods(http.MethodPost)
 	r.Handle("/play", middleware.AuthMiddleware(http.HandlerFunc(handlers.AllPlay))).Methods(http.MethodGet)
 	r.Handle("/play/{id}", middleware.AuthMiddleware(http.HandlerFunc(handlers.GetGameId))).Methods(http.MethodGet)
+	r.Handle("/play/{id}/start", middleware.AuthMiddleware(http.HandlerFunc(handlers.StartGame))).Methods(http.MethodPost)
+	r.Handle("/play/{id}/bot", middleware.AuthMiddleware(http.HandlerFunc(handlers.AddBot))).Methods(http.MethodPost)
 	r.Handle("/play/{id}/end", middleware.AuthMiddleware(http.HandlerFunc(handlers.EndGame))).Methods(http.MethodPost)
 	r.Handle("/enter-game", middleware.AuthMiddleware(http.HandlerFunc(handlers.EnterGame))).Methods(http.MethodPost)
+	r.Handle("/watch-game", middleware.AuthMiddleware(http.HandlerFunc(handlers.WatchGame))).Methods(http.MethodPost)
 
 	log.Sugar().Infof("Serving on %s", addr)
 	handler := cors.AllowAll().Handler(r)
@@ -17,7 +17,7 @@ func InitDb(dsn string) (*gorm.DB, error) {
 	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 
 	if err == nil {
-		db.AutoMigrate(&User{}, &Game{})
+		db.AutoMigrate(&User{}, &Game{}, &Spectator{})
 	}
 
 	return db, err
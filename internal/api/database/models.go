@@ -1,29 +1,84 @@
 package database
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type User struct {
 	ID        int       `json:"id"`
 	Username  string    `json:"username"`
 	Password  string    `json:"password"`
+	Rating    int       `gorm:"default:1500" json:"rating"`
+	Bot       bool      `json:"bot"` // The built-in computer player, which can't log in
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Username of the built-in computer player
+const BotUsername = "computer"
+
 type GameType string
 
 const (
 	SingleGameType GameType = "single"
 	PairGameType   GameType = "pair"
+
+	// Two teams of two players on two linked boards
+	BughouseGameType GameType = "bughouse"
 )
 
+// Returns true if the game is played by two teams of two players
+func (t GameType) IsTeam() bool {
+	return t == PairGameType || t == BughouseGameType
+}
+
 type MoveChooseType string
 
 const (
 	SequentialChooseType MoveChooseType = "sequential"
 	RandomChooseType     MoveChooseType = "random"
+
+	// Both teammates vote the move, the first player of the team plays it
+	ConsultationChooseType MoveChooseType = "consultation"
+
+	// The player of each move is picked at random, weighted by rating
+	WeightedChooseType MoveChooseType = "weighted"
+
+	// The first player of the team decides who plays each move
+	CaptainChooseType MoveChooseType = "captain"
 )
 
+// The players of a team take turns every `n` moves of the team
+func AlternateChooseType(n int) MoveChooseType {
+	return MoveChooseType(fmt.Sprintf("alternate-%d", n))
+}
+
+// Time control of a game, in seconds. A game without base time is untimed.
+type TimeControl struct {
+	Base      int `json:"base"`
+	Increment int `json:"increment"`
+	Delay     int `json:"delay"`
+}
+
+func (tc TimeControl) IsTimed() bool {
+	return tc.Base > 0
+}
+
+// Returns the time control as minutes+increment, plus the delay if any
+func (tc TimeControl) String() string {
+	if !tc.IsTimed() {
+		return "untimed"
+	}
+
+	s := fmt.Sprintf("%g+%d", float64(tc.Base)/60, tc.Increment)
+	if tc.Delay > 0 {
+		s += fmt.Sprintf(" d%d", tc.Delay)
+	}
+
+	return s
+}
+
 type Game struct {
 	ID         int            `json:"id"`
 	Type       GameType       `json:"type"`
@@ -36,13 +91,34 @@ type Game struct {
 	Player3    *User          `gorm:"foreignKey:Player3ID;null" json:"player3"`
 	Player4ID  *int           `json:"-"`
 	Player4    *User          `gorm:"foreignKey:Player4ID;null" json:"player4"`
+	Rating1    int            `json:"rating1"` // Ratings of the players when they took their seats
+	Rating2    int            `json:"rating2"`
+	Rating3    int            `json:"rating3"`
+	Rating4    int            `json:"rating4"`
 	Name       string         `json:"name"`
 	IP1        string         `json:"ip1"`
 	IP2        string         `json:"ip2"`
 	IP3        string         `json:"ip3"`
 	IP4        string         `json:"ip4"`
 	Outcome    string         `json:"outcome"`
+	Method     string         `json:"method"`      // How the outcome has been reached
 	LastPlayer int            `json:"last_player"` // Last player entered in game
+	Clock      TimeControl    `gorm:"embedded;embeddedPrefix:clock_" json:"time_control"`
+	StartFEN   string         `json:"start_fen"` // Empty for the standard starting position
+	Chess960   bool           `json:"chess960"`  // The starting position is drawn among the Chess960 ones
+	Moves      string         `json:"moves"`     // Moves played in UCI, separated by spaces, once the game is over
+	Spectators []Spectator    `gorm:"foreignKey:GameID" json:"spectators"`
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 }
+
+// A user watching a game without playing it
+type Spectator struct {
+	ID        int       `json:"id"`
+	GameID    int       `json:"-"`
+	UserID    int       `json:"-"`
+	User      User      `gorm:"foreignKey:UserID" json:"user"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
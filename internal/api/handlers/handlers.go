@@ -9,8 +9,12 @@ import (
 	"github.com/boozec/rahanna/internal/api/auth"
 	"github.com/boozec/rahanna/internal/api/database"
 	"github.com/boozec/rahanna/internal/logger"
+	"github.com/boozec/rahanna/pkg/chess960"
 	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
 	"github.com/gorilla/mux"
+	"github.com/notnil/chess"
+	"gorm.io/gorm"
 )
 
 type NewGameRequest struct {
@@ -33,6 +37,14 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.Username == database.BotUsername {
+		JsonError(&w, "this username is reserved")
+		return
+	}
+
+	// The bot flag can't be set from the registration
+	user.Bot = false
+
 	var storedUser database.User
 	db, _ := database.GetDb()
 	if result := db.Where("username = ?", user.Username).First(&storedUser); result.Error == nil {
@@ -105,9 +117,12 @@ func NewPlay(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var payload struct {
-		IP         string                  `json:"ip"`
-		Type       database.GameType       `json:"type"`
-		MoveChoose database.MoveChooseType `json:"move_choose_type"`
+		IP          string                  `json:"ip"`
+		Type        database.GameType       `json:"type"`
+		MoveChoose  database.MoveChooseType `json:"move_choose_type"`
+		TimeControl database.TimeControl    `json:"time_control"`
+		StartFEN    string                  `json:"start_fen"`
+		Chess960    bool                    `json:"chess960"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
@@ -115,8 +130,46 @@ func NewPlay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if tc := payload.TimeControl; tc.Base < 0 || tc.Increment < 0 || tc.Delay < 0 || !tc.IsTimed() && tc != (database.TimeControl{}) {
+		JsonError(&w, "invalid time control")
+		return
+	}
+
+	if _, err := multiplayer.NewStrategy(string(payload.MoveChoose)); err != nil {
+		JsonError(&w, err.Error())
+		return
+	}
+
+	if payload.Type == database.BughouseGameType && payload.TimeControl.IsTimed() {
+		JsonError(&w, "time controls are not supported in bughouse")
+		return
+	}
+
+	if payload.StartFEN != "" {
+		if _, err := chess960.NewGame(payload.StartFEN); err != nil {
+			JsonError(&w, err.Error())
+			return
+		}
+	}
+
+	if payload.StartFEN != "" && payload.Chess960 {
+		JsonError(&w, "a Chess960 game can't have a custom starting position")
+		return
+	}
+
+	if payload.Type == database.BughouseGameType && (payload.StartFEN != "" || payload.Chess960) {
+		JsonError(&w, "bughouse games start from the standard position")
+		return
+	}
+
 	db, _ := database.GetDb()
 
+	rating, err := userRating(db, claims.UserID)
+	if err != nil {
+		JsonError(&w, err.Error())
+		return
+	}
+
 	var name string
 	for {
 		name = p2p.NewSession()
@@ -129,10 +182,14 @@ func NewPlay(w http.ResponseWriter, r *http.Request) {
 		Type:       payload.Type,
 		MoveChoose: payload.MoveChoose,
 		Player1ID:  claims.UserID,
+		Rating1:    rating,
 		Name:       name,
 		IP1:        payload.IP,
 		Outcome:    "*",
 		LastPlayer: 1,
+		Clock:      payload.TimeControl,
+		StartFEN:   payload.StartFEN,
+		Chess960:   payload.Chess960,
 	}
 
 	if result := db.Create(&play); result.Error != nil {
@@ -174,10 +231,17 @@ func EnterGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	rating, err := userRating(db, claims.UserID)
+	if err != nil {
+		JsonError(&w, err.Error())
+		return
+	}
+
 	switch game.Type {
 	case database.SingleGameType:
 		if game.Player2ID == nil {
 			game.Player2ID = &claims.UserID
+			game.Rating2 = rating
 			game.IP2 = payload.IP
 			game.LastPlayer = 2
 		} else {
@@ -191,17 +255,20 @@ func EnterGame(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-	case database.PairGameType:
+	case database.PairGameType, database.BughouseGameType:
 		if game.Player2ID == nil {
 			game.Player2ID = &claims.UserID
+			game.Rating2 = rating
 			game.IP2 = payload.IP
 			game.LastPlayer = 2
 		} else if game.Player3ID == nil {
 			game.Player3ID = &claims.UserID
+			game.Rating3 = rating
 			game.IP3 = payload.IP
 			game.LastPlayer = 3
 		} else if game.Player4ID == nil {
 			game.Player4ID = &claims.UserID
+			game.Rating4 = rating
 			game.IP4 = payload.IP
 			game.LastPlayer = 4
 		} else {
@@ -247,6 +314,76 @@ func EnterGame(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(game)
 }
 
+func WatchGame(w http.ResponseWriter, r *http.Request) {
+	log, _ := logger.GetLogger()
+	log.Info("POST /watch-game")
+
+	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	if !ok {
+		JsonError(&w, "claims not found")
+		return
+	}
+
+	var payload struct {
+		Name string `json:"name"`
+		IP   string `json:"ip"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		JsonError(&w, err.Error())
+		return
+	}
+
+	db, _ := database.GetDb()
+
+	var game database.Game
+
+	if result := db.Where("name = ? AND outcome = '*'", payload.Name).First(&game); result.Error != nil {
+		JsonError(&w, result.Error.Error())
+		return
+	}
+
+	if game.Player1ID == claims.UserID ||
+		game.Player2ID != nil && *game.Player2ID == claims.UserID ||
+		game.Player3ID != nil && *game.Player3ID == claims.UserID ||
+		game.Player4ID != nil && *game.Player4ID == claims.UserID {
+		JsonError(&w, "you are a player of this game")
+		return
+	}
+
+	var spectator database.Spectator
+
+	if result := db.Where("game_id = ? AND user_id = ?", game.ID, claims.UserID).First(&spectator); result.Error != nil {
+		spectator = database.Spectator{
+			GameID: game.ID,
+			UserID: claims.UserID,
+		}
+	}
+
+	spectator.IP = payload.IP
+	spectator.UpdatedAt = time.Now()
+
+	if err := db.Save(&spectator).Error; err != nil {
+		JsonError(&w, err.Error())
+		return
+	}
+
+	result := db.Where("id = ?", game.ID).
+		Preload("Player1", auth.OmitPassword).
+		Preload("Player2", auth.OmitPassword).
+		Preload("Player3", auth.OmitPassword).
+		Preload("Player4", auth.OmitPassword).
+		Preload("Spectators.User", auth.OmitPassword).
+		First(&game)
+
+	if result.Error != nil {
+		JsonError(&w, result.Error.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(game)
+}
+
 func AllPlay(w http.ResponseWriter, r *http.Request) {
 	log, _ := logger.GetLogger()
 	log.Info("GET /play")
@@ -291,12 +428,13 @@ func GetGameId(w http.ResponseWriter, r *http.Request) {
 	db, _ := database.GetDb()
 	var game database.Game
 
-	if result := db.Where("id = ? AND (player1_id = ? OR player2_id = ? OR player3_id = ? OR player4_id = ?)",
-		id, claims.UserID, claims.UserID, claims.UserID, claims.UserID).
+	if result := db.Where("id = ? AND (player1_id = ? OR player2_id = ? OR player3_id = ? OR player4_id = ? OR id IN (SELECT game_id FROM spectators WHERE user_id = ?))",
+		id, claims.UserID, claims.UserID, claims.UserID, claims.UserID, claims.UserID).
 		Preload("Player1", auth.OmitPassword).
 		Preload("Player2", auth.OmitPassword).
 		Preload("Player3", auth.OmitPassword).
 		Preload("Player4", auth.OmitPassword).
+		Preload("Spectators.User", auth.OmitPassword).
 		First(&game); result.Error != nil {
 		JsonError(&w, result.Error.Error())
 		return
@@ -319,6 +457,8 @@ func EndGame(w http.ResponseWriter, r *http.Request) {
 
 	var payload struct {
 		Outcome string `json:"outcome"`
+		Method  string `json:"method"`
+		Moves   string `json:"moves"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
@@ -326,6 +466,13 @@ func EndGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	switch chess.Outcome(payload.Outcome) {
+	case chess.WhiteWon, chess.BlackWon, chess.Draw:
+	default:
+		JsonError(&w, fmt.Sprintf("invalid outcome `%s`", payload.Outcome))
+		return
+	}
+
 	db, _ := database.GetDb()
 
 	var game database.Game
@@ -339,14 +486,204 @@ func EndGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	game.Outcome = payload.Outcome
+	// The moves reported are stored only if they can be replayed
+	if payload.Moves != "" {
+		if _, err := multiplayer.ReplayMoves(game.StartFEN, payload.Moves); err != nil {
+			JsonError(&w, err.Error())
+			return
+		}
+	}
+
+	// Every player reports the end: the first report sets the outcome, the
+	// later ones can only agree with it
+	end := map[string]any{"outcome": payload.Outcome, "method": payload.Method}
+	if payload.Moves != "" {
+		end["moves"] = payload.Moves
+	}
+
+	claimed := db.Model(&database.Game{}).
+		Where("id = ? AND (outcome = '' OR outcome = '*')", game.ID).
+		Updates(end)
+	if claimed.Error != nil {
+		JsonError(&w, claimed.Error.Error())
+		return
+	}
+
+	if claimed.RowsAffected == 0 {
+		if err := db.First(&game, game.ID).Error; err != nil {
+			JsonError(&w, err.Error())
+			return
+		}
+
+		if game.Outcome != payload.Outcome {
+			JsonErrorStatus(&w, http.StatusConflict, fmt.Sprintf("the game already ended with %s", game.Outcome))
+			return
+		}
+	}
+
+	result := db.Where("id = ?", game.ID).
+		Preload("Player1", auth.OmitPassword).
+		Preload("Player2", auth.OmitPassword).
+		Preload("Player3", auth.OmitPassword).
+		Preload("Player4", auth.OmitPassword).
+		First(&game)
+
+	if result.Error != nil {
+		JsonError(&w, result.Error.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(game)
+}
+
+// Returns the rating of the user `id`, which stays the same in the seat it
+// takes
+func userRating(db *gorm.DB, id int) (int, error) {
+	var user database.User
+	if err := db.First(&user, id).Error; err != nil {
+		return 0, err
+	}
+
+	return user.Rating, nil
+}
+
+// Sets the Chess960 starting position drawn by the players, before the first
+// move
+func StartGame(w http.ResponseWriter, r *http.Request) {
+	log, _ := logger.GetLogger()
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Info(fmt.Sprintf("POST /play/%s/start", id))
+
+	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	if !ok {
+		JsonError(&w, "claims not found")
+		return
+	}
+
+	var payload struct {
+		StartFEN string `json:"start_fen"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		JsonError(&w, err.Error())
+		return
+	}
+
+	if _, ok := chess960.Index(payload.StartFEN); !ok {
+		JsonError(&w, "not a Chess960 starting position")
+		return
+	}
+
+	db, _ := database.GetDb()
+
+	var game database.Game
+
+	if result := db.Where("id = ? AND player1_id = ?", id, claims.UserID).First(&game); result.Error != nil {
+		JsonError(&w, result.Error.Error())
+		return
+	}
+
+	if !game.Chess960 {
+		JsonError(&w, "not a Chess960 game")
+		return
+	}
+
+	// Every peer draws the same position, so it can't change once set
+	if game.StartFEN != "" && game.StartFEN != payload.StartFEN {
+		JsonError(&w, "the starting position is already set")
+		return
+	}
+
+	game.StartFEN = payload.StartFEN
+
+	if err := db.Save(&game).Error; err != nil {
+		JsonError(&w, err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(game)
+}
+
+// Seats the built-in computer player in the next free seat of a game. The
+// engine runs on the machine of the first player, at the given address.
+func AddBot(w http.ResponseWriter, r *http.Request) {
+	log, _ := logger.GetLogger()
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Info(fmt.Sprintf("POST /play/%s/bot", id))
+
+	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	if !ok {
+		JsonError(&w, "claims not found")
+		return
+	}
+
+	var payload NewGameRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		JsonError(&w, err.Error())
+		return
+	}
+
+	db, _ := database.GetDb()
+
+	var game database.Game
+
+	if result := db.Where("id = ? AND player1_id = ?", id, claims.UserID).First(&game); result.Error != nil {
+		JsonError(&w, result.Error.Error())
+		return
+	}
+
+	switch {
+	case game.Type == database.BughouseGameType:
+		JsonError(&w, "the computer can't play bughouse")
+		return
+	case game.MoveChoose == database.ConsultationChooseType:
+		JsonError(&w, "the computer can't vote moves")
+		return
+	case game.Outcome != "" && game.Outcome != "*":
+		JsonError(&w, "the game is over")
+		return
+	}
+
+	var bot database.User
+
+	result := db.Where("bot = ?", true).Attrs(database.User{Username: database.BotUsername}).FirstOrCreate(&bot)
+	if result.Error != nil {
+		JsonError(&w, result.Error.Error())
+		return
+	}
+
+	switch {
+	case game.Player2ID == nil:
+		game.Player2ID = &bot.ID
+		game.Rating2 = bot.Rating
+		game.IP2 = payload.IP
+		game.LastPlayer = 2
+	case game.Type.IsTeam() && game.Player3ID == nil:
+		game.Player3ID = &bot.ID
+		game.Rating3 = bot.Rating
+		game.IP3 = payload.IP
+		game.LastPlayer = 3
+	case game.Type.IsTeam() && game.Player4ID == nil:
+		game.Player4ID = &bot.ID
+		game.Rating4 = bot.Rating
+		game.IP4 = payload.IP
+		game.LastPlayer = 4
+	default:
+		JsonError(&w, "the game is full")
+		return
+	}
+
+	game.UpdatedAt = time.Now()
 
 	if err := db.Save(&game).Error; err != nil {
 		JsonError(&w, err.Error())
 		return
 	}
 
-	result := db.Where("id = ?", game.ID).
+	result = db.Where("id = ?", game.ID).
 		Preload("Player1", auth.OmitPassword).
 		Preload("Player2", auth.OmitPassword).
 		Preload("Player3", auth.OmitPassword).
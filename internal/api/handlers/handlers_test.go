@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boozec/rahanna/internal/api/auth"
+	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/internal/logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// Sends the end report `payload` of the game `id` as the user `userID`
+func endGame(id int, userID int, payload map[string]string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+
+	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/play/%d/end", id), bytes.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"id": fmt.Sprint(id)})
+	r = r.WithContext(context.WithValue(r.Context(), "claims", &auth.Claims{UserID: userID}))
+
+	w := httptest.NewRecorder()
+	EndGame(w, r)
+
+	return w
+}
+
+// Opens the database of the tests from TEST_DATABASE_URL, and skips the test
+// without it
+func testDb(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	_, err := database.InitDb(dsn)
+	assert.NoError(t, err)
+}
+
+// TestEndGameInvalidOutcome tests that a game can't end without a result.
+func TestEndGameInvalidOutcome(t *testing.T) {
+	logger.InitLogger(filepath.Join(t.TempDir(), "api.log"), true)
+
+	for _, outcome := range []string{"", "*", "2-0"} {
+		w := endGame(1, 1, map[string]string{"outcome": outcome})
+		assert.Equal(t, http.StatusBadRequest, w.Code, outcome)
+	}
+}
+
+// TestEndGameReports tests that the first report ends the game, that the later
+// ones can't change it and that a different outcome is a conflict.
+func TestEndGameReports(t *testing.T) {
+	logger.InitLogger(filepath.Join(t.TempDir(), "api.log"), true)
+	testDb(t)
+
+	db, _ := database.GetDb()
+
+	white := database.User{Username: "test-end-white"}
+	black := database.User{Username: "test-end-black"}
+	assert.NoError(t, db.Create(&white).Error)
+	assert.NoError(t, db.Create(&black).Error)
+
+	game := database.Game{Type: database.SingleGameType, Player1ID: white.ID, Player2ID: &black.ID, Name: "test-end", Outcome: "*"}
+	assert.NoError(t, db.Create(&game).Error)
+
+	t.Cleanup(func() {
+		db.Delete(&game)
+		db.Delete(&white)
+		db.Delete(&black)
+	})
+
+	stored := func() database.Game {
+		var stored database.Game
+		assert.NoError(t, db.First(&stored, game.ID).Error)
+		return stored
+	}
+
+	// Moves which can't be replayed are refused
+	w := endGame(game.ID, white.ID, map[string]string{"outcome": "0-1", "method": "Checkmate", "moves": "f2f3 e7e5 e2e5"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "*", stored().Outcome)
+
+	moves := "f2f3 e7e5 g2g4 d8h4"
+	w = endGame(game.ID, white.ID, map[string]string{"outcome": "0-1", "method": "Checkmate", "moves": moves})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The same outcome is accepted but doesn't change what was stored
+	w = endGame(game.ID, black.ID, map[string]string{"outcome": "0-1", "method": "Resignation", "moves": "f2f3"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Checkmate", stored().Method)
+	assert.Equal(t, moves, stored().Moves)
+
+	w = endGame(game.ID, black.ID, map[string]string{"outcome": "1-0", "method": "Resignation"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "0-1", stored().Outcome)
+}
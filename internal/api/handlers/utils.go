@@ -18,10 +18,15 @@ func CheckPasswordHash(hash, password string) error {
 
 // Set a JSON response with status code 400
 func JsonError(w *http.ResponseWriter, error string) {
+	JsonErrorStatus(w, http.StatusBadRequest, error)
+}
+
+// Set a JSON response with the error status code `status`
+func JsonErrorStatus(w *http.ResponseWriter, status int, error string) {
 	payloadMap := map[string]string{"error": error}
 
 	(*w).Header().Set("Content-Type", "application/json")
-	(*w).WriteHeader(http.StatusBadRequest)
+	(*w).WriteHeader(status)
 
 	payload, err := json.Marshal(payloadMap)
 
@@ -0,0 +1,294 @@
+package bughouse
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/boozec/rahanna/pkg/rules"
+	"github.com/notnil/chess"
+)
+
+// Pieces a player can drop on the board, by type
+type Pocket map[chess.PieceType]int
+
+// Letters of the pieces in the drop notation, such as `N@f3`
+var dropLetters = map[chess.PieceType]string{
+	chess.Queen:  "Q",
+	chess.Rook:   "R",
+	chess.Bishop: "B",
+	chess.Knight: "N",
+	chess.Pawn:   "P",
+}
+
+// Pieces which can be dropped, in the order they are listed
+var droppable = []chess.PieceType{chess.Queen, chess.Rook, chess.Bishop, chess.Knight, chess.Pawn}
+
+// Board is a chess board where the player to move can drop a piece of its
+// pocket instead of moving one. Moves use the UCI notation, drops the `N@f3`
+// notation.
+type Board struct {
+	position *chess.Position
+	pockets  map[chess.Color]Pocket
+	promoted map[chess.Square]bool
+	moves    []string
+	notation []string
+}
+
+func NewBoard() *Board {
+	return &Board{
+		position: chess.StartingPosition(),
+		pockets:  map[chess.Color]Pocket{chess.White: {}, chess.Black: {}},
+		promoted: make(map[chess.Square]bool),
+	}
+}
+
+func (b *Board) Position() *chess.Position {
+	return b.position
+}
+
+func (b *Board) Turn() chess.Color {
+	return b.position.Turn()
+}
+
+// Returns the moves played on the board
+func (b *Board) Moves() []string {
+	return slices.Clone(b.moves)
+}
+
+// Returns the moves played on the board in SAN
+func (b *Board) Notation() []string {
+	return slices.Clone(b.notation)
+}
+
+// Returns a copy of the pocket of `color`
+func (b *Board) Pocket(color chess.Color) Pocket {
+	pocket := make(Pocket)
+	for pieceType, count := range b.pockets[color] {
+		if count > 0 {
+			pocket[pieceType] = count
+		}
+	}
+
+	return pocket
+}
+
+// Gives `piece` to the player of its color
+func (b *Board) AddToPocket(piece chess.Piece) {
+	if piece == chess.NoPiece || piece.Type() == chess.King {
+		return
+	}
+	b.pockets[piece.Color()][piece.Type()]++
+}
+
+// Returns the legal moves and drops of the player to move
+func (b *Board) ValidMoves() []string {
+	var moves []string
+	for _, move := range b.position.ValidMoves() {
+		moves = append(moves, move.String())
+	}
+
+	squares := b.position.Board().SquareMap()
+	turn := b.Turn()
+	for _, pieceType := range droppable {
+		if b.pockets[turn][pieceType] == 0 {
+			continue
+		}
+
+		for sq := chess.A1; sq <= chess.H8; sq++ {
+			if b.canDrop(squares, chess.NewPiece(pieceType, turn), sq) {
+				moves = append(moves, dropLetters[pieceType]+"@"+sq.String())
+			}
+		}
+	}
+
+	return moves
+}
+
+// Returns true if `piece` can be dropped on `sq`
+func (b *Board) canDrop(squares map[chess.Square]chess.Piece, piece chess.Piece, sq chess.Square) bool {
+	if _, occupied := squares[sq]; occupied {
+		return false
+	}
+
+	if piece.Type() == chess.Pawn && (sq.Rank() == chess.Rank1 || sq.Rank() == chess.Rank8) {
+		return false
+	}
+
+	// A drop can't discover a check, but it must block the current one
+	squares[sq] = piece
+	defer delete(squares, sq)
+
+	return !rules.InCheck(squares, piece.Color())
+}
+
+// Returns a copy of the board
+func (b *Board) clone() *Board {
+	return &Board{
+		position: b.position,
+		pockets:  map[chess.Color]Pocket{chess.White: maps.Clone(b.pockets[chess.White]), chess.Black: maps.Clone(b.pockets[chess.Black])},
+		promoted: maps.Clone(b.promoted),
+		moves:    slices.Clone(b.moves),
+		notation: slices.Clone(b.notation),
+	}
+}
+
+// Returns the legal move `move` of the player to move, nil if there is none
+func (b *Board) findMove(move string) *chess.Move {
+	for _, valid := range b.position.ValidMoves() {
+		if valid.String() == move {
+			return valid
+		}
+	}
+
+	return nil
+}
+
+// Returns `move` in SAN, as `Nxf3+`, or as it is if it is illegal. Drops keep
+// their notation, as `N@f3`. A check is a mate only if no drop can block it.
+func (b *Board) SAN(move string) string {
+	san := move
+	if !strings.Contains(move, "@") {
+		found := b.findMove(move)
+		if found == nil {
+			return move
+		}
+		san = strings.TrimRight(chess.AlgebraicNotation{}.Encode(b.position, found), "+#")
+	}
+
+	next := b.clone()
+	if _, err := next.play(move); err != nil {
+		return move
+	}
+
+	if !rules.InCheck(next.position.Board().SquareMap(), next.Turn()) {
+		return san
+	}
+	if next.Status() == chess.Checkmate {
+		return san + "#"
+	}
+	return san + "+"
+}
+
+// Plays `move` and returns the captured piece, if any. A captured piece which
+// was promoted goes back to be a pawn.
+func (b *Board) Move(move string) (chess.Piece, error) {
+	san := b.SAN(move)
+
+	captured, err := b.play(move)
+	if err != nil {
+		return chess.NoPiece, err
+	}
+	b.notation = append(b.notation, san)
+
+	return captured, nil
+}
+
+func (b *Board) play(move string) (chess.Piece, error) {
+	if strings.Contains(move, "@") {
+		return chess.NoPiece, b.drop(move)
+	}
+
+	found := b.findMove(move)
+	if found == nil {
+		return chess.NoPiece, fmt.Errorf("illegal move `%s`", move)
+	}
+
+	captured := b.position.Board().Piece(found.S2())
+	if found.HasTag(chess.EnPassant) {
+		captured = chess.NewPiece(chess.Pawn, b.Turn().Other())
+	}
+
+	if captured != chess.NoPiece && b.promoted[found.S2()] {
+		captured = chess.NewPiece(chess.Pawn, captured.Color())
+	}
+
+	delete(b.promoted, found.S2())
+	if b.promoted[found.S1()] || found.Promo() != chess.NoPieceType {
+		b.promoted[found.S2()] = true
+	}
+	delete(b.promoted, found.S1())
+
+	b.position = b.position.Update(found)
+	b.moves = append(b.moves, move)
+
+	return captured, nil
+}
+
+// Drops a piece of the pocket of the player to move
+func (b *Board) drop(move string) error {
+	pieceType, sq, err := parseDrop(move)
+	if err != nil {
+		return err
+	}
+
+	turn := b.Turn()
+	if b.pockets[turn][pieceType] == 0 {
+		return fmt.Errorf("illegal drop `%s`: no such piece in the pocket", move)
+	}
+
+	squares := b.position.Board().SquareMap()
+	piece := chess.NewPiece(pieceType, turn)
+	if !b.canDrop(squares, piece, sq) {
+		return fmt.Errorf("illegal drop `%s`", move)
+	}
+	squares[sq] = piece
+
+	// Dropping resets the en passant square and the fifty-move counter
+	fields := strings.Fields(b.position.String())
+	moveCount, _ := strconv.Atoi(fields[5])
+	if turn == chess.Black {
+		moveCount++
+	}
+
+	fen := fmt.Sprintf("%s %s %s - 0 %d", chess.NewBoard(squares).String(), turn.Other(), fields[2], moveCount)
+
+	position := &chess.Position{}
+	if err := position.UnmarshalText([]byte(fen)); err != nil {
+		return err
+	}
+
+	b.pockets[turn][pieceType]--
+	b.position = position
+	b.moves = append(b.moves, move)
+
+	return nil
+}
+
+// Returns `Checkmate` or `Stalemate` if the player to move can neither move
+// nor drop, `NoMethod` otherwise
+func (b *Board) Status() chess.Method {
+	if len(b.ValidMoves()) > 0 {
+		return chess.NoMethod
+	}
+
+	if rules.InCheck(b.position.Board().SquareMap(), b.Turn()) {
+		return chess.Checkmate
+	}
+
+	return chess.Stalemate
+}
+
+// Parses a drop such as `N@f3`
+func parseDrop(move string) (chess.PieceType, chess.Square, error) {
+	err := fmt.Errorf("invalid drop `%s`", move)
+	if len(move) != 4 || move[1] != '@' {
+		return chess.NoPieceType, chess.NoSquare, err
+	}
+
+	pieceType := chess.NoPieceType
+	for t, letter := range dropLetters {
+		if letter == move[:1] {
+			pieceType = t
+		}
+	}
+
+	file, rank := int(move[2]-'a'), int(move[3]-'1')
+	if pieceType == chess.NoPieceType || file < 0 || file > 7 || rank < 0 || rank > 7 {
+		return chess.NoPieceType, chess.NoSquare, err
+	}
+
+	return pieceType, chess.NewSquare(chess.File(file), chess.Rank(rank)), nil
+}
@@ -0,0 +1,98 @@
+package bughouse
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDrop tests that a dropped piece leaves the pocket and lands on the board.
+func TestDrop(t *testing.T) {
+	board := NewBoard()
+	board.AddToPocket(chess.NewPiece(chess.Knight, chess.White))
+
+	assert.Contains(t, board.ValidMoves(), "N@e4")
+	assert.NotContains(t, board.ValidMoves(), "N@e2")
+
+	captured, err := board.Move("N@e4")
+	assert.NoError(t, err)
+	assert.Equal(t, chess.NoPiece, captured)
+	assert.Equal(t, chess.NewPiece(chess.Knight, chess.White), board.Position().Board().Piece(chess.E4))
+	assert.Empty(t, board.Pocket(chess.White))
+	assert.Equal(t, chess.Black, board.Turn())
+
+	_, err = board.Move("N@e5")
+	assert.Error(t, err)
+}
+
+// TestPawnDrop tests that pawns can't be dropped on the first and last ranks.
+func TestPawnDrop(t *testing.T) {
+	board := NewBoard()
+	board.Move("e2e4")
+	board.Move("e7e5")
+	board.Move("d2d4")
+	board.Move("d7d5")
+	board.AddToPocket(chess.NewPiece(chess.Pawn, chess.White))
+
+	_, err := board.Move("P@e8")
+	assert.Error(t, err)
+	assert.Contains(t, board.ValidMoves(), "P@e3")
+}
+
+// TestCapturedPromotedPiece tests that a captured promoted piece is a pawn.
+func TestCapturedPromotedPiece(t *testing.T) {
+	board := NewBoard()
+	for _, move := range []string{"h2h4", "g7g5", "h4g5", "h7h6", "g5h6", "f8g7", "h6g7", "g8f6", "g7g8q"} {
+		_, err := board.Move(move)
+		assert.NoError(t, err, move)
+	}
+
+	captured, err := board.Move("h8g8")
+	assert.NoError(t, err)
+	assert.Equal(t, chess.NewPiece(chess.Pawn, chess.White), captured)
+}
+
+// TestParseDrop tests the parsing of the drop notation.
+func TestParseDrop(t *testing.T) {
+	pieceType, sq, err := parseDrop("Q@d4")
+	assert.NoError(t, err)
+	assert.Equal(t, chess.Queen, pieceType)
+	assert.Equal(t, chess.D4, sq)
+
+	for _, move := range []string{"K@d4", "Q@i4", "Q@d9", "Qd4", "q@d4"} {
+		_, _, err := parseDrop(move)
+		assert.Error(t, err, move)
+	}
+}
+
+// TestNotation tests that the moves are written in SAN, with the mates which
+// no drop can block.
+func TestNotation(t *testing.T) {
+	board := NewBoard()
+	for _, move := range []string{"e2e4", "d7d5", "e4d5", "g8f6", "f1b5"} {
+		_, err := board.Move(move)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"e4", "d5", "exd5", "Nf6", "Bb5+"}, board.Notation())
+	assert.Equal(t, "c6", board.SAN("c7c6"))
+	assert.Equal(t, "e2e4", board.SAN("e2e4"))
+
+	// The pawn captured on the other board can block the check
+	board.AddToPocket(chess.NewPiece(chess.Pawn, chess.Black))
+	assert.Equal(t, "P@c6", board.SAN("P@c6"))
+}
+
+// TestNotationMate tests that a mate which a drop could block is a check.
+func TestNotationMate(t *testing.T) {
+	board := NewBoard()
+	for _, move := range []string{"f2f3", "e7e5", "g2g4"} {
+		_, err := board.Move(move)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "Qh4#", board.SAN("d8h4"))
+
+	board.AddToPocket(chess.NewPiece(chess.Pawn, chess.White))
+	assert.Equal(t, "Qh4+", board.SAN("d8h4"))
+}
@@ -0,0 +1,238 @@
+package bughouse
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/notnil/chess"
+)
+
+// The two boards of a match
+const (
+	BoardA = 0
+	BoardB = 1
+)
+
+// A move played on one of the boards of a match
+type Move struct {
+	Board int    `json:"board"`
+	Ply   int    `json:"ply"` // Number of the move on its board, from 0
+	Move  string `json:"move"`
+}
+
+// Returns the color which plays the move on its board
+func (m Move) Color() chess.Color {
+	if m.Ply%2 == 0 {
+		return chess.White
+	}
+	return chess.Black
+}
+
+// Match is a bughouse match: two teams play on two linked boards, and the
+// pieces captured on a board go to the partner playing on the other one.
+//
+// The White team plays white on board A and black on board B, the Black team
+// plays black on board A and white on board B. The first board which ends
+// decides the match.
+//
+// The moves of the two boards can reach a player in any order, so every board
+// keeps its own history: a move received before the previous ones of its
+// board, or a drop of a piece not captured yet, waits until it can be played.
+type Match struct {
+	boards  [2]*Board
+	history [2][]Move
+	pending []Move
+	outcome chess.Outcome
+	method  string
+}
+
+func NewMatch() *Match {
+	return &Match{
+		boards:  [2]*Board{NewBoard(), NewBoard()},
+		outcome: chess.NoOutcome,
+	}
+}
+
+// Replays a match from its history, where the moves of each board are in the
+// order they have been played there
+func Replay(history []Move) (*Match, error) {
+	match := NewMatch()
+	for _, move := range history {
+		if err := match.receive(move); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(match.pending) > 0 {
+		move := match.pending[0]
+		return nil, fmt.Errorf("move %d of board %s can't be played", move.Ply, boardName(move.Board))
+	}
+
+	return match, nil
+}
+
+// Returns the board and the color played by the `seat`-th player. Players 1
+// and 3 are the White team, players 2 and 4 the Black team.
+func SeatBoard(seat int) (int, chess.Color) {
+	switch seat {
+	case 1:
+		return BoardA, chess.White
+	case 2:
+		return BoardA, chess.Black
+	case 3:
+		return BoardB, chess.Black
+	default:
+		return BoardB, chess.White
+	}
+}
+
+// Returns the team which plays `color` on `board`
+func TeamColor(board int, color chess.Color) chess.Color {
+	if board == BoardB {
+		return color.Other()
+	}
+	return color
+}
+
+func (m *Match) Board(board int) *Board {
+	return m.boards[board]
+}
+
+// Returns every move of the match, the ones of board A and then the ones of
+// board B, in the order they have been played on their board
+func (m *Match) History() []Move {
+	return slices.Concat(m.history[BoardA], m.history[BoardB])
+}
+
+// Returns the outcome of the match for the teams
+func (m *Match) Outcome() chess.Outcome {
+	return m.outcome
+}
+
+// Returns how the match ended, such as `Checkmate on board A`
+func (m *Match) Method() string {
+	return m.method
+}
+
+// Plays the move of the local player on `board` and returns it with its ply
+func (m *Match) Move(board int, move string) (Move, error) {
+	if m.outcome != chess.NoOutcome {
+		return Move{}, fmt.Errorf("the match is over")
+	}
+
+	if board != BoardA && board != BoardB {
+		return Move{}, fmt.Errorf("invalid board %d", board)
+	}
+
+	played := Move{Board: board, Ply: len(m.history[board]), Move: move}
+	if err := m.play(played); err != nil {
+		return Move{}, err
+	}
+
+	return played, nil
+}
+
+// Plays a move of another player, as soon as it can be played. An illegal move
+// is discarded.
+func (m *Match) Receive(move Move) error {
+	if m.outcome != chess.NoOutcome {
+		return fmt.Errorf("the match is over")
+	}
+
+	return m.receive(move)
+}
+
+func (m *Match) receive(move Move) error {
+	if move.Board != BoardA && move.Board != BoardB {
+		return fmt.Errorf("invalid board %d", move.Board)
+	}
+
+	received := func(other Move) bool {
+		return other.Board == move.Board && other.Ply == move.Ply
+	}
+	if move.Ply < len(m.history[move.Board]) || slices.ContainsFunc(m.pending, received) {
+		return fmt.Errorf("move %d of board %s already received", move.Ply, boardName(move.Board))
+	}
+
+	m.pending = append(m.pending, move)
+
+	return m.playPending()
+}
+
+// Plays the waiting moves as long as one of them can be played
+func (m *Match) playPending() error {
+	var errs []error
+	for {
+		i := slices.IndexFunc(m.pending, m.playable)
+		if i == -1 {
+			return errors.Join(errs...)
+		}
+
+		move := m.pending[i]
+		m.pending = slices.Delete(m.pending, i, i+1)
+
+		if err := m.play(move); err != nil {
+			errs = append(errs, fmt.Errorf("move %d of board %s: %v", move.Ply, boardName(move.Board), err))
+		}
+	}
+}
+
+// Returns true if `move` is the next one of its board and, if it is a drop,
+// its piece is in the pocket
+func (m *Match) playable(move Move) bool {
+	if move.Ply != len(m.history[move.Board]) {
+		return false
+	}
+
+	board := m.boards[move.Board]
+	pieceType, _, err := parseDrop(move.Move)
+
+	return err != nil || board.pockets[board.Turn()][pieceType] > 0
+}
+
+// Plays `move` and passes the captured piece to the partner
+func (m *Match) play(move Move) error {
+	captured, err := m.boards[move.Board].Move(move.Move)
+	if err != nil {
+		return err
+	}
+
+	// The partner plays the color of the captured piece on the other board
+	m.boards[1-move.Board].AddToPocket(captured)
+	m.history[move.Board] = append(m.history[move.Board], move)
+
+	m.updateOutcome(move.Board)
+
+	return nil
+}
+
+// Ends the match if `board` ended first
+func (m *Match) updateOutcome(board int) {
+	status := m.boards[board].Status()
+	if status == chess.NoMethod || m.outcome != chess.NoOutcome {
+		return
+	}
+
+	m.method = fmt.Sprintf("%s on board %s", status, boardName(board))
+
+	if status == chess.Stalemate {
+		m.outcome = chess.Draw
+		return
+	}
+
+	// The player to move has been mated
+	winner := TeamColor(board, m.boards[board].Turn().Other())
+	if winner == chess.White {
+		m.outcome = chess.WhiteWon
+	} else {
+		m.outcome = chess.BlackWon
+	}
+}
+
+func boardName(board int) string {
+	if board == BoardA {
+		return "A"
+	}
+	return "B"
+}
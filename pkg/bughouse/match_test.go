@@ -0,0 +1,108 @@
+package bughouse
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCaptureGoesToPartner tests that a captured piece can be dropped by the
+// partner on the other board.
+func TestCaptureGoesToPartner(t *testing.T) {
+	match := NewMatch()
+	for _, move := range []string{"e2e4", "d7d5", "e4d5"} {
+		_, err := match.Move(BoardB, move)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, Pocket{chess.Pawn: 1}, match.Board(BoardA).Pocket(chess.Black))
+	assert.Empty(t, match.Board(BoardB).Pocket(chess.White))
+}
+
+// TestMatchOutcome tests that a mate on board B is won by the team playing
+// black there.
+func TestMatchOutcome(t *testing.T) {
+	match := NewMatch()
+	for _, move := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		_, err := match.Move(BoardB, move)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, chess.WhiteWon, match.Outcome())
+	assert.Equal(t, "Checkmate on board B", match.Method())
+	_, err := match.Move(BoardA, "e2e4")
+	assert.Error(t, err)
+}
+
+// TestDropBlocksMate tests that a mate which can be blocked by a drop does not
+// end the match.
+func TestDropBlocksMate(t *testing.T) {
+	match := NewMatch()
+	for _, move := range []string{"e2e4", "d7d5", "e4d5", "d8d5"} {
+		_, err := match.Move(BoardB, move)
+		assert.NoError(t, err)
+	}
+
+	for _, move := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		_, err := match.Move(BoardA, move)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, chess.NoOutcome, match.Outcome())
+	assert.ElementsMatch(t, []string{"P@f2", "P@g3"}, match.Board(BoardA).ValidMoves())
+
+	replayed, err := Replay(match.History())
+	assert.NoError(t, err)
+	assert.Equal(t, match.Board(BoardA).Position().String(), replayed.Board(BoardA).Position().String())
+}
+
+// TestReceiveOutOfOrder tests that the moves of a board received before the
+// previous ones, and the drops received before the capture of their piece,
+// wait until they can be played.
+func TestReceiveOutOfOrder(t *testing.T) {
+	moves := []Move{
+		{Board: BoardB, Ply: 0, Move: "e2e4"},
+		{Board: BoardB, Ply: 1, Move: "d7d5"},
+		{Board: BoardB, Ply: 2, Move: "e4d5"},
+		{Board: BoardA, Ply: 0, Move: "e2e4"},
+		{Board: BoardA, Ply: 1, Move: "P@e5"},
+	}
+
+	match := NewMatch()
+	for _, i := range []int{4, 3, 1, 0, 2} {
+		assert.NoError(t, match.Receive(moves[i]))
+	}
+
+	assert.Equal(t, []string{"e2e4", "P@e5"}, match.Board(BoardA).Moves())
+	assert.Equal(t, []string{"e2e4", "d7d5", "e4d5"}, match.Board(BoardB).Moves())
+	assert.Equal(t, []Move{moves[3], moves[4], moves[0], moves[1], moves[2]}, match.History())
+
+	assert.Error(t, match.Receive(moves[1]))
+}
+
+// TestHistoryPerBoard tests that the players agree on the history whatever
+// the order they received the moves of the two boards.
+func TestHistoryPerBoard(t *testing.T) {
+	first, second := NewMatch(), NewMatch()
+
+	for _, move := range []Move{{Board: BoardA, Ply: 0, Move: "e2e4"}, {Board: BoardB, Ply: 0, Move: "d2d4"}} {
+		assert.NoError(t, first.Receive(move))
+	}
+	for _, move := range []Move{{Board: BoardB, Ply: 0, Move: "d2d4"}, {Board: BoardA, Ply: 0, Move: "e2e4"}} {
+		assert.NoError(t, second.Receive(move))
+	}
+
+	assert.Equal(t, first.History(), second.History())
+
+	replayed, err := Replay(second.History())
+	assert.NoError(t, err)
+	assert.Equal(t, first.History(), replayed.History())
+}
+
+// TestReplayUnplayable tests that a history with a move which never becomes
+// playable is rejected.
+func TestReplayUnplayable(t *testing.T) {
+	_, err := Replay([]Move{{Board: BoardA, Ply: 0, Move: "Q@e4"}})
+	assert.Error(t, err)
+}
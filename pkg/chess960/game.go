@@ -0,0 +1,659 @@
+// Package chess960 implements chess games from any starting position, with
+// the Chess960 castling rules. The standard game is the Chess960 position 518,
+// so the same rules apply to every game.
+package chess960
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/boozec/rahanna/pkg/rules"
+	"github.com/notnil/chess"
+)
+
+// FEN of the standard starting position
+const StandardFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// Sides of the castling
+const (
+	kingSide  = 0
+	queenSide = 1
+)
+
+// Squares of the rooks which can still castle, by color and side. NoSquare if
+// the castling is not allowed anymore.
+type castling [2][2]chess.Square
+
+func colorIndex(color chess.Color) int {
+	if color == chess.White {
+		return 0
+	}
+	return 1
+}
+
+func backRank(color chess.Color) chess.Rank {
+	if color == chess.White {
+		return chess.Rank1
+	}
+	return chess.Rank8
+}
+
+// Game is a chess game which can start from any position. notnil/chess only
+// knows the standard castling, so its positions have no castling rights and
+// the castling moves are generated here.
+type Game struct {
+	// Castling moves are written with the king destination, as in standard
+	// chess, instead of the square of the rook
+	standard bool
+
+	positions []*chess.Position
+	rights    []castling
+	moves     []*chess.Move
+	outcome   chess.Outcome
+	method    chess.Method
+
+	// Repetition key of each position, and how many times each key occurred
+	keys        []string
+	occurrences map[string]int
+}
+
+// Returns a game starting from the standard position
+func NewStandardGame() *Game {
+	game, _ := NewGame(StandardFEN)
+	return game
+}
+
+// Returns a game starting from `fen`. The castling rights can be written as
+// `KQkq`, meaning the outermost rooks, or with the files of the rooks.
+func NewGame(fen string) (*Game, error) {
+	fields := strings.Fields(fen)
+	if len(fields) == 4 {
+		fields = append(fields, "0", "1")
+	}
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("invalid FEN `%s`", fen)
+	}
+
+	castlingField := fields[2]
+	fields[2] = "-"
+
+	position := &chess.Position{}
+	if err := position.UnmarshalText([]byte(strings.Join(fields, " "))); err != nil {
+		return nil, fmt.Errorf("invalid FEN `%s`: %v", fen, err)
+	}
+
+	squares := position.Board().SquareMap()
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		kings := 0
+		for _, piece := range squares {
+			if piece == chess.NewPiece(chess.King, color) {
+				kings++
+			}
+		}
+		if kings != 1 {
+			return nil, fmt.Errorf("invalid FEN `%s`: %s must have one king", fen, color.Name())
+		}
+	}
+
+	if rules.InCheck(squares, position.Turn().Other()) {
+		return nil, fmt.Errorf("invalid FEN `%s`: the side not to move is in check", fen)
+	}
+
+	rights, err := parseCastling(squares, castlingField)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FEN `%s`: %v", fen, err)
+	}
+
+	game := &Game{
+		standard:    isStandardCastling(squares, rights),
+		positions:   []*chess.Position{position},
+		rights:      []castling{rights},
+		outcome:     chess.NoOutcome,
+		method:      chess.NoMethod,
+		occurrences: make(map[string]int),
+	}
+	game.updateOutcome()
+
+	return game, nil
+}
+
+// Returns the square of the king of `color`
+func kingSquare(squares map[chess.Square]chess.Piece, color chess.Color) chess.Square {
+	for sq, piece := range squares {
+		if piece == chess.NewPiece(chess.King, color) {
+			return sq
+		}
+	}
+	return chess.NoSquare
+}
+
+// Returns the rooks of `color` on its back rank, from the a-file to the h-file
+func backRankRooks(squares map[chess.Square]chess.Piece, color chess.Color) []chess.Square {
+	var rooks []chess.Square
+	for file := chess.FileA; file <= chess.FileH; file++ {
+		sq := chess.NewSquare(file, backRank(color))
+		if squares[sq] == chess.NewPiece(chess.Rook, color) {
+			rooks = append(rooks, sq)
+		}
+	}
+	return rooks
+}
+
+// Parses the castling rights of a FEN
+func parseCastling(squares map[chess.Square]chess.Piece, field string) (castling, error) {
+	rights := castling{{chess.NoSquare, chess.NoSquare}, {chess.NoSquare, chess.NoSquare}}
+	if field == "-" {
+		return rights, nil
+	}
+
+	for _, c := range field {
+		color := chess.White
+		if c >= 'a' && c <= 'z' {
+			color = chess.Black
+		}
+
+		king := kingSquare(squares, color)
+		if king.Rank() != backRank(color) {
+			return rights, fmt.Errorf("%s can't castle, its king left the back rank", color.Name())
+		}
+
+		rooks := backRankRooks(squares, color)
+		rook := chess.NoSquare
+
+		switch lower := c | 0x20; {
+		case lower == 'k':
+			for _, sq := range rooks {
+				if sq.File() > king.File() {
+					rook = sq
+				}
+			}
+		case lower == 'q':
+			for _, sq := range slices.Backward(rooks) {
+				if sq.File() < king.File() {
+					rook = sq
+				}
+			}
+		case lower >= 'a' && lower <= 'h':
+			sq := chess.NewSquare(chess.File(lower-'a'), backRank(color))
+			if slices.Contains(rooks, sq) {
+				rook = sq
+			}
+		default:
+			return rights, fmt.Errorf("invalid castling right `%c`", c)
+		}
+
+		if rook == chess.NoSquare || rook.File() == king.File() {
+			return rights, fmt.Errorf("no rook for the castling right `%c`", c)
+		}
+
+		side := kingSide
+		if rook.File() < king.File() {
+			side = queenSide
+		}
+		rights[colorIndex(color)][side] = rook
+	}
+
+	return rights, nil
+}
+
+// Returns true if the castling rooks are in the corners and the kings on the
+// e-file
+func isStandardCastling(squares map[chess.Square]chess.Piece, rights castling) bool {
+	for i, color := range []chess.Color{chess.White, chess.Black} {
+		for side, rook := range rights[i] {
+			if rook == chess.NoSquare {
+				continue
+			}
+
+			corner := chess.FileH
+			if side == queenSide {
+				corner = chess.FileA
+			}
+
+			if rook.File() != corner || kingSquare(squares, color).File() != chess.FileE {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Returns the castling rights as written in a FEN: `KQkq` for the outermost
+// rooks, their files otherwise
+func (g *Game) castlingString() string {
+	rights := g.rights[len(g.rights)-1]
+	squares := g.Position().Board().SquareMap()
+
+	var s string
+	for i, color := range []chess.Color{chess.White, chess.Black} {
+		rooks := backRankRooks(squares, color)
+		for side, rook := range rights[i] {
+			if rook == chess.NoSquare {
+				continue
+			}
+
+			outermost := rooks[len(rooks)-1]
+			letter := "K"
+			if side == queenSide {
+				outermost = rooks[0]
+				letter = "Q"
+			}
+
+			if rook != outermost {
+				letter = strings.ToUpper(rook.File().String())
+			}
+
+			if color == chess.Black {
+				letter = strings.ToLower(letter)
+			}
+			s += letter
+		}
+	}
+
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// Returns the FEN of the starting position
+func (g *Game) StartFEN() string {
+	return g.fen(0)
+}
+
+// Returns the FEN of the current position
+func (g *Game) FEN() string {
+	return g.fen(len(g.positions) - 1)
+}
+
+func (g *Game) fen(i int) string {
+	fields := strings.Fields(g.positions[i].String())
+
+	// The castling rights are the ones of the `i`-th position
+	game := &Game{positions: g.positions[:i+1], rights: g.rights[:i+1]}
+	fields[2] = game.castlingString()
+
+	return strings.Join(fields, " ")
+}
+
+// Returns a copy of the game, which can be played without changing `g`
+func (g *Game) Clone() *Game {
+	clone := *g
+	clone.positions = slices.Clone(g.positions)
+	clone.rights = slices.Clone(g.rights)
+	clone.moves = slices.Clone(g.moves)
+	clone.keys = slices.Clone(g.keys)
+	clone.occurrences = maps.Clone(g.occurrences)
+
+	return &clone
+}
+
+// Returns the game from the same starting position with only its first `n`
+// moves, replayed
+func (g *Game) Rollback(n int) (*Game, error) {
+	if n < 0 || n > len(g.moves) {
+		return nil, fmt.Errorf("can't roll back to move %d of %d", n, len(g.moves))
+	}
+
+	game, err := NewGame(g.StartFEN())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, move := range g.Moves()[:n] {
+		if err := game.MoveStr(move.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	return game, nil
+}
+
+// Returns a game from the current position with the other player to move, as
+// if a null move was played. The player waiting for the opponent plans its
+// next moves on it. An error is returned if the player to move is in check.
+func (g *Game) PassTurn() (*Game, error) {
+	fields := strings.Fields(g.FEN())
+	fields[1] = g.Position().Turn().Other().String()
+	fields[3] = "-"
+
+	game, err := NewGame(strings.Join(fields, " "))
+	if err != nil {
+		return nil, err
+	}
+	game.standard = g.standard
+
+	return game, nil
+}
+
+// Returns true if the castling moves are written with the king destination, as
+// in standard chess. Otherwise they are written with the square of the rook.
+func (g *Game) StandardCastling() bool {
+	return g.standard
+}
+
+// Returns the current position. It has no castling rights, use `FEN` to get
+// them.
+func (g *Game) Position() *chess.Position {
+	return g.positions[len(g.positions)-1]
+}
+
+func (g *Game) Positions() []*chess.Position {
+	return slices.Clone(g.positions)
+}
+
+func (g *Game) Moves() []*chess.Move {
+	return slices.Clone(g.moves)
+}
+
+func (g *Game) Outcome() chess.Outcome {
+	return g.outcome
+}
+
+func (g *Game) Method() chess.Method {
+	return g.method
+}
+
+// Returns the castling moves of the player to move
+func (g *Game) castleMoves() []*chess.Move {
+	position := g.Position()
+	color := position.Turn()
+	squares := position.Board().SquareMap()
+	king := kingSquare(squares, color)
+
+	if rules.InCheck(squares, color) {
+		return nil
+	}
+
+	var moves []*chess.Move
+	for side, rook := range g.rights[len(g.rights)-1][colorIndex(color)] {
+		if rook == chess.NoSquare {
+			continue
+		}
+
+		kingTo, rookTo := castlingTargets(color, side)
+		if !g.canCastle(squares, color, king, rook, kingTo, rookTo) {
+			continue
+		}
+
+		target := rook
+		if g.standard {
+			target = kingTo
+		}
+
+		move, err := chess.UCINotation{}.Decode(nil, king.String()+target.String())
+		if err == nil {
+			moves = append(moves, move)
+		}
+	}
+
+	return moves
+}
+
+// Returns the destinations of king and rook castling on `side`
+func castlingTargets(color chess.Color, side int) (chess.Square, chess.Square) {
+	if side == kingSide {
+		return chess.NewSquare(chess.FileG, backRank(color)), chess.NewSquare(chess.FileF, backRank(color))
+	}
+	return chess.NewSquare(chess.FileC, backRank(color)), chess.NewSquare(chess.FileD, backRank(color))
+}
+
+// Returns true if the squares between king, rook and their destinations are
+// free and the king does not pass through an attacked square
+func (g *Game) canCastle(squares map[chess.Square]chess.Piece, color chess.Color, king, rook, kingTo, rookTo chess.Square) bool {
+	files := []chess.File{king.File(), rook.File(), kingTo.File(), rookTo.File()}
+	for file := slices.Min(files); file <= slices.Max(files); file++ {
+		sq := chess.NewSquare(file, backRank(color))
+		if _, occupied := squares[sq]; occupied && sq != king && sq != rook {
+			return false
+		}
+	}
+
+	board := castledBoard(squares, king, rook, kingTo, rookTo)
+	if rules.InCheck(board, color) {
+		return false
+	}
+
+	// The king can't pass through an attacked square
+	step := chess.File(1)
+	if kingTo.File() < king.File() {
+		step = ^chess.File(0)
+	}
+
+	delete(squares, king)
+	defer func() { squares[king] = chess.NewPiece(chess.King, color) }()
+
+	for file := king.File(); file != kingTo.File(); file += step {
+		if rules.Attacked(squares, chess.NewSquare(file, backRank(color)), color.Other()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns the board after the castling of `king` with `rook`
+func castledBoard(squares map[chess.Square]chess.Piece, king, rook, kingTo, rookTo chess.Square) map[chess.Square]chess.Piece {
+	board := make(map[chess.Square]chess.Piece, len(squares))
+	for sq, piece := range squares {
+		board[sq] = piece
+	}
+
+	kingPiece, rookPiece := board[king], board[rook]
+	delete(board, king)
+	delete(board, rook)
+	board[kingTo] = kingPiece
+	board[rookTo] = rookPiece
+
+	return board
+}
+
+// Returns the legal moves of the player to move, castling included
+func (g *Game) ValidMoves() []*chess.Move {
+	if g.outcome != chess.NoOutcome {
+		return nil
+	}
+
+	return append(g.Position().ValidMoves(), g.castleMoves()...)
+}
+
+// Returns `O-O` or `O-O-O` if `move` is a castling of the player to move, an
+// empty string otherwise
+func (g *Game) CastlingName(move *chess.Move) string {
+	for _, castle := range g.castleMoves() {
+		if castle.String() != move.String() {
+			continue
+		}
+
+		if castle.S2().File() > castle.S1().File() {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+
+	return ""
+}
+
+// Plays a move written in UCI notation
+func (g *Game) MoveStr(s string) error {
+	if g.outcome != chess.NoOutcome {
+		return errors.New("the game is over")
+	}
+
+	for _, castle := range g.castleMoves() {
+		if castle.String() == s {
+			return g.castle(castle)
+		}
+	}
+
+	for _, move := range g.Position().ValidMoves() {
+		if move.String() == s {
+			g.play(move)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("illegal move `%s`", s)
+}
+
+// Plays a move generated by notnil/chess
+func (g *Game) play(move *chess.Move) {
+	position := g.Position()
+	piece := position.Board().Piece(move.S1())
+
+	rights := g.rights[len(g.rights)-1]
+	for i := range rights {
+		for side, rook := range rights[i] {
+			if rook == move.S1() || rook == move.S2() {
+				rights[i][side] = chess.NoSquare
+			}
+		}
+	}
+	if piece.Type() == chess.King {
+		rights[colorIndex(piece.Color())] = [2]chess.Square{chess.NoSquare, chess.NoSquare}
+	}
+
+	g.push(position.Update(move), rights, move)
+}
+
+// Plays a castling move built by `castleMoves`
+func (g *Game) castle(move *chess.Move) error {
+	position := g.Position()
+	color := position.Turn()
+	rights := g.rights[len(g.rights)-1]
+
+	for side, rook := range rights[colorIndex(color)] {
+		kingTo, rookTo := castlingTargets(color, side)
+		if rook == chess.NoSquare || (move.S2() != rook && move.S2() != kingTo) {
+			continue
+		}
+
+		squares := castledBoard(position.Board().SquareMap(), move.S1(), rook, kingTo, rookTo)
+		rights[colorIndex(color)] = [2]chess.Square{chess.NoSquare, chess.NoSquare}
+
+		fields := strings.Fields(position.String())
+		halfMoves, _ := strconv.Atoi(fields[4])
+		moveCount, _ := strconv.Atoi(fields[5])
+		if color == chess.Black {
+			moveCount++
+		}
+
+		fen := fmt.Sprintf("%s %s - - %d %d", chess.NewBoard(squares).String(), color.Other(), halfMoves+1, moveCount)
+
+		next := &chess.Position{}
+		if err := next.UnmarshalText([]byte(fen)); err != nil {
+			return err
+		}
+
+		g.push(next, rights, move)
+		return nil
+	}
+
+	return fmt.Errorf("illegal castling `%s`", move)
+}
+
+func (g *Game) push(position *chess.Position, rights castling, move *chess.Move) {
+	g.positions = append(g.positions, position)
+	g.rights = append(g.rights, rights)
+	g.moves = append(g.moves, move)
+	g.updateOutcome()
+}
+
+// Ends the game if the player to move is mated or stalemated, or if the
+// position is an automatic draw
+func (g *Game) updateOutcome() {
+	position := g.Position()
+	moves := g.ValidMoves()
+
+	key := g.repetitionKey(moves)
+	g.keys = append(g.keys, key)
+	g.occurrences[key]++
+
+	if len(moves) == 0 {
+		if rules.InCheck(position.Board().SquareMap(), position.Turn()) {
+			g.method = chess.Checkmate
+			g.outcome = chess.WhiteWon
+			if position.Turn() == chess.White {
+				g.outcome = chess.BlackWon
+			}
+		} else {
+			g.method = chess.Stalemate
+			g.outcome = chess.Draw
+		}
+		return
+	}
+
+	switch {
+	case g.repetitions() >= 5:
+		g.method = chess.FivefoldRepetition
+	case position.HalfMoveClock() >= 150:
+		g.method = chess.SeventyFiveMoveRule
+	case !hasSufficientMaterial(position):
+		g.method = chess.InsufficientMaterial
+	default:
+		return
+	}
+	g.outcome = chess.Draw
+}
+
+// Returns false if nobody can mate, as decided by notnil/chess
+func hasSufficientMaterial(position *chess.Position) bool {
+	fen, err := chess.FEN(position.String())
+	if err != nil {
+		return true
+	}
+	return chess.NewGame(fen).Method() != chess.InsufficientMaterial
+}
+
+// Returns what makes the current position the same as another one: the
+// pieces, the player to move, the castling rights and the en passant square,
+// only if one of the legal `moves` captures en passant
+func (g *Game) repetitionKey(moves []*chess.Move) string {
+	fields := strings.Fields(g.Position().String())
+	fields[2] = g.castlingString()
+	fields[3] = "-"
+
+	for _, move := range moves {
+		if move.HasTag(chess.EnPassant) {
+			fields[3] = move.S2().String()
+			break
+		}
+	}
+
+	return strings.Join(fields[:4], " ")
+}
+
+// Returns how many times the current position occurred
+func (g *Game) repetitions() int {
+	return g.occurrences[g.keys[len(g.keys)-1]]
+}
+
+// Returns the draws which can be claimed now
+func (g *Game) EligibleDraws() []chess.Method {
+	draws := []chess.Method{chess.DrawOffer}
+	if g.repetitions() >= 3 {
+		draws = append(draws, chess.ThreefoldRepetition)
+	}
+	if g.Position().HalfMoveClock() >= 100 {
+		draws = append(draws, chess.FiftyMoveRule)
+	}
+	return draws
+}
+
+// Ends the game as a draw by `method`, if it can be claimed
+func (g *Game) Draw(method chess.Method) error {
+	if !slices.Contains(g.EligibleDraws(), method) {
+		return fmt.Errorf("draw by %s can't be claimed", method)
+	}
+
+	g.outcome = chess.Draw
+	g.method = method
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package chess960
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+func moveStrings(moves []*chess.Move) []string {
+	var s []string
+	for _, move := range moves {
+		s = append(s, move.String())
+	}
+	return s
+}
+
+// TestStandardCastling tests that castling is written with the destination
+// of the king in standard games.
+func TestStandardCastling(t *testing.T) {
+	game := NewStandardGame()
+	for _, move := range []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "g8f6"} {
+		assert.NoError(t, game.MoveStr(move), move)
+	}
+
+	assert.Contains(t, moveStrings(game.ValidMoves()), "e1g1")
+	assert.NoError(t, game.MoveStr("e1g1"))
+	assert.Equal(t, "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 5 4", game.FEN())
+}
+
+// TestChess960Castling tests that castling moves the king onto its rook.
+func TestChess960Castling(t *testing.T) {
+	game, err := NewGame("1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/1R2K1R1 w KQkq - 0 1")
+	assert.NoError(t, err)
+
+	moves := moveStrings(game.ValidMoves())
+	assert.Contains(t, moves, "e1g1")
+	assert.Contains(t, moves, "e1b1")
+
+	assert.Equal(t, "O-O-O", game.CastlingName(game.ValidMoves()[len(moves)-1]))
+
+	assert.NoError(t, game.MoveStr("e1b1"))
+	assert.Equal(t, "1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/2KR2R1 b kq - 1 1", game.FEN())
+
+	// The king goes to g8 and the rook to f8
+	assert.NoError(t, game.MoveStr("e8g8"))
+	assert.Equal(t, "1r3rk1/pppppppp/8/8/8/8/PPPPPPPP/2KR2R1 w - - 2 2", game.FEN())
+}
+
+// TestCastlingThroughCheck tests that the king can't castle through an
+// attacked square.
+func TestCastlingThroughCheck(t *testing.T) {
+	game, err := NewGame("4k3/8/8/8/8/8/5r2/4K2R w K - 0 1")
+	assert.NoError(t, err)
+	assert.NotContains(t, moveStrings(game.ValidMoves()), "e1g1")
+}
+
+// TestCastlingRights tests that moving a rook loses its castling right.
+func TestCastlingRights(t *testing.T) {
+	game, err := NewGame("rk4r1/8/8/8/8/8/8/RK4R1 w GAga - 0 1")
+	assert.NoError(t, err)
+	assert.Equal(t, "rk4r1/8/8/8/8/8/8/RK4R1 w KQkq - 0 1", game.FEN())
+
+	assert.NoError(t, game.MoveStr("g1g2"))
+	assert.Equal(t, "rk4r1/8/8/8/8/8/6R1/RK6 b Qkq - 1 1", game.FEN())
+}
+
+// TestInvalidFEN tests that impossible positions are refused.
+func TestInvalidFEN(t *testing.T) {
+	_, err := NewGame("8/8/8/8/8/8/8/4K3 w - - 0 1")
+	assert.Error(t, err)
+
+	_, err = NewGame("4k3/8/8/8/8/8/8/4K3 w K - 0 1")
+	assert.Error(t, err)
+
+	_, err = NewGame("4k3/4R3/8/8/8/8/8/4K3 w - - 0 1")
+	assert.Error(t, err)
+}
+
+// TestOutcome tests checkmate and insufficient material.
+func TestOutcome(t *testing.T) {
+	game := NewStandardGame()
+	for _, move := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		assert.NoError(t, game.MoveStr(move), move)
+	}
+	assert.Equal(t, chess.BlackWon, game.Outcome())
+	assert.Equal(t, chess.Checkmate, game.Method())
+	assert.Error(t, game.MoveStr("a2a3"))
+
+	game, err := NewGame("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	assert.NoError(t, err)
+	assert.Equal(t, chess.Draw, game.Outcome())
+	assert.Equal(t, chess.InsufficientMaterial, game.Method())
+}
+
+// TestThreefoldRepetition tests that a repeated position can be claimed.
+func TestThreefoldRepetition(t *testing.T) {
+	game := NewStandardGame()
+	assert.Error(t, game.Draw(chess.ThreefoldRepetition))
+
+	for range 2 {
+		for _, move := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+			assert.NoError(t, game.MoveStr(move), move)
+		}
+	}
+
+	assert.NoError(t, game.Draw(chess.ThreefoldRepetition))
+	assert.Equal(t, chess.Draw, game.Outcome())
+}
+
+// TestRepetitionEnPassant tests that the en passant square tells two
+// positions apart only if an en passant capture is legal.
+func TestRepetitionEnPassant(t *testing.T) {
+	game := NewStandardGame()
+
+	// Black can't capture on e3, so the position is repeated
+	for _, move := range []string{"e2e4", "g8f6", "g1f3", "f6g8", "f3g1", "g8f6", "g1f3", "f6g8", "f3g1"} {
+		assert.NoError(t, game.MoveStr(move), move)
+	}
+	assert.Equal(t, 3, game.repetitions())
+	assert.Contains(t, game.EligibleDraws(), chess.ThreefoldRepetition)
+
+	game, err := NewGame("4k3/3p4/8/4P3/8/8/8/4K3 b - - 0 1")
+	assert.NoError(t, err)
+
+	// White can capture on d6 only after d7d5
+	for _, move := range []string{"d7d5", "e1d1", "e8d8", "d1e1", "d8e8", "e1d1", "e8d8", "d1e1", "d8e8"} {
+		assert.NoError(t, game.MoveStr(move), move)
+	}
+	assert.Equal(t, 2, game.repetitions())
+	assert.NotContains(t, game.EligibleDraws(), chess.ThreefoldRepetition)
+
+	clone := game.Clone()
+	for _, move := range []string{"e1d1", "e8d8", "d1e1", "d8e8"} {
+		assert.NoError(t, clone.MoveStr(move), move)
+	}
+	assert.Equal(t, 3, clone.repetitions())
+	assert.Equal(t, 2, game.repetitions(), "Expected the clone to count its own positions")
+}
+
+// TestPassTurn tests the moves planned while the opponent is to move.
+func TestPassTurn(t *testing.T) {
+	game := NewStandardGame()
+	assert.NoError(t, game.MoveStr("e2e4"))
+
+	passed, err := game.PassTurn()
+	assert.NoError(t, err)
+	assert.Equal(t, chess.White, passed.Position().Turn())
+	assert.Contains(t, moveStrings(passed.ValidMoves()), "g1f3")
+	assert.Len(t, game.Moves(), 1)
+
+	// The player to move can't pass while in check
+	for _, move := range []string{"f7f6", "d1h5"} {
+		assert.NoError(t, game.MoveStr(move))
+	}
+	_, err = game.PassTurn()
+	assert.Error(t, err)
+}
+
+// TestRollback tests that a game rolled back keeps only its first moves, from
+// the same starting position.
+func TestRollback(t *testing.T) {
+	game, err := NewGame("bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w KQkq - 0 1")
+	assert.NoError(t, err)
+	for _, move := range []string{"e2e4", "e7e5", "h1g3"} {
+		assert.NoError(t, game.MoveStr(move))
+	}
+
+	rolledBack, err := game.Rollback(1)
+	assert.NoError(t, err)
+	assert.Equal(t, game.StartFEN(), rolledBack.StartFEN())
+	assert.Equal(t, game.Positions()[1].String(), rolledBack.Position().String())
+	assert.Len(t, game.Moves(), 3)
+
+	_, err = game.Rollback(4)
+	assert.Error(t, err)
+}
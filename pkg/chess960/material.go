@@ -0,0 +1,55 @@
+package chess960
+
+import "github.com/notnil/chess"
+
+// Points of the pieces, as usually counted. The king has none.
+var pieceValues = map[chess.PieceType]int{
+	chess.Queen:  9,
+	chess.Rook:   5,
+	chess.Bishop: 3,
+	chess.Knight: 3,
+	chess.Pawn:   1,
+}
+
+// Returns the points of a piece of type `pieceType`
+func PieceValue(pieceType chess.PieceType) int {
+	return pieceValues[pieceType]
+}
+
+// Returns the pieces captured by `color`, in the order they were taken
+func (g *Game) Captured(color chess.Color) []chess.PieceType {
+	var captured []chess.PieceType
+	for i, move := range g.moves {
+		board := g.positions[i].Board()
+		if board.Piece(move.S1()).Color() != color {
+			continue
+		}
+
+		if move.HasTag(chess.EnPassant) {
+			captured = append(captured, chess.Pawn)
+			continue
+		}
+
+		// A castling king moves on its own rook
+		if target := board.Piece(move.S2()); target != chess.NoPiece && target.Color() != color {
+			captured = append(captured, target.Type())
+		}
+	}
+
+	return captured
+}
+
+// Returns the points of the white pieces on the board minus the ones of the
+// black pieces
+func (g *Game) MaterialBalance() int {
+	balance := 0
+	for _, piece := range g.Position().Board().SquareMap() {
+		if piece.Color() == chess.White {
+			balance += pieceValues[piece.Type()]
+		} else {
+			balance -= pieceValues[piece.Type()]
+		}
+	}
+
+	return balance
+}
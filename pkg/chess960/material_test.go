@@ -0,0 +1,40 @@
+package chess960
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCaptured tests the pieces taken by each player, en passant included.
+func TestCaptured(t *testing.T) {
+	game := NewStandardGame()
+	for _, move := range []string{"e2e4", "d7d5", "e4d5", "d8d5", "b1c3", "d5a2", "a1a2", "e7e5", "d2d4", "e5e4", "f2f4", "e4f3"} {
+		assert.NoError(t, game.MoveStr(move), move)
+	}
+
+	assert.Equal(t, []chess.PieceType{chess.Pawn, chess.Queen}, game.Captured(chess.White))
+	assert.Equal(t, []chess.PieceType{chess.Pawn, chess.Pawn, chess.Pawn}, game.Captured(chess.Black))
+	assert.Equal(t, 7, game.MaterialBalance())
+}
+
+// TestCapturedChess960 tests that castling on the own rook is no capture.
+func TestCapturedChess960(t *testing.T) {
+	game, err := NewGame("1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/1R2K1R1 w KQkq - 0 1")
+	assert.NoError(t, err)
+
+	assert.NoError(t, game.MoveStr("e1b1"))
+	assert.Empty(t, game.Captured(chess.White))
+	assert.Equal(t, 0, game.MaterialBalance())
+}
+
+// TestMaterialBalancePromotion tests that a promoted pawn counts as its piece.
+func TestMaterialBalancePromotion(t *testing.T) {
+	game, err := NewGame("8/4P1k1/8/8/8/8/8/4K3 w - - 0 1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, game.MaterialBalance())
+
+	assert.NoError(t, game.MoveStr("e7e8q"))
+	assert.Equal(t, 9, game.MaterialBalance())
+}
@@ -0,0 +1,164 @@
+package chess960
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/boozec/rahanna/pkg/rules"
+	"github.com/notnil/chess"
+)
+
+// Returned when a move written in SAN can be more than one legal move
+var ErrAmbiguousMove = errors.New("ambiguous move")
+
+// SAN without the piece or the square it leaves when they are not needed, as
+// `Nd2` or `e8Q`. Captures, checks and `=` are removed before.
+var looseSAN = regexp.MustCompile(`^([KQRBN])?([a-h])?([1-8])?([a-h][1-8])([QRBNqrbn])?$`)
+
+// Returns true if `move` is a castling in `position`: the king moves on its
+// rook, or by more than one file
+func isCastling(position *chess.Position, move *chess.Move) bool {
+	piece := position.Board().Piece(move.S1())
+	if piece.Type() != chess.King {
+		return false
+	}
+
+	if target := position.Board().Piece(move.S2()); target != chess.NoPiece && target.Color() == piece.Color() {
+		return true
+	}
+
+	files := int(move.S2().File()) - int(move.S1().File())
+	return files > 1 || files < -1
+}
+
+// Returns `move` in SAN, played from `before` to `after`
+func san(before, after *chess.Position, move *chess.Move) string {
+	if !isCastling(before, move) {
+		return chess.AlgebraicNotation{}.Encode(before, move)
+	}
+
+	s := "O-O-O"
+	if move.S2().File() > move.S1().File() {
+		s = "O-O"
+	}
+
+	if rules.InCheck(after.Board().SquareMap(), after.Turn()) {
+		if len(after.ValidMoves()) == 0 {
+			return s + "#"
+		}
+		return s + "+"
+	}
+
+	return s
+}
+
+// Returns a legal move of the player to move in SAN, as `Nf3` or `O-O`
+func (g *Game) SAN(move *chess.Move) string {
+	next := g.Clone()
+	if err := next.MoveStr(move.String()); err != nil {
+		return move.String()
+	}
+
+	return san(g.Position(), next.Position(), move)
+}
+
+// Returns the moves played in SAN
+func (g *Game) Notation() []string {
+	notation := make([]string, len(g.moves))
+	for i, move := range g.moves {
+		notation[i] = san(g.positions[i], g.positions[i+1], move)
+	}
+
+	return notation
+}
+
+// Removes the characters which don't tell a move from another
+func normalizeMove(s string) string {
+	s = strings.TrimRight(strings.TrimSpace(s), "+#!?")
+	if s == "0-0" || s == "0-0-0" {
+		s = strings.ReplaceAll(s, "0", "O")
+	}
+
+	return strings.NewReplacer("x", "", "-", "", "=", "", ":", "", " ", "").Replace(s)
+}
+
+// Returns the ways `move` can be written: UCI, SAN and long algebraic
+func (g *Game) moveForms(move *chess.Move) []string {
+	piece := g.Position().Board().Piece(move.S1())
+	letter := pieceLetters[piece.Type()]
+
+	var promo string
+	if move.Promo() != chess.NoPieceType {
+		promo = pieceLetters[move.Promo()]
+	}
+
+	return []string{
+		move.String(),
+		normalizeMove(g.SAN(move)),
+		letter + move.S1().String() + move.S2().String() + promo,
+	}
+}
+
+// Letters of the pieces in SAN
+var pieceLetters = map[chess.PieceType]string{
+	chess.King:   "K",
+	chess.Queen:  "Q",
+	chess.Rook:   "R",
+	chess.Bishop: "B",
+	chess.Knight: "N",
+}
+
+// Returns the legal move written as `s` in SAN, UCI or long algebraic
+// notation. An ambiguous SAN is an error listing the moves it can be.
+func (g *Game) ParseMove(s string) (*chess.Move, error) {
+	input := normalizeMove(s)
+	moves := g.ValidMoves()
+
+	for _, move := range moves {
+		for _, form := range g.moveForms(move) {
+			if form == input {
+				return move, nil
+			}
+		}
+	}
+
+	parts := looseSAN.FindStringSubmatch(input)
+	if parts == nil {
+		return nil, fmt.Errorf("illegal move `%s`", s)
+	}
+
+	var candidates []*chess.Move
+	for _, move := range moves {
+		piece := g.Position().Board().Piece(move.S1())
+		if pieceLetters[piece.Type()] != parts[1] || move.S2().String() != parts[4] || isCastling(g.Position(), move) {
+			continue
+		}
+		if parts[2] != "" && move.S1().File().String() != parts[2] {
+			continue
+		}
+		if parts[3] != "" && move.S1().Rank().String() != parts[3] {
+			continue
+		}
+		if parts[5] != "" && pieceLetters[move.Promo()] != strings.ToUpper(parts[5]) {
+			continue
+		}
+
+		candidates = append(candidates, move)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("illegal move `%s`", s)
+	case 1:
+		return candidates[0], nil
+	}
+
+	sans := make([]string, len(candidates))
+	for i, move := range candidates {
+		sans[i] = g.SAN(move)
+	}
+
+	return nil, fmt.Errorf("%w `%s`: it can be %s", ErrAmbiguousMove, s, strings.Join(sans, ", "))
+}
@@ -0,0 +1,84 @@
+package chess960
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNotation tests the moves played written in SAN, castling included.
+func TestNotation(t *testing.T) {
+	game := NewStandardGame()
+	for _, move := range []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "g8f6", "e1g1", "f6e4", "c4f7"} {
+		assert.NoError(t, game.MoveStr(move), move)
+	}
+
+	assert.Equal(t, []string{"e4", "e5", "Nf3", "Nc6", "Bc4", "Nf6", "O-O", "Nxe4", "Bxf7+"}, game.Notation())
+}
+
+// TestChess960Notation tests the castling on the square of the rook.
+func TestChess960Notation(t *testing.T) {
+	game, err := NewGame("1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/1R2K1R1 w KQkq - 0 1")
+	assert.NoError(t, err)
+
+	move, err := game.ParseMove("0-0-0")
+	assert.NoError(t, err)
+	assert.Equal(t, "e1b1", move.String())
+	assert.Equal(t, "O-O-O", game.SAN(move))
+
+	assert.NoError(t, game.MoveStr("e1b1"))
+	assert.NoError(t, game.MoveStr("e8g8"))
+	assert.Equal(t, []string{"O-O-O", "O-O"}, game.Notation())
+}
+
+// TestParseMove tests the moves written in SAN, UCI and long algebraic
+// notation.
+func TestParseMove(t *testing.T) {
+	game := NewStandardGame()
+	for _, move := range []string{"e2e4", "d7d5"} {
+		assert.NoError(t, game.MoveStr(move))
+	}
+
+	for input, expected := range map[string]string{
+		"exd5":   "e4d5",
+		"ed5":    "e4d5",
+		"e4d5":   "e4d5",
+		"e4xd5":  "e4d5",
+		"Nf3":    "g1f3",
+		"Ng1-f3": "g1f3",
+		"Qh5":    "d1h5",
+		"Bb5+":   "f1b5",
+	} {
+		move, err := game.ParseMove(input)
+		if assert.NoError(t, err, input) {
+			assert.Equal(t, expected, move.String(), input)
+		}
+	}
+
+	_, err := game.ParseMove("Nc4")
+	assert.EqualError(t, err, "illegal move `Nc4`")
+}
+
+// TestParseAmbiguousMove tests that an ambiguous SAN lists its moves.
+func TestParseAmbiguousMove(t *testing.T) {
+	game, err := NewGame("4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1")
+	assert.NoError(t, err)
+
+	_, err = game.ParseMove("Nd2")
+	assert.ErrorIs(t, err, ErrAmbiguousMove)
+	assert.ErrorContains(t, err, "Nbd2, Nfd2")
+
+	move, err := game.ParseMove("Nbd2")
+	assert.NoError(t, err)
+	assert.Equal(t, "b1d2", move.String())
+
+	game, err = NewGame("8/4P1k1/8/8/8/8/8/4K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	_, err = game.ParseMove("e8")
+	assert.ErrorIs(t, err, ErrAmbiguousMove)
+
+	move, err = game.ParseMove("e8=N")
+	assert.NoError(t, err)
+	assert.Equal(t, "e7e8n", move.String())
+}
@@ -0,0 +1,94 @@
+package chess960
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Width of the lines of the moves in PGN
+const pgnLineWidth = 80
+
+// Tag pair of a PGN header, as `[White "alice"]`
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Returns the game in PGN, with `tags` in its header. A starting position
+// which is not the standard one is added, and the result is the outcome of the
+// game unless a `Result` tag is given.
+func (g *Game) PGN(tags []Tag) string {
+	tags = slices.Clone(tags)
+
+	result := ""
+	for _, tag := range tags {
+		if tag.Name == "Result" {
+			result = tag.Value
+		}
+	}
+
+	if result == "" {
+		result = g.outcome.String()
+		tags = append(tags, Tag{"Result", result})
+	}
+	if g.StartFEN() != StandardFEN {
+		if !g.standard {
+			tags = append(tags, Tag{"Variant", "Chess960"})
+		}
+		tags = append(tags, Tag{"SetUp", "1"}, Tag{"FEN", g.StartFEN()})
+	}
+
+	var b strings.Builder
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for _, tag := range tags {
+		fmt.Fprintf(&b, "[%s \"%s\"]\n", tag.Name, escape.Replace(tag.Value))
+	}
+	b.WriteString("\n")
+
+	line := ""
+	for _, word := range append(g.movetext(), result) {
+		if line != "" && len(line)+1+len(word) > pgnLineWidth {
+			b.WriteString(line + "\n")
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	b.WriteString(line + "\n")
+
+	return b.String()
+}
+
+// Returns the moves in SAN with their numbers, which go on from the starting
+// position
+func (g *Game) movetext() []string {
+	number := 1
+	if fields := strings.Fields(g.StartFEN()); len(fields) == 6 {
+		if n, err := strconv.Atoi(fields[5]); err == nil && n > 0 {
+			number = n
+		}
+	}
+
+	var words []string
+	for i, san := range g.Notation() {
+		switch {
+		case g.positions[i].Turn() == chess.White:
+			words = append(words, fmt.Sprintf("%d.", number))
+		case i == 0:
+			words = append(words, fmt.Sprintf("%d...", number))
+		}
+		words = append(words, san)
+
+		if g.positions[i].Turn() == chess.Black {
+			number++
+		}
+	}
+
+	return words
+}
@@ -0,0 +1,43 @@
+package chess960
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPGN tests the header and the numbered moves of a standard game.
+func TestPGN(t *testing.T) {
+	game := NewStandardGame()
+	for _, move := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		assert.NoError(t, game.MoveStr(move), move)
+	}
+
+	pgn := game.PGN([]Tag{{"White", "alice"}, {"Black", `bob "the rook"`}})
+	assert.Equal(t, `[White "alice"]
+[Black "bob \"the rook\""]
+[Result "0-1"]
+
+1. f3 e5 2. g4 Qh4# 0-1
+`, pgn)
+}
+
+// TestChess960PGN tests the starting position of a Chess960 game, from a move
+// of black.
+func TestChess960PGN(t *testing.T) {
+	game, err := NewGame("1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/1R2K1R1 b KQkq - 0 7")
+	assert.NoError(t, err)
+
+	for _, move := range []string{"e8b8", "e1g1"} {
+		assert.NoError(t, game.MoveStr(move), move)
+	}
+
+	pgn := game.PGN([]Tag{{"Result", "1/2-1/2"}})
+	assert.Equal(t, `[Result "1/2-1/2"]
+[Variant "Chess960"]
+[SetUp "1"]
+[FEN "1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/1R2K1R1 b KQkq - 0 7"]
+
+7... O-O-O 8. O-O 1/2-1/2
+`, pgn)
+}
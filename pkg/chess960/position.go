@@ -0,0 +1,77 @@
+package chess960
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Number of the Chess960 starting positions
+const Positions = 960
+
+// Index of the standard starting position
+const StandardIndex = 518
+
+// Knights placements on the five squares left free by bishops and queen
+var knightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Returns the FEN of the `index`-th Chess960 starting position, following the
+// Scharnagl numbering
+func StartingFEN(index int) (string, error) {
+	if index < 0 || index >= Positions {
+		return "", fmt.Errorf("invalid Chess960 position %d", index)
+	}
+
+	var rank [8]byte
+	n := index
+
+	// Bishops on opposite colors
+	rank[2*(n%4)+1] = 'B'
+	n /= 4
+	rank[2*(n%4)] = 'B'
+	n /= 4
+
+	// Returns the `i`-th empty file
+	empty := func(i int) int {
+		for file := range rank {
+			if rank[file] == 0 {
+				if i == 0 {
+					return file
+				}
+				i--
+			}
+		}
+		return -1
+	}
+
+	rank[empty(n%6)] = 'Q'
+	n /= 6
+
+	// The second knight is placed after the first one took its square
+	knights := knightPlacements[n]
+	rank[empty(knights[1])] = 'N'
+	rank[empty(knights[0])] = 'N'
+
+	// The king stands between the rooks
+	rank[empty(0)] = 'R'
+	rank[empty(0)] = 'K'
+	rank[empty(0)] = 'R'
+
+	white := string(rank[:])
+	black := strings.ToLower(white)
+
+	return fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w KQkq - 0 1", black, white), nil
+}
+
+// Returns the index of the Chess960 starting position `fen`, false if it is
+// not one of them
+func Index(fen string) (int, bool) {
+	for i := range Positions {
+		if start, _ := StartingFEN(i); start == fen {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,47 @@
+package chess960
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStandardPosition tests that the position 518 is the standard one.
+func TestStandardPosition(t *testing.T) {
+	fen, err := StartingFEN(StandardIndex)
+	assert.NoError(t, err)
+	assert.Equal(t, StandardFEN, fen)
+}
+
+// TestStartingFEN tests the first Chess960 position and the bounds.
+func TestStartingFEN(t *testing.T) {
+	fen, err := StartingFEN(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1", fen)
+
+	_, err = StartingFEN(Positions)
+	assert.Error(t, err)
+
+	_, err = StartingFEN(-1)
+	assert.Error(t, err)
+}
+
+// TestStartingFENsAreValid tests that every position can start a game.
+func TestStartingFENsAreValid(t *testing.T) {
+	for i := range Positions {
+		fen, _ := StartingFEN(i)
+		game, err := NewGame(fen)
+		assert.NoError(t, err, fen)
+		assert.Equal(t, fen, game.StartFEN())
+	}
+}
+
+// TestIndex tests that the index of a starting position is found back.
+func TestIndex(t *testing.T) {
+	index, ok := Index(StandardFEN)
+	assert.True(t, ok)
+	assert.Equal(t, StandardIndex, index)
+
+	_, ok = Index("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	assert.False(t, ok)
+}
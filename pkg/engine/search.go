@@ -0,0 +1,254 @@
+// Package engine implements a small chess engine, which plays without any
+// external binary. It searches the moves with alpha-beta pruning up to a
+// depth or a time budget.
+package engine
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/notnil/chess"
+)
+
+// Default depth of the search, in plies
+const DefaultDepth = 3
+
+// Score of a checkmate, lowered by the plies needed to reach it
+const mateScore = 100000
+
+// Captures followed after the depth of the search, so the engine does not
+// stop in the middle of an exchange
+const quiescenceDepth = 4
+
+// Nodes searched between two checks of the time budget, the first check is
+// made at the first node
+const deadlineCheckNodes = 512
+
+// Returned when the player to move has no legal moves
+var ErrNoMoves = errors.New("no legal moves")
+
+// Options of the search
+type Options struct {
+	// Maximum depth, in plies. `DefaultDepth` if zero.
+	Depth int
+
+	// Time budget of a move. The search deepens until the depth or the budget
+	// is reached. No budget if zero.
+	MoveTime time.Duration
+}
+
+// Engine picks the moves of a game
+type Engine struct {
+	opts Options
+
+	deadline time.Time
+	nodes    int
+	stopped  bool
+}
+
+func New(opts Options) *Engine {
+	if opts.Depth <= 0 {
+		opts.Depth = DefaultDepth
+	}
+
+	return &Engine{opts: opts}
+}
+
+// Returns the best move found for the player to move, in UCI notation
+func (e *Engine) BestMove(game *chess960.Game) (string, error) {
+	move, _, err := e.Search(game)
+	return move, err
+}
+
+// Returns the best move found for the player to move and its score in
+// centipawns, from the point of view of the player to move
+func (e *Engine) Search(game *chess960.Game) (string, int, error) {
+	type candidate struct {
+		move  string
+		game  *chess960.Game
+		score int
+	}
+
+	var candidates []candidate
+	for _, move := range orderMoves(game.Position(), game.ValidMoves()) {
+		child := game.Clone()
+		if err := child.MoveStr(move.String()); err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{move: move.String(), game: child})
+	}
+
+	if len(candidates) == 0 {
+		return "", 0, ErrNoMoves
+	}
+
+	e.nodes = 0
+	e.stopped = false
+	e.deadline = time.Time{}
+	if e.opts.MoveTime > 0 {
+		e.deadline = time.Now().Add(e.opts.MoveTime)
+	}
+
+	best := candidates[0]
+
+	// Iterative deepening: the result of the last full iteration is kept if
+	// the time budget runs out. At depth 1 only the moves searched before it
+	// are kept, or the first legal move if none was searched.
+	for depth := 1; depth <= e.opts.Depth; depth++ {
+		alpha := -mateScore - 1
+
+		var searched []candidate
+		for _, c := range candidates {
+			c.score = e.rootScore(c.game, depth, alpha)
+
+			// The search of the move has been cut, its score is not reliable
+			if e.stopped {
+				break
+			}
+			searched = append(searched, c)
+
+			if c.score > alpha {
+				alpha = c.score
+			}
+		}
+
+		if e.stopped && (depth > 1 || len(searched) == 0) {
+			break
+		}
+
+		// The best moves are searched first at the next depth
+		slices.SortStableFunc(searched, func(a, b candidate) int {
+			return b.score - a.score
+		})
+		best = searched[0]
+
+		if e.stopped || best.score >= mateScore-depth {
+			break
+		}
+		candidates = searched
+	}
+
+	return best.move, best.score, nil
+}
+
+// Returns the score of a root move for the player who played it
+func (e *Engine) rootScore(game *chess960.Game, depth int, alpha int) int {
+	switch game.Outcome() {
+	case chess.NoOutcome:
+		return -e.alphaBeta(game.Position(), depth-1, 1, -mateScore-1, -alpha)
+	case chess.Draw:
+		return 0
+	default:
+		return mateScore - 1
+	}
+}
+
+// Returns the score of `position` for the player to move. Castling is only
+// considered at the root, where the moves come from the full game rules.
+func (e *Engine) alphaBeta(position *chess.Position, depth, ply, alpha, beta int) int {
+	if e.checkDeadline() {
+		return 0
+	}
+
+	moves := position.ValidMoves()
+	if len(moves) == 0 {
+		if position.Status() == chess.Checkmate {
+			return -mateScore + ply
+		}
+		return 0
+	}
+
+	if depth <= 0 {
+		return e.quiescence(position, quiescenceDepth, alpha, beta)
+	}
+
+	for _, move := range orderMoves(position, moves) {
+		score := -e.alphaBeta(position.Update(move), depth-1, ply+1, -beta, -alpha)
+		if e.stopped {
+			return 0
+		}
+
+		if score >= beta {
+			return beta
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	return alpha
+}
+
+// Follows the captures and promotions until the position is quiet
+func (e *Engine) quiescence(position *chess.Position, depth, alpha, beta int) int {
+	standPat := evaluate(position)
+	if depth == 0 || standPat >= beta {
+		return standPat
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+
+	for _, move := range orderMoves(position, position.ValidMoves()) {
+		if !move.HasTag(chess.Capture) && move.Promo() == chess.NoPieceType {
+			continue
+		}
+
+		if e.checkDeadline() {
+			return 0
+		}
+
+		score := -e.quiescence(position.Update(move), depth-1, -beta, -alpha)
+		if score >= beta {
+			return beta
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	return alpha
+}
+
+// Returns true if the time budget is over
+func (e *Engine) checkDeadline() bool {
+	e.nodes++
+	if !e.stopped && !e.deadline.IsZero() && e.nodes%deadlineCheckNodes == 1 && time.Now().After(e.deadline) {
+		e.stopped = true
+	}
+
+	return e.stopped
+}
+
+// Sorts the moves so the most promising ones are searched first: promotions,
+// then captures of valuable pieces by cheap ones
+func orderMoves(position *chess.Position, moves []*chess.Move) []*chess.Move {
+	board := position.Board()
+
+	priority := func(move *chess.Move) int {
+		p := 0
+		if move.Promo() != chess.NoPieceType {
+			p += pieceValues[move.Promo()]
+		}
+		if move.HasTag(chess.Capture) {
+			victim := pieceValues[chess.Pawn]
+			if piece := board.Piece(move.S2()); piece != chess.NoPiece {
+				victim = pieceValues[piece.Type()]
+			}
+			p += 10*victim - pieceValues[board.Piece(move.S1()).Type()]
+		}
+		if move.HasTag(chess.Check) {
+			p += 50
+		}
+		return p
+	}
+
+	sorted := slices.Clone(moves)
+	slices.SortStableFunc(sorted, func(a, b *chess.Move) int {
+		return priority(b) - priority(a)
+	})
+
+	return sorted
+}
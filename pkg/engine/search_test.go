@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGame(t *testing.T, fen string) *chess960.Game {
+	game, err := chess960.NewGame(fen)
+	assert.NoError(t, err)
+	return game
+}
+
+// TestMateInOne tests that the engine finds a back rank mate.
+func TestMateInOne(t *testing.T) {
+	game := newGame(t, "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+
+	move, score, err := New(Options{Depth: 2}).Search(game)
+	assert.NoError(t, err)
+	assert.Equal(t, "a1a8", move)
+	assert.Greater(t, score, mateScore-10)
+}
+
+// TestWinMaterial tests that the engine takes a hanging queen.
+func TestWinMaterial(t *testing.T) {
+	game := newGame(t, "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1")
+
+	move, err := New(Options{}).BestMove(game)
+	assert.NoError(t, err)
+	assert.Equal(t, "d1d5", move)
+}
+
+// TestAvoidMate tests that the engine defends against a mate in one.
+func TestAvoidMate(t *testing.T) {
+	game := newGame(t, "r5k1/8/8/8/8/8/5PPP/6K1 w - - 0 1")
+
+	move, err := New(Options{Depth: 2}).BestMove(game)
+	assert.NoError(t, err)
+	assert.Contains(t, []string{"f2f3", "f2f4", "g2g3", "g2g4", "h2h3", "h2h4", "g1f1"}, move)
+}
+
+// TestMoveTime tests that the time budget stops the search.
+func TestMoveTime(t *testing.T) {
+	game := chess960.NewStandardGame()
+
+	start := time.Now()
+	move, err := New(Options{Depth: 20, MoveTime: 200 * time.Millisecond}).BestMove(game)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, move)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+// TestTinyMoveTime tests that a budget which runs out at depth 1 still gives
+// a legal move.
+func TestTinyMoveTime(t *testing.T) {
+	for _, fen := range []string{
+		chess960.StandardFEN,
+		"r1bqk2r/pp2bppp/2n1pn2/2pp4/3P4/2PBPN2/PP1N1PPP/R1BQK2R w KQkq - 0 7",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	} {
+		game := newGame(t, fen)
+
+		move, err := New(Options{Depth: 20, MoveTime: time.Nanosecond}).BestMove(game)
+		assert.NoError(t, err, fen)
+		assert.NotEmpty(t, move, fen)
+
+		_, err = game.ParseMove(move)
+		assert.NoError(t, err, fen)
+	}
+}
+
+// TestNoMoves tests that a finished game has no best move.
+func TestNoMoves(t *testing.T) {
+	game := newGame(t, "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1")
+
+	_, err := New(Options{}).BestMove(game)
+	assert.ErrorIs(t, err, ErrNoMoves)
+}
@@ -152,6 +152,15 @@ func (n *TCPNetwork) Send(remoteID NetworkID, messageType []byte, payload []byte
 	return nil
 }
 
+// IsConnected returns true if there is an open connection to the remote peer
+func (n *TCPNetwork) IsConnected(remoteID NetworkID) bool {
+	n.Lock()
+	defer n.Unlock()
+
+	peerConn, exists := n.connections[remoteID]
+	return exists && peerConn.Conn != nil
+}
+
 // RegisterHandler registers a callback for a message type.
 func (n *TCPNetwork) RegisterHandler(callback NetworkMessageReceiveFunc) {
 	n.OnReceiveFn = callback
@@ -46,6 +46,9 @@ func TestPeerToPeerCommunication(t *testing.T) {
 	// Wait for connections to be established with a timeout
 	time.Sleep(5 * time.Second)
 
+	assert.True(t, peer1.IsConnected("peer-2"))
+	assert.True(t, peer2.IsConnected("peer-1"))
+
 	// Send a message from peer-1 to peer-2
 	err := peer1.Send("peer-2", []byte("simple-msg"), []byte("Hey from peer-1!"))
 	assert.NoError(t, err)
@@ -78,6 +81,8 @@ func TestSendFailure(t *testing.T) {
 	peer2 := NewTCPNetwork("peer-2", peer2Opts)
 	defer peer2.Close()
 
+	assert.False(t, peer1.IsConnected("peer-2"))
+
 	// Attempt to send a message without establishing a connection first
 	err := peer1.Send("peer-2", []byte("msg"), []byte("Message without connection"))
 	assert.Error(t, err, "Expected error when sending to a non-connected peer")
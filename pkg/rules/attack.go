@@ -0,0 +1,82 @@
+// Package rules implements the chess rules notnil/chess does not expose, so
+// the variants built on top of it can check their own moves.
+package rules
+
+import (
+	"slices"
+
+	"github.com/notnil/chess"
+)
+
+// Returns true if the king of `color` is attacked
+func InCheck(squares map[chess.Square]chess.Piece, color chess.Color) bool {
+	for sq, piece := range squares {
+		if piece == chess.NewPiece(chess.King, color) {
+			return Attacked(squares, sq, color.Other())
+		}
+	}
+
+	return false
+}
+
+// Returns true if a piece of `by` attacks `sq`
+func Attacked(squares map[chess.Square]chess.Piece, sq chess.Square, by chess.Color) bool {
+	file, rank := int(sq.File()), int(sq.Rank())
+
+	pieceAt := func(df, dr int) chess.Piece {
+		f, r := file+df, rank+dr
+		if f < 0 || f > 7 || r < 0 || r > 7 {
+			return chess.NoPiece
+		}
+		return squares[chess.NewSquare(chess.File(f), chess.Rank(r))]
+	}
+
+	isAttacker := func(piece chess.Piece, types ...chess.PieceType) bool {
+		return piece != chess.NoPiece && piece.Color() == by && slices.Contains(types, piece.Type())
+	}
+
+	// Pawns attack the diagonal squares in front of them
+	forward := 1
+	if by == chess.Black {
+		forward = -1
+	}
+	if isAttacker(pieceAt(-1, -forward), chess.Pawn) || isAttacker(pieceAt(1, -forward), chess.Pawn) {
+		return true
+	}
+
+	for _, d := range [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}} {
+		if isAttacker(pieceAt(d[0], d[1]), chess.Knight) {
+			return true
+		}
+	}
+
+	for _, d := range [][2]int{{1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}, {0, 1}} {
+		if isAttacker(pieceAt(d[0], d[1]), chess.King) {
+			return true
+		}
+
+		// Diagonals are slid by bishops and queens, lines by rooks and queens
+		slider := chess.Rook
+		if d[0] != 0 && d[1] != 0 {
+			slider = chess.Bishop
+		}
+
+		for step := 1; step < 8; step++ {
+			f, r := file+d[0]*step, rank+d[1]*step
+			if f < 0 || f > 7 || r < 0 || r > 7 {
+				break
+			}
+
+			piece := squares[chess.NewSquare(chess.File(f), chess.Rank(r))]
+			if piece == chess.NoPiece {
+				continue
+			}
+			if isAttacker(piece, slider, chess.Queen) {
+				return true
+			}
+			break
+		}
+	}
+
+	return false
+}
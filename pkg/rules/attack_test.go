@@ -0,0 +1,39 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAttacked tests the attacks of sliding pieces, knights and pawns.
+func TestAttacked(t *testing.T) {
+	squares := map[chess.Square]chess.Piece{
+		chess.A1: chess.NewPiece(chess.Rook, chess.White),
+		chess.C1: chess.NewPiece(chess.Knight, chess.Black),
+		chess.E4: chess.NewPiece(chess.Pawn, chess.White),
+		chess.B3: chess.NewPiece(chess.Knight, chess.White),
+	}
+
+	assert.True(t, Attacked(squares, chess.A8, chess.White))
+	assert.True(t, Attacked(squares, chess.B1, chess.White))
+	assert.False(t, Attacked(squares, chess.D1, chess.White))
+	assert.True(t, Attacked(squares, chess.D5, chess.White))
+	assert.False(t, Attacked(squares, chess.E5, chess.White))
+	assert.True(t, Attacked(squares, chess.C5, chess.White))
+	assert.True(t, Attacked(squares, chess.E2, chess.Black))
+}
+
+// TestInCheck tests that a king is in check when attacked.
+func TestInCheck(t *testing.T) {
+	squares := map[chess.Square]chess.Piece{
+		chess.E1: chess.NewPiece(chess.King, chess.White),
+		chess.E8: chess.NewPiece(chess.Queen, chess.Black),
+	}
+	assert.True(t, InCheck(squares, chess.White))
+
+	squares[chess.E4] = chess.NewPiece(chess.Bishop, chess.White)
+	assert.False(t, InCheck(squares, chess.White))
+	assert.False(t, InCheck(squares, chess.Black))
+}
@@ -0,0 +1,467 @@
+// Package uci plays and analyses games with an external engine, which speaks
+// the Universal Chess Interface over its standard input and output.
+package uci
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/notnil/chess"
+)
+
+// Default depth of the search, in plies, when no time budget is given
+const DefaultDepth = 12
+
+// Score of a checkmate, lowered by the moves needed to reach it
+const MateScore = 100000
+
+// Time waited for the answer to a command which does not search. A variable,
+// so the tests don't wait that long.
+var answerTimeout = 10 * time.Second
+
+var (
+	// Returned when the engine exited or closed its output
+	ErrClosed = errors.New("the engine exited")
+
+	// Returned when the player to move has no legal moves
+	ErrNoMoves = errors.New("no legal moves")
+
+	// Returned when the engine does not answer in time
+	ErrTimeout = errors.New("the engine does not answer")
+)
+
+// Options of the engine
+type Options struct {
+	// Path of the engine binary and its arguments
+	Path string
+	Args []string
+
+	// Maximum depth of a search, in plies. `DefaultDepth` if both the depth and
+	// the time budget are zero.
+	Depth int
+
+	// Time budget of a search
+	MoveTime time.Duration
+
+	// Options sent to the engine with `setoption`, as `Hash` or `Threads`
+	Settings map[string]string
+}
+
+// Result of a search
+type Evaluation struct {
+	Turn     chess.Color // Player to move
+	Depth    int         // Depth reached, in plies
+	Score    int         // Centipawns, from the point of view of the player to move
+	Mate     int         // Moves to mate, negative if the player to move gets mated
+	BestMove string      // In UCI notation, empty if the game is over
+	PV       []string    // Best line found
+}
+
+// Returns the score from the point of view of white
+func (e Evaluation) WhiteScore() int {
+	if e.Turn == chess.Black {
+		return -e.Score
+	}
+	return e.Score
+}
+
+// Engine is a running UCI engine. It implements `multiplayer.Engine`, so it can
+// play as a virtual peer.
+type Engine struct {
+	sync.Mutex
+
+	opts     Options
+	name     string
+	cmd      *exec.Cmd
+	in       io.WriteCloser
+	lines    chan string
+	done     chan struct{}
+	chess960 bool
+	closed   bool
+}
+
+// Starts the engine at `opts.Path` and waits until it is ready
+func Start(opts Options) (*Engine, error) {
+	if opts.Path == "" {
+		return nil, errors.New("no engine path")
+	}
+
+	cmd := exec.Command(opts.Path, opts.Args...)
+
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	e, err := newEngine(in, out, opts)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	e.cmd = cmd
+
+	return e, nil
+}
+
+// Speaks UCI over `in` and `out`, then waits until the engine is ready
+func newEngine(in io.WriteCloser, out io.Reader, opts Options) (*Engine, error) {
+	if opts.Depth <= 0 && opts.MoveTime <= 0 {
+		opts.Depth = DefaultDepth
+	}
+
+	e := &Engine{
+		opts:  opts,
+		in:    in,
+		lines: make(chan string),
+		done:  make(chan struct{}),
+	}
+	go e.read(out)
+
+	if err := e.send("uci"); err != nil {
+		return nil, err
+	}
+
+	lines, err := e.waitFor("uciok", answerTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		if name, ok := strings.CutPrefix(line, "id name "); ok {
+			e.name = name
+		}
+	}
+
+	// Sorted, so the engine always gets the same commands
+	names := make([]string, 0, len(opts.Settings))
+	for name := range opts.Settings {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if err := e.send(fmt.Sprintf("setoption name %s value %s", name, opts.Settings[name])); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.ready(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Returns the name sent by the engine
+func (e *Engine) Name() string {
+	return e.name
+}
+
+// Reads the output of the engine, one line at a time
+func (e *Engine) read(out io.Reader) {
+	defer close(e.lines)
+
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// Once closed, the output is drained so the engine never blocks on it
+		select {
+		case e.lines <- line:
+		case <-e.done:
+		}
+	}
+}
+
+func (e *Engine) send(command string) error {
+	if _, err := io.WriteString(e.in, command+"\n"); err != nil {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Returns the lines read until the one starting with `token`, included. No
+// timeout if `timeout` is zero.
+func (e *Engine) waitFor(token string, timeout time.Duration) ([]string, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var lines []string
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return lines, ErrClosed
+			}
+
+			lines = append(lines, line)
+			if fields := strings.Fields(line); fields[0] == token {
+				return lines, nil
+			}
+		case <-expired:
+			return lines, ErrTimeout
+		}
+	}
+}
+
+func (e *Engine) ready() error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+
+	_, err := e.waitFor("readyok", answerTimeout)
+	return err
+}
+
+// Switches the castling notation of the engine to the one of `game`
+func (e *Engine) setChess960(game *chess960.Game) error {
+	chess960 := !game.StandardCastling()
+	if chess960 == e.chess960 {
+		return nil
+	}
+
+	if err := e.send(fmt.Sprintf("setoption name UCI_Chess960 value %t", chess960)); err != nil {
+		return err
+	}
+	e.chess960 = chess960
+
+	return e.ready()
+}
+
+// Returns the `position` command of the current position of `game`
+func positionCommand(game *chess960.Game) string {
+	command := "position fen " + game.StartFEN()
+
+	if moves := game.Moves(); len(moves) > 0 {
+		command += " moves"
+		for _, move := range moves {
+			command += " " + move.String()
+		}
+	}
+
+	return command
+}
+
+// Returns the `go` command, with the depth and the time budget of the search
+func (e *Engine) goCommand() string {
+	command := "go"
+	if e.opts.Depth > 0 {
+		command += fmt.Sprintf(" depth %d", e.opts.Depth)
+	}
+	if e.opts.MoveTime > 0 {
+		command += fmt.Sprintf(" movetime %d", e.opts.MoveTime.Milliseconds())
+	}
+
+	return command
+}
+
+// Returns the evaluation of the current position of `game`
+func (e *Engine) Search(game *chess960.Game) (Evaluation, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	evaluation := Evaluation{Turn: game.Position().Turn()}
+
+	if e.closed {
+		return evaluation, ErrClosed
+	}
+
+	if err := e.setChess960(game); err != nil {
+		return evaluation, err
+	}
+
+	if err := e.send(positionCommand(game)); err != nil {
+		return evaluation, err
+	}
+
+	if err := e.send(e.goCommand()); err != nil {
+		return evaluation, err
+	}
+
+	// A search without time budget can last any time
+	var timeout time.Duration
+	if e.opts.MoveTime > 0 {
+		timeout = e.opts.MoveTime + answerTimeout
+	}
+
+	lines, err := e.waitFor("bestmove", timeout)
+	if errors.Is(err, ErrTimeout) {
+		// The late answer would be read as the one of the next search
+		if err := e.stop(); err != nil {
+			return evaluation, err
+		}
+		return evaluation, ErrTimeout
+	}
+	if err != nil {
+		return evaluation, err
+	}
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "info ") {
+			parseInfo(&evaluation, strings.Fields(line)[1:])
+		}
+	}
+
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 2 || fields[1] == "(none)" || fields[1] == "0000" {
+		return evaluation, ErrNoMoves
+	}
+	evaluation.BestMove = fields[1]
+
+	return evaluation, nil
+}
+
+// Stops the running search and drops its output, up to its `bestmove`
+func (e *Engine) stop() error {
+	if err := e.send("stop"); err != nil {
+		return err
+	}
+
+	_, err := e.waitFor("bestmove", answerTimeout)
+	return err
+}
+
+// Reads the depth, the score and the best line of an `info` line. Lines
+// without a score, such as the current move ones, are ignored.
+func parseInfo(evaluation *Evaluation, fields []string) {
+	if !slices.Contains(fields, "score") {
+		return
+	}
+
+	for i := 0; i < len(fields)-1; i++ {
+		switch fields[i] {
+		case "depth":
+			evaluation.Depth, _ = strconv.Atoi(fields[i+1])
+		case "score":
+			if i+2 >= len(fields) {
+				return
+			}
+
+			value, err := strconv.Atoi(fields[i+2])
+			if err != nil {
+				continue
+			}
+
+			switch fields[i+1] {
+			case "cp":
+				evaluation.Score, evaluation.Mate = value, 0
+			case "mate":
+				evaluation.Mate = value
+				if value > 0 {
+					evaluation.Score = MateScore - value
+				} else {
+					evaluation.Score = -MateScore - value
+				}
+			}
+		case "pv":
+			evaluation.PV = slices.Clone(fields[i+1:])
+			return
+		}
+	}
+}
+
+// Returns the best move found for the player to move, in UCI notation
+func (e *Engine) BestMove(game *chess960.Game) (string, error) {
+	evaluation, err := e.Search(game)
+	return evaluation.BestMove, err
+}
+
+// Returns the evaluation of every position of `game`, from the starting one.
+// The positions where the game is over are scored without the engine.
+func (e *Engine) Analyse(game *chess960.Game) ([]Evaluation, error) {
+	replay, err := chess960.NewGame(game.StartFEN())
+	if err != nil {
+		return nil, err
+	}
+
+	moves := game.Moves()
+	evaluations := make([]Evaluation, 0, len(moves)+1)
+
+	for i := 0; ; i++ {
+		if replay.Outcome() != chess.NoOutcome {
+			evaluations = append(evaluations, finalEvaluation(replay))
+		} else {
+			evaluation, err := e.Search(replay)
+			if err != nil {
+				return evaluations, err
+			}
+			evaluations = append(evaluations, evaluation)
+		}
+
+		if i == len(moves) {
+			return evaluations, nil
+		}
+
+		if err := replay.MoveStr(moves[i].String()); err != nil {
+			return evaluations, err
+		}
+	}
+}
+
+// Returns the evaluation of a position where the game is over
+func finalEvaluation(game *chess960.Game) Evaluation {
+	evaluation := Evaluation{Turn: game.Position().Turn()}
+	if game.Method() == chess.Checkmate {
+		evaluation.Score = -MateScore
+	}
+
+	return evaluation
+}
+
+// Quits the engine
+func (e *Engine) Close() error {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	close(e.done)
+	e.send("quit")
+	e.in.Close()
+
+	if e.cmd == nil {
+		return nil
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- e.cmd.Wait()
+	}()
+
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(answerTimeout):
+		e.cmd.Process.Kill()
+		return <-exited
+	}
+}
@@ -0,0 +1,277 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+// Engine which answers every command with the lines of its script. The
+// commands it receives are recorded.
+type fakeEngine struct {
+	sync.Mutex
+
+	// Lines sent after a command, by the first word of the command
+	script   map[string][]string
+	received []string
+
+	// The engine exits on this command, as on `quit`
+	exitOn string
+
+	// Number of searches answered only when they are stopped
+	stalls  int
+	stalled bool
+}
+
+func newFakeEngine(search ...string) *fakeEngine {
+	return &fakeEngine{script: map[string][]string{
+		"uci":     {"id name Fake 1.0", "id author Rahanna", "option name Hash type spin default 16 min 1 max 1024", "uciok"},
+		"isready": {"readyok"},
+		"go":      search,
+	}}
+}
+
+// Runs the engine until `quit` or the end of its input
+func (f *fakeEngine) run(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		command := scanner.Text()
+
+		f.Lock()
+		f.received = append(f.received, command)
+		f.Unlock()
+
+		name := strings.Fields(command)[0]
+		if name == "quit" || name == f.exitOn {
+			return
+		}
+
+		if name == "go" && f.stalls > 0 {
+			f.stalls--
+			f.stalled = true
+			continue
+		}
+		if name == "stop" && f.stalled {
+			f.stalled = false
+			name = "go"
+		}
+
+		for _, line := range f.script[name] {
+			fmt.Fprintln(out, line)
+		}
+	}
+}
+
+func (f *fakeEngine) commands() []string {
+	f.Lock()
+	defer f.Unlock()
+
+	return append([]string(nil), f.received...)
+}
+
+// Returns an engine connected to `fake` through pipes
+func connect(t *testing.T, fake *fakeEngine, opts Options) *Engine {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+
+	go func() {
+		fake.run(inReader, outWriter)
+		inReader.Close()
+		outWriter.Close()
+	}()
+
+	e, err := newEngine(inWriter, outReader, opts)
+	assert.NoError(t, err)
+	t.Cleanup(func() { e.Close() })
+
+	return e
+}
+
+func TestMain(m *testing.M) {
+	// The test binary itself plays as the engine in `TestStart`
+	if os.Getenv("RAHANNA_FAKE_ENGINE") == "1" {
+		newFakeEngine("info depth 1 score cp 10 pv e2e4", "bestmove e2e4").run(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// TestHandshake tests that the engine is set up before the first search.
+func TestHandshake(t *testing.T) {
+	fake := newFakeEngine()
+	e := connect(t, fake, Options{Settings: map[string]string{"Threads": "2", "Hash": "64"}})
+
+	assert.Equal(t, "Fake 1.0", e.Name())
+	assert.Equal(t, []string{
+		"uci",
+		"setoption name Hash value 64",
+		"setoption name Threads value 2",
+		"isready",
+	}, fake.commands())
+}
+
+// TestBestMove tests the commands of a search and the parse of its result.
+func TestBestMove(t *testing.T) {
+	fake := newFakeEngine(
+		"info depth 1 score cp 20 pv e7e5",
+		"info depth 2 currmove e7e5 currmovenumber 1",
+		"info depth 2 seldepth 4 score cp -15 nodes 120 pv c7c5 g1f3",
+		"bestmove c7c5 ponder g1f3",
+	)
+	e := connect(t, fake, Options{Depth: 2, MoveTime: 500_000_000})
+
+	game := chess960.NewStandardGame()
+	assert.NoError(t, game.MoveStr("e2e4"))
+
+	evaluation, err := e.Search(game)
+	assert.NoError(t, err)
+	assert.Equal(t, Evaluation{
+		Turn:     chess.Black,
+		Depth:    2,
+		Score:    -15,
+		BestMove: "c7c5",
+		PV:       []string{"c7c5", "g1f3"},
+	}, evaluation)
+	assert.Equal(t, 15, evaluation.WhiteScore())
+
+	commands := fake.commands()
+	assert.Equal(t, []string{
+		"position fen " + chess960.StandardFEN + " moves e2e4",
+		"go depth 2 movetime 500",
+	}, commands[len(commands)-2:])
+}
+
+// TestChess960 tests that the engine switches to the Chess960 castling only
+// for the games which need it.
+func TestChess960(t *testing.T) {
+	fake := newFakeEngine("bestmove e1h1")
+	e := connect(t, fake, Options{})
+
+	fen, err := chess960.StartingFEN(0)
+	assert.NoError(t, err)
+	game, err := chess960.NewGame(fen)
+	assert.NoError(t, err)
+
+	_, err = e.BestMove(game)
+	assert.NoError(t, err)
+	assert.Contains(t, fake.commands(), "setoption name UCI_Chess960 value true")
+
+	_, err = e.BestMove(chess960.NewStandardGame())
+	assert.NoError(t, err)
+	assert.Contains(t, fake.commands(), "setoption name UCI_Chess960 value false")
+	assert.Contains(t, fake.commands(), fmt.Sprintf("go depth %d", DefaultDepth))
+}
+
+// TestMateScore tests the scores of the mates found by the engine.
+func TestMateScore(t *testing.T) {
+	fake := newFakeEngine("info depth 3 score mate -2 pv h7h6 a1a8", "bestmove h7h6")
+	e := connect(t, fake, Options{})
+
+	evaluation, err := e.Search(chess960.NewStandardGame())
+	assert.NoError(t, err)
+	assert.Equal(t, -2, evaluation.Mate)
+	assert.Equal(t, -MateScore+2, evaluation.Score)
+}
+
+// TestNoMoves tests that a search without a move is an error.
+func TestNoMoves(t *testing.T) {
+	e := connect(t, newFakeEngine("info depth 0 score mate 0", "bestmove (none)"), Options{})
+
+	_, err := e.BestMove(chess960.NewStandardGame())
+	assert.ErrorIs(t, err, ErrNoMoves)
+}
+
+// TestEngineExits tests that a search fails when the engine exits.
+func TestEngineExits(t *testing.T) {
+	fake := newFakeEngine()
+	fake.exitOn = "go"
+	e := connect(t, fake, Options{})
+
+	_, err := e.BestMove(chess960.NewStandardGame())
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+// TestSearchTimeout tests that a search which does not end in time is stopped,
+// and its answer is not read as the one of the next search.
+func TestSearchTimeout(t *testing.T) {
+	timeout := answerTimeout
+	answerTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { answerTimeout = timeout })
+
+	fake := newFakeEngine("bestmove e2e4")
+	fake.stalls = 1
+	e := connect(t, fake, Options{MoveTime: time.Millisecond})
+
+	_, err := e.BestMove(chess960.NewStandardGame())
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Contains(t, fake.commands(), "stop")
+
+	move, err := e.BestMove(chess960.NewStandardGame())
+	assert.NoError(t, err)
+	assert.Equal(t, "e2e4", move)
+
+	select {
+	case line := <-e.lines:
+		t.Errorf("unexpected line `%s` after the search", line)
+	case <-time.After(answerTimeout):
+	}
+}
+
+// TestAnalyse tests that every position of a game is evaluated, and the final
+// checkmate without searching.
+func TestAnalyse(t *testing.T) {
+	fake := newFakeEngine("info depth 1 score cp 30 pv e2e4", "bestmove e2e4")
+	e := connect(t, fake, Options{})
+
+	game := chess960.NewStandardGame()
+	for _, move := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		assert.NoError(t, game.MoveStr(move))
+	}
+
+	evaluations, err := e.Analyse(game)
+	assert.NoError(t, err)
+	assert.Len(t, evaluations, 5)
+
+	assert.Equal(t, 30, evaluations[0].WhiteScore())
+	assert.Equal(t, -30, evaluations[1].WhiteScore())
+	assert.Equal(t, -MateScore, evaluations[4].WhiteScore())
+	assert.Empty(t, evaluations[4].BestMove)
+
+	searches := 0
+	for _, command := range fake.commands() {
+		if strings.HasPrefix(command, "go") {
+			searches++
+		}
+	}
+	assert.Equal(t, 4, searches)
+}
+
+// TestStart tests an engine running in another process.
+func TestStart(t *testing.T) {
+	t.Setenv("RAHANNA_FAKE_ENGINE", "1")
+
+	e, err := Start(Options{Path: os.Args[0], Depth: 1})
+	assert.NoError(t, err)
+
+	move, err := e.BestMove(chess960.NewStandardGame())
+	assert.NoError(t, err)
+	assert.Equal(t, "e2e4", move)
+
+	assert.NoError(t, e.Close())
+}
+
+// TestStartWithoutPath tests that an engine needs a binary.
+func TestStartWithoutPath(t *testing.T) {
+	_, err := Start(Options{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+// Package local keeps the games played offline on a single terminal. They are
+// stored next to `.rahannarc` and never reach the API or the network.
+package local
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/ui/storage"
+	"github.com/notnil/chess"
+)
+
+// File of a game in its directory
+const gameFile = "game.json"
+
+type Mode string
+
+const (
+	// Two players share the terminal and move in turn
+	HotseatMode Mode = "hotseat"
+
+	// One player faces the built-in engine
+	ComputerMode Mode = "computer"
+)
+
+// An offline game and its history
+type Game struct {
+	ID        int       `json:"id"`
+	Mode      Mode      `json:"mode"`
+	StartFEN  string    `json:"start_fen"`
+	Computer  string    `json:"computer"` // Color played by the computer, empty in hotseat
+	Moves     []string  `json:"moves"`    // UCI notation
+	Outcome   string    `json:"outcome"`
+	Method    string    `json:"method"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Creates and saves a new game from `startFEN`. `computer` is the color played
+// by the computer in `ComputerMode`.
+func New(mode Mode, startFEN string, computer chess.Color) (*Game, error) {
+	if startFEN == "" {
+		startFEN = chess960.StandardFEN
+	}
+
+	if _, err := chess960.NewGame(startFEN); err != nil {
+		return nil, err
+	}
+
+	game := &Game{
+		Mode:      mode,
+		StartFEN:  startFEN,
+		Outcome:   chess.NoOutcome.String(),
+		CreatedAt: time.Now(),
+	}
+
+	switch mode {
+	case HotseatMode:
+	case ComputerMode:
+		if computer == chess.NoColor {
+			return nil, errors.New("the computer needs a color")
+		}
+		game.Computer = computer.Name()
+	default:
+		return nil, errors.New("mode not recognized")
+	}
+
+	ids, err := storage.LocalGameIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	game.ID = 1
+	if len(ids) > 0 {
+		game.ID = ids[len(ids)-1] + 1
+	}
+
+	return game, game.Save()
+}
+
+// Loads the game with `id`
+func Load(id int) (*Game, error) {
+	game, err := storage.Read[Game](storage.LocalGameDir(id), gameFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &game, nil
+}
+
+// Returns every saved game, the last played first
+func List() ([]Game, error) {
+	ids, err := storage.LocalGameIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var games []Game
+	for _, id := range ids {
+		game, err := Load(id)
+		if err != nil {
+			continue
+		}
+		games = append(games, *game)
+	}
+
+	slices.SortStableFunc(games, func(a, b Game) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+
+	return games, nil
+}
+
+func (g *Game) Save() error {
+	g.UpdatedAt = time.Now()
+	return storage.Write(storage.LocalGameDir(g.ID), gameFile, g)
+}
+
+// Returns true if the game has not ended yet
+func (g *Game) IsOngoing() bool {
+	return g.Outcome == chess.NoOutcome.String()
+}
+
+// Returns the color played by the computer, `chess.NoColor` in hotseat
+func (g *Game) ComputerColor() chess.Color {
+	switch g.Computer {
+	case chess.White.Name():
+		return chess.White
+	case chess.Black.Name():
+		return chess.Black
+	}
+	return chess.NoColor
+}
+
+// Rebuilds the game from its starting position and its moves
+func (g *Game) Replay() (*chess960.Game, error) {
+	game, err := chess960.NewGame(g.StartFEN)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, move := range g.Moves {
+		if err := game.MoveStr(move); err != nil {
+			return nil, err
+		}
+	}
+
+	return game, nil
+}
+
+// Copies the moves and the outcome of `game`
+func (g *Game) Record(game *chess960.Game) {
+	g.Moves = nil
+	for _, move := range game.Moves() {
+		g.Moves = append(g.Moves, move.String())
+	}
+
+	g.Outcome = game.Outcome().String()
+	g.Method = ""
+	if game.Outcome() != chess.NoOutcome {
+		g.Method = game.Method().String()
+	}
+}
+
+// Ends the game without a move, as for a resignation or an agreed draw
+func (g *Game) End(outcome chess.Outcome, method chess.Method) {
+	g.Outcome = outcome.String()
+	g.Method = method.String()
+}
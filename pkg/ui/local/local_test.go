@@ -0,0 +1,86 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNewGame tests that new games get increasing IDs and are saved.
+func TestNewGame(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	first, err := New(HotseatMode, "", chess.NoColor)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, first.ID)
+	assert.Equal(t, chess960.StandardFEN, first.StartFEN)
+	assert.True(t, first.IsOngoing())
+
+	second, err := New(ComputerMode, "", chess.Black)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, second.ID)
+	assert.Equal(t, chess.Black, second.ComputerColor())
+
+	loaded, err := Load(2)
+	assert.NoError(t, err)
+	assert.Equal(t, second.Mode, loaded.Mode)
+	assert.Equal(t, chess.Black, loaded.ComputerColor())
+}
+
+// TestNewGameErrors tests the games which can't be created.
+func TestNewGameErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	_, err := New(ComputerMode, "", chess.NoColor)
+	assert.Error(t, err)
+
+	_, err = New(HotseatMode, "not a fen", chess.NoColor)
+	assert.Error(t, err)
+
+	_, err = New(Mode("online"), "", chess.NoColor)
+	assert.Error(t, err)
+}
+
+// TestRecordReplay tests that the history of a game is kept across saves.
+func TestRecordReplay(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	game, err := New(HotseatMode, "", chess.NoColor)
+	assert.NoError(t, err)
+
+	board := chess960.NewStandardGame()
+	for _, move := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		assert.NoError(t, board.MoveStr(move))
+	}
+	game.Record(board)
+	assert.NoError(t, game.Save())
+
+	loaded, err := Load(game.ID)
+	assert.NoError(t, err)
+	assert.False(t, loaded.IsOngoing())
+	assert.Equal(t, chess.BlackWon.String(), loaded.Outcome)
+	assert.Equal(t, chess.Checkmate.String(), loaded.Method)
+
+	replay, err := loaded.Replay()
+	assert.NoError(t, err)
+	assert.Equal(t, board.FEN(), replay.FEN())
+}
+
+// TestList tests that the last played games come first.
+func TestList(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	first, _ := New(HotseatMode, "", chess.NoColor)
+	second, _ := New(HotseatMode, "", chess.NoColor)
+
+	first.End(chess.Draw, chess.DrawOffer)
+	assert.NoError(t, first.Save())
+
+	games, err := List()
+	assert.NoError(t, err)
+	assert.Len(t, games, 2)
+	assert.Equal(t, []int{first.ID, second.ID}, []int{games[0].ID, games[1].ID})
+	assert.Equal(t, chess.DrawOffer.String(), games[0].Method)
+}
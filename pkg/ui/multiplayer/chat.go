@@ -0,0 +1,29 @@
+package multiplayer
+
+import (
+	"time"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+)
+
+// The recipients of a chat message
+type ChatChannel string
+
+const (
+	// Only the players of the same team
+	TeamChannel ChatChannel = "team"
+
+	// Every player and spectator of the game
+	AllChannel ChatChannel = "all"
+)
+
+// Maximum length of a chat message
+const MaxChatLength = 280
+
+// Payload of a `ChatGameMessage`
+type ChatMessage struct {
+	Channel   ChatChannel   `json:"channel"`
+	Author    p2p.NetworkID `json:"author"`
+	Text      string        `json:"text"`
+	Timestamp time.Time     `json:"timestamp"`
+}
@@ -0,0 +1,221 @@
+package multiplayer
+
+import (
+	"maps"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// Clock keeps the remaining time of both colors. Every peer keeps its own
+// clock, punched with the time of each move.
+type Clock struct {
+	increment time.Duration
+	delay     time.Duration
+	remaining map[chess.Color]time.Duration
+	running   chess.Color
+	since     time.Time
+	flagged   chess.Color
+	paused    bool
+
+	// State before every punch, to roll the clock back with the moves
+	history []clockState
+}
+
+// Remaining times and running color before a punch
+type clockState struct {
+	remaining map[chess.Color]time.Duration
+	running   chess.Color
+}
+
+// State of a clock sent in the game snapshots
+type ClockState struct {
+	White   time.Duration `json:"white"`
+	Black   time.Duration `json:"black"`
+	Running chess.Color   `json:"running"`
+}
+
+// Creates a stopped clock with `base` time for each color. After every move
+// the player gets `increment` more time, and the first `delay` of every move
+// is not counted.
+func NewClock(base, increment, delay time.Duration) *Clock {
+	return &Clock{
+		increment: increment,
+		delay:     delay,
+		remaining: map[chess.Color]time.Duration{
+			chess.White: base,
+			chess.Black: base,
+		},
+		running: chess.NoColor,
+		flagged: chess.NoColor,
+	}
+}
+
+// Returns the time spent by the running color from its last punch until `now`.
+// No time is spent while the clock is paused.
+func (c *Clock) spent(now time.Time) time.Duration {
+	if c.paused {
+		return 0
+	}
+	return max(now.Sub(c.since)-c.delay, 0)
+}
+
+// Returns the time left to `color` at the time `now`
+func (c *Clock) Remaining(color chess.Color, now time.Time) time.Duration {
+	left := c.remaining[color]
+	if color == c.running {
+		left -= c.spent(now)
+	}
+
+	return max(left, 0)
+}
+
+// Returns the color whose clock is running
+func (c *Clock) Running() chess.Color {
+	return c.running
+}
+
+// Stops the clock of `color`, which moved at the time `at`, and starts the
+// clock of its opponent. The first move of the game only starts the clock of
+// the opponent.
+func (c *Clock) Punch(color chess.Color, at time.Time) {
+	c.history = append(c.history, clockState{remaining: maps.Clone(c.remaining), running: c.running})
+
+	if c.running == color {
+		c.remaining[color] -= c.spent(at)
+		if c.remaining[color] <= 0 {
+			c.remaining[color] = 0
+			c.flagged = color
+			c.running = chess.NoColor
+			return
+		}
+		c.remaining[color] += c.increment
+	}
+
+	c.running = color.Other()
+	c.since = at
+}
+
+// Stops both clocks at the time `at`
+func (c *Clock) Stop(at time.Time) {
+	if c.running != chess.NoColor {
+		c.remaining[c.running] = c.Remaining(c.running, at)
+		c.running = chess.NoColor
+	}
+}
+
+// Stops the running clock at the time `at`, until `Resume`
+func (c *Clock) Pause(at time.Time) {
+	if c.paused {
+		return
+	}
+
+	if c.running != chess.NoColor {
+		c.remaining[c.running] = c.Remaining(c.running, at)
+	}
+	c.paused = true
+}
+
+// Starts again at the time `at` the clock stopped by `Pause`
+func (c *Clock) Resume(at time.Time) {
+	if !c.paused {
+		return
+	}
+
+	c.paused = false
+	c.since = at
+}
+
+// Returns true if the clock is paused
+func (c *Clock) Paused() bool {
+	return c.paused
+}
+
+// Rolls back the last `n` punches, as their moves have been taken back. The
+// color to move gets its time back from `at`. Returns false if the clock
+// doesn't know that many punches, as after a restore.
+func (c *Clock) Undo(n int, at time.Time) bool {
+	if n <= 0 {
+		return true
+	}
+	if n > len(c.history) {
+		return false
+	}
+
+	state := c.history[len(c.history)-n]
+	c.history = c.history[:len(c.history)-n]
+
+	c.remaining = state.remaining
+	c.running = state.running
+	c.flagged = chess.NoColor
+	c.since = at
+
+	return true
+}
+
+// Returns the color which ran out of time at the time `now`, if any
+func (c *Clock) Flagged(now time.Time) chess.Color {
+	if c.flagged != chess.NoColor {
+		return c.flagged
+	}
+
+	if c.running != chess.NoColor && c.Remaining(c.running, now) == 0 {
+		return c.running
+	}
+
+	return chess.NoColor
+}
+
+// Returns the state of the clock at the time `now`
+func (c *Clock) State(now time.Time) ClockState {
+	return ClockState{
+		White:   c.Remaining(chess.White, now),
+		Black:   c.Remaining(chess.Black, now),
+		Running: c.running,
+	}
+}
+
+// Restores the clock from a state, starting the running color at `now`
+func (c *Clock) Restore(state ClockState, now time.Time) {
+	c.remaining[chess.White] = state.White
+	c.remaining[chess.Black] = state.Black
+	c.running = state.Running
+	c.since = now
+}
+
+// Returns the local time when a move has been sent. The timestamp of the
+// sender is converted with its clock `offset`, and it is bounded by the round
+// trip time, so a sender can't claim more than the network latency.
+func CompensatedTime(sentAt time.Time, receivedAt time.Time, offset time.Duration, rtt time.Duration) time.Time {
+	at := sentAt.Add(-offset)
+
+	if earliest := receivedAt.Add(-rtt); at.Before(earliest) {
+		return earliest
+	}
+
+	if at.After(receivedAt) {
+		return receivedAt
+	}
+
+	return at
+}
+
+// Returns true if `color` has enough material to checkmate: a pawn, a rook, a
+// queen or at least two minor pieces
+func HasMatingMaterial(board *chess.Board, color chess.Color) bool {
+	minors := 0
+	for _, piece := range board.SquareMap() {
+		if piece.Color() != color {
+			continue
+		}
+
+		switch piece.Type() {
+		case chess.Pawn, chess.Rook, chess.Queen:
+			return true
+		case chess.Knight, chess.Bishop:
+			minors++
+		}
+	}
+
+	return minors >= 2
+}
@@ -0,0 +1,116 @@
+package multiplayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClockIncrement tests that the clock starts with the first move and adds
+// the increment after every move.
+func TestClockIncrement(t *testing.T) {
+	start := time.Now()
+	clock := NewClock(time.Minute, 2*time.Second, 0)
+
+	clock.Punch(chess.White, start)
+	assert.Equal(t, chess.Black, clock.Running())
+	assert.Equal(t, time.Minute, clock.Remaining(chess.White, start.Add(time.Hour)))
+
+	clock.Punch(chess.Black, start.Add(10*time.Second))
+	assert.Equal(t, 52*time.Second, clock.Remaining(chess.Black, start.Add(time.Hour)))
+	assert.Equal(t, 55*time.Second, clock.Remaining(chess.White, start.Add(15*time.Second)))
+	assert.Equal(t, chess.NoColor, clock.Flagged(start.Add(15*time.Second)))
+}
+
+// TestClockDelay tests that the first part of every move is not counted.
+func TestClockDelay(t *testing.T) {
+	start := time.Now()
+	clock := NewClock(time.Minute, 0, 5*time.Second)
+
+	clock.Punch(chess.White, start)
+	assert.Equal(t, time.Minute, clock.Remaining(chess.Black, start.Add(3*time.Second)))
+
+	clock.Punch(chess.Black, start.Add(8*time.Second))
+	assert.Equal(t, 57*time.Second, clock.Remaining(chess.Black, start.Add(time.Hour)))
+}
+
+// TestClockFlag tests that a color runs out of time with or without moving.
+func TestClockFlag(t *testing.T) {
+	start := time.Now()
+	clock := NewClock(time.Minute, 0, 0)
+
+	clock.Punch(chess.White, start)
+	assert.Equal(t, chess.Black, clock.Flagged(start.Add(2*time.Minute)))
+
+	clock.Punch(chess.Black, start.Add(2*time.Minute))
+	assert.Equal(t, chess.Black, clock.Flagged(start.Add(2*time.Minute)))
+	assert.Equal(t, time.Duration(0), clock.Remaining(chess.Black, start.Add(2*time.Minute)))
+}
+
+// TestClockPause tests that no time is spent and nobody flags while paused.
+func TestClockPause(t *testing.T) {
+	start := time.Now()
+	clock := NewClock(time.Minute, 0, 0)
+
+	clock.Punch(chess.White, start)
+	clock.Pause(start.Add(10 * time.Second))
+	assert.True(t, clock.Paused())
+	assert.Equal(t, 50*time.Second, clock.Remaining(chess.Black, start.Add(time.Hour)))
+	assert.Equal(t, chess.NoColor, clock.Flagged(start.Add(time.Hour)))
+
+	clock.Resume(start.Add(time.Hour))
+	assert.Equal(t, 40*time.Second, clock.Remaining(chess.Black, start.Add(time.Hour+10*time.Second)))
+}
+
+// TestClockUndo tests that the clock goes back with the moves taken back.
+func TestClockUndo(t *testing.T) {
+	start := time.Now()
+	clock := NewClock(time.Minute, 2*time.Second, 0)
+
+	clock.Punch(chess.White, start)
+	clock.Punch(chess.Black, start.Add(10*time.Second))
+	clock.Punch(chess.White, start.Add(30*time.Second))
+
+	// White gets its move back, with the time it had before it
+	assert.True(t, clock.Undo(1, start.Add(40*time.Second)))
+	assert.Equal(t, chess.White, clock.Running())
+	assert.Equal(t, time.Minute, clock.Remaining(chess.White, start.Add(40*time.Second)))
+	assert.Equal(t, 52*time.Second, clock.Remaining(chess.Black, start.Add(40*time.Second)))
+
+	assert.True(t, clock.Undo(2, start.Add(50*time.Second)))
+	assert.Equal(t, chess.NoColor, clock.Running())
+	assert.Equal(t, time.Minute, clock.Remaining(chess.Black, start.Add(time.Hour)))
+
+	assert.False(t, clock.Undo(1, start.Add(time.Hour)))
+}
+
+// TestCompensatedTime tests that the claimed send time is bounded by the RTT.
+func TestCompensatedTime(t *testing.T) {
+	received := time.Now()
+
+	at := CompensatedTime(received.Add(-30*time.Millisecond), received, 0, 100*time.Millisecond)
+	assert.Equal(t, received.Add(-30*time.Millisecond), at)
+
+	at = CompensatedTime(received.Add(-time.Second), received, 0, 100*time.Millisecond)
+	assert.Equal(t, received.Add(-100*time.Millisecond), at, "Expected the claim to be bounded by the RTT")
+
+	at = CompensatedTime(received.Add(time.Second), received, 0, 100*time.Millisecond)
+	assert.Equal(t, received, at, "Expected no move from the future")
+
+	at = CompensatedTime(received.Add(time.Hour), received, time.Hour+20*time.Millisecond, 100*time.Millisecond)
+	assert.Equal(t, received.Add(-20*time.Millisecond), at, "Expected the clock offset to be removed")
+}
+
+// TestHasMatingMaterial tests the material needed to win on time.
+func TestHasMatingMaterial(t *testing.T) {
+	fen, err := chess.FEN("8/8/4k3/8/8/3NK3/8/8 w - - 0 1")
+	assert.NoError(t, err)
+	game := chess.NewGame(fen)
+	assert.False(t, HasMatingMaterial(game.Position().Board(), chess.White))
+	assert.False(t, HasMatingMaterial(game.Position().Board(), chess.Black))
+
+	game = chess.NewGame()
+	assert.True(t, HasMatingMaterial(game.Position().Board(), chess.White))
+}
@@ -0,0 +1,104 @@
+package multiplayer
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+)
+
+// Payload of a `ConsultGameMessage`: the move a player votes for at the
+// `Ply`-th move
+type ConsultVote struct {
+	Ply  int    `json:"ply"`
+	Move string `json:"move"`
+}
+
+// A move proposed by a team and the players who voted for it
+type MoveVotes struct {
+	Move   string
+	Voters []p2p.NetworkID
+}
+
+// Consultation collects the moves voted by the players of a team for one move
+// until its deadline.
+type Consultation struct {
+	Ply      int
+	votes    map[p2p.NetworkID]string
+	deadline time.Time
+}
+
+func NewConsultation(ply int, deadline time.Time) *Consultation {
+	return &Consultation{
+		Ply:      ply,
+		votes:    make(map[p2p.NetworkID]string),
+		deadline: deadline,
+	}
+}
+
+func (c *Consultation) Deadline() time.Time {
+	return c.deadline
+}
+
+// Saves the vote of `peer`, replacing its previous one
+func (c *Consultation) Vote(peer p2p.NetworkID, move string) {
+	c.votes[peer] = move
+}
+
+// Returns the voted moves, the most voted first
+func (c *Consultation) Proposals() []MoveVotes {
+	byMove := make(map[string][]p2p.NetworkID)
+	for peer, move := range c.votes {
+		byMove[move] = append(byMove[move], peer)
+	}
+
+	var proposals []MoveVotes
+	for move, voters := range byMove {
+		slices.Sort(voters)
+		proposals = append(proposals, MoveVotes{Move: move, Voters: voters})
+	}
+
+	slices.SortFunc(proposals, func(a, b MoveVotes) int {
+		if len(a.Voters) != len(b.Voters) {
+			return len(b.Voters) - len(a.Voters)
+		}
+		return strings.Compare(a.Move, b.Move)
+	})
+
+	return proposals
+}
+
+// Returns the move chosen by the team: the one every member agrees on or,
+// after the deadline, the most voted one with ties broken by the vote of the
+// captain. Returns an empty string if the team has not chosen yet.
+func (c *Consultation) Resolve(members []p2p.NetworkID, captain p2p.NetworkID, now time.Time) string {
+	agreed := c.votes[captain]
+	for _, member := range members {
+		if move, ok := c.votes[member]; !ok || move != agreed {
+			agreed = ""
+			break
+		}
+	}
+
+	if agreed != "" || !now.After(c.deadline) {
+		return agreed
+	}
+
+	proposals := c.Proposals()
+	if len(proposals) == 0 {
+		return ""
+	}
+
+	for _, proposal := range proposals {
+		if len(proposal.Voters) < len(proposals[0].Voters) {
+			break
+		}
+
+		if slices.Contains(proposal.Voters, captain) {
+			return proposal.Move
+		}
+	}
+
+	return proposals[0].Move
+}
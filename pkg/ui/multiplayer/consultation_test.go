@@ -0,0 +1,42 @@
+package multiplayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/stretchr/testify/assert"
+)
+
+var team = []p2p.NetworkID{"game-1", "game-3"}
+
+// TestConsultationAgreement tests that the team moves as soon as it agrees.
+func TestConsultationAgreement(t *testing.T) {
+	now := time.Now()
+	c := NewConsultation(0, now.Add(time.Minute))
+
+	c.Vote("game-3", "e2e4")
+	assert.Equal(t, "", c.Resolve(team, "game-1", now))
+
+	c.Vote("game-1", "d2d4")
+	assert.Equal(t, "", c.Resolve(team, "game-1", now))
+
+	c.Vote("game-1", "e2e4")
+	assert.Equal(t, "e2e4", c.Resolve(team, "game-1", now))
+	assert.Equal(t, []MoveVotes{{Move: "e2e4", Voters: team}}, c.Proposals())
+}
+
+// TestConsultationTimeout tests the choice made when the time is over.
+func TestConsultationTimeout(t *testing.T) {
+	now := time.Now()
+	late := now.Add(2 * time.Minute)
+	c := NewConsultation(0, now.Add(time.Minute))
+
+	assert.Equal(t, "", c.Resolve(team, "game-1", late), "Expected no move without votes")
+
+	c.Vote("game-3", "e2e4")
+	assert.Equal(t, "e2e4", c.Resolve(team, "game-1", late))
+
+	c.Vote("game-1", "d2d4")
+	assert.Equal(t, "d2d4", c.Resolve(team, "game-1", late), "Expected the captain to break the tie")
+}
@@ -0,0 +1,207 @@
+package multiplayer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/p2p"
+)
+
+// Size in bytes of the secret each peer contributes to the shared seed
+const secretSize = 32
+
+// Entropy implements a commit-reveal scheme: every peer commits to a secret
+// sending its hash, and reveals the secret only after it has received the
+// commitments of all the other peers. The shared seed is the hash of all the
+// revealed secrets, so no peer can bias it.
+type Entropy struct {
+	secret      []byte
+	commitments map[p2p.NetworkID][]byte
+	secrets     map[p2p.NetworkID][]byte
+	revealed    bool
+}
+
+// The part of the network used to exchange the secrets
+type secretNetwork interface {
+	Send(peer p2p.NetworkID, messageType []byte, payload []byte) error
+	SendAll(messageType []byte, payload []byte) error
+}
+
+// Creates a new local secret for the peer `me`
+func NewEntropy(me p2p.NetworkID) (*Entropy, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	e := &Entropy{
+		secret:      secret,
+		commitments: make(map[p2p.NetworkID][]byte),
+		secrets:     make(map[p2p.NetworkID][]byte),
+	}
+	e.Commit(me, e.Commitment())
+	e.secrets[me] = secret
+
+	return e, nil
+}
+
+// Returns the hash of the local secret
+func (e *Entropy) Commitment() []byte {
+	sum := sha256.Sum256(e.secret)
+	return sum[:]
+}
+
+// Returns the local secret. It must be sent only after receiving all the
+// commitments.
+func (e *Entropy) Secret() []byte {
+	return e.secret
+}
+
+// Saves the commitment of `peer`. A peer can't change its commitment.
+func (e *Entropy) Commit(peer p2p.NetworkID, commitment []byte) error {
+	if stored, exists := e.commitments[peer]; exists {
+		if !bytes.Equal(stored, commitment) {
+			return fmt.Errorf("peer %s changed its commitment", peer)
+		}
+		return nil
+	}
+
+	e.commitments[peer] = commitment
+	return nil
+}
+
+// Saves the secret of `peer` checking that it matches its commitment
+func (e *Entropy) Reveal(peer p2p.NetworkID, secret []byte) error {
+	commitment, exists := e.commitments[peer]
+	if !exists {
+		return fmt.Errorf("peer %s revealed a secret without a commitment", peer)
+	}
+
+	sum := sha256.Sum256(secret)
+	if !bytes.Equal(sum[:], commitment) {
+		return fmt.Errorf("secret of peer %s does not match its commitment", peer)
+	}
+
+	e.secrets[peer] = secret
+	return nil
+}
+
+// Saves the commitment of `peer` received from the network. If the local
+// secret is already revealed it is sent again, since the peer is still
+// collecting secrets and it could have lost it.
+func (e *Entropy) ReceiveCommitment(network secretNetwork, peer p2p.NetworkID, commitment []byte) error {
+	if err := e.Commit(peer, commitment); err != nil {
+		return err
+	}
+
+	if e.revealed {
+		return network.Send(peer, []byte(string(RevealGameMessage)), e.secret)
+	}
+
+	return nil
+}
+
+// Sends the local secret to everyone, once, after all the `peers` have
+// committed to their own
+func (e *Entropy) RevealSecret(network secretNetwork, peers []p2p.NetworkID) error {
+	if e.revealed || !e.HasCommitments(peers) {
+		return nil
+	}
+
+	e.revealed = true
+	return network.SendAll([]byte(string(RevealGameMessage)), e.secret)
+}
+
+// Saves the secret of `peer` received from the network and returns the shared
+// seed, or nil until the secrets of all the `peers` are known
+func (e *Entropy) ReceiveSecret(peer p2p.NetworkID, secret []byte, peers []p2p.NetworkID) ([]byte, error) {
+	if err := e.Reveal(peer, secret); err != nil {
+		return nil, err
+	}
+
+	seed, err := e.Seed(peers)
+	if err != nil {
+		return nil, nil
+	}
+
+	return seed, nil
+}
+
+// Returns true if all the `peers` have sent their commitment
+func (e *Entropy) HasCommitments(peers []p2p.NetworkID) bool {
+	for _, peer := range peers {
+		if _, exists := e.commitments[peer]; !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the shared seed computed from the secrets of `peers`, in the given
+// order
+func (e *Entropy) Seed(peers []p2p.NetworkID) ([]byte, error) {
+	h := sha256.New()
+	for _, peer := range peers {
+		secret, exists := e.secrets[peer]
+		if !exists {
+			return nil, fmt.Errorf("missing secret of peer %s", peer)
+		}
+		h.Write(secret)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// Picks one of the `candidates` for the `ply`-th move. The pick only depends
+// on the shared seed, so every peer can verify it.
+func PickTurn(seed []byte, ply int, candidates []p2p.NetworkID) p2p.NetworkID {
+	if len(candidates) == 0 {
+		return p2p.EmptyNetworkID
+	}
+
+	return candidates[seedValue(seed, ply)%uint64(len(candidates))]
+}
+
+// Picks the index of the Chess960 starting position from the shared seed. It
+// uses a ply no move can have, so it does not follow the first turn.
+func PickStartPosition(seed []byte) int {
+	return int(seedValue(seed, -1) % chess960.Positions)
+}
+
+// Returns the FEN of the Chess960 starting position picked from the shared seed
+func StartPositionFEN(seed []byte) (string, error) {
+	return chess960.StartingFEN(PickStartPosition(seed))
+}
+
+// Returns a number derived from the shared seed for the `ply`-th move
+func seedValue(seed []byte, ply int) uint64 {
+	data := make([]byte, len(seed)+8)
+	copy(data, seed)
+	binary.BigEndian.PutUint64(data[len(seed):], uint64(ply))
+	sum := sha256.Sum256(data)
+
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// A turn assignment claimed by `Source` for the `Ply`-th move
+type TurnRecord struct {
+	Ply    int           `json:"ply"`
+	Seat   p2p.NetworkID `json:"seat"`
+	Source p2p.NetworkID `json:"source"`
+}
+
+// Checks every turn assignment against the strategy of the game. `context`
+// returns what the strategy knows at the given ply.
+func AuditTurns(strategy TurnStrategy, records []TurnRecord, context func(ply int) TurnContext) error {
+	for _, record := range records {
+		if err := strategy.CheckTurn(context(record.Ply), record.Source, record.Seat); err != nil {
+			return fmt.Errorf("ply %d: %v", record.Ply, err)
+		}
+	}
+
+	return nil
+}
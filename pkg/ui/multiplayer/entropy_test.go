@@ -0,0 +1,140 @@
+package multiplayer
+
+import (
+	"testing"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSharedSeed tests that every peer computes the same seed.
+func TestSharedSeed(t *testing.T) {
+	peers := []p2p.NetworkID{"game-1", "game-2", "game-3", "game-4"}
+	entropies := make([]*Entropy, len(peers))
+
+	for i, peer := range peers {
+		e, err := NewEntropy(peer)
+		assert.NoError(t, err)
+		entropies[i] = e
+	}
+
+	for i, e := range entropies {
+		for j, other := range entropies {
+			if i != j {
+				assert.NoError(t, e.Commit(peers[j], other.Commitment()))
+			}
+		}
+		assert.True(t, e.HasCommitments(peers))
+	}
+
+	for i, e := range entropies {
+		for j, other := range entropies {
+			if i != j {
+				assert.NoError(t, e.Reveal(peers[j], other.Secret()))
+			}
+		}
+	}
+
+	seed, err := entropies[0].Seed(peers)
+	assert.NoError(t, err)
+
+	for _, e := range entropies[1:] {
+		other, err := e.Seed(peers)
+		assert.NoError(t, err)
+		assert.Equal(t, seed, other)
+	}
+}
+
+// TestRevealMismatch tests that a secret different from the commitment is refused.
+func TestRevealMismatch(t *testing.T) {
+	e, err := NewEntropy("game-1")
+	assert.NoError(t, err)
+
+	other, err := NewEntropy("game-2")
+	assert.NoError(t, err)
+
+	cheater, err := NewEntropy("game-2")
+	assert.NoError(t, err)
+
+	assert.Error(t, e.Reveal("game-2", other.Secret()), "Expected error when revealing before committing")
+	assert.NoError(t, e.Commit("game-2", other.Commitment()))
+	assert.Error(t, e.Commit("game-2", cheater.Commitment()), "Expected error when changing the commitment")
+	assert.Error(t, e.Reveal("game-2", cheater.Secret()), "Expected error when revealing another secret")
+	assert.NoError(t, e.Reveal("game-2", other.Secret()))
+
+	_, err = e.Seed([]p2p.NetworkID{"game-1", "game-2", "game-3"})
+	assert.Error(t, err, "Expected error when a secret is missing")
+}
+
+// TestAuditTurns tests that a forged turn assignment is detected.
+func TestAuditTurns(t *testing.T) {
+	seed := []byte("shared seed")
+	candidates := func(ply int) []p2p.NetworkID {
+		if ply%2 == 0 {
+			return []p2p.NetworkID{"game-1", "game-3"}
+		}
+		return []p2p.NetworkID{"game-2", "game-4"}
+	}
+
+	var records []TurnRecord
+	for ply := 0; ply < 20; ply++ {
+		seat := PickTurn(seed, ply, candidates(ply))
+		assert.Contains(t, candidates(ply), seat)
+		records = append(records, TurnRecord{Ply: ply, Seat: seat, Source: "game-1"})
+	}
+
+	context := func(ply int) TurnContext {
+		return TurnContext{Ply: ply, Candidates: candidates(ply), Seed: seed}
+	}
+
+	assert.NoError(t, AuditTurns(RandomStrategy{}, records, context))
+
+	forged := candidates(7)[0]
+	if forged == records[7].Seat {
+		forged = candidates(7)[1]
+	}
+	records[7].Seat = forged
+
+	assert.Error(t, AuditTurns(RandomStrategy{}, records, context))
+}
+
+// TestPickStartPosition tests that the Chess960 position only depends on the seed.
+func TestPickStartPosition(t *testing.T) {
+	seed := []byte("shared seed")
+
+	index := PickStartPosition(seed)
+	assert.GreaterOrEqual(t, index, 0)
+	assert.Less(t, index, chess960.Positions)
+	assert.Equal(t, index, PickStartPosition([]byte("shared seed")))
+}
+
+// TestSecretExchange tests that the secret is revealed once every peer has
+// committed, and sent again to a peer which commits later.
+func TestSecretExchange(t *testing.T) {
+	peers := []p2p.NetworkID{"game-1", "game-2"}
+	network := &fakeNetwork{me: "game-1"}
+
+	local, err := NewEntropy("game-1")
+	assert.NoError(t, err)
+	remote, err := NewEntropy("game-2")
+	assert.NoError(t, err)
+
+	assert.NoError(t, local.RevealSecret(network, peers))
+	assert.Empty(t, network.sentOf(RevealGameMessage))
+
+	assert.NoError(t, local.ReceiveCommitment(network, "game-2", remote.Commitment()))
+	assert.NoError(t, local.RevealSecret(network, peers))
+	assert.NoError(t, local.RevealSecret(network, peers))
+	assert.Len(t, network.sentOf(RevealGameMessage), 1)
+
+	assert.NoError(t, local.ReceiveCommitment(network, "game-2", remote.Commitment()))
+	assert.Len(t, network.sentOf(RevealGameMessage), 2)
+
+	seed, err := local.ReceiveSecret("game-2", remote.Secret(), peers)
+	assert.NoError(t, err)
+	assert.NotNil(t, seed)
+
+	_, err = local.ReceiveSecret("game-2", []byte("forged"), peers)
+	assert.Error(t, err)
+}
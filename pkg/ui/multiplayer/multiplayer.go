@@ -1,7 +1,11 @@
 package multiplayer
 
 import (
+	"encoding/json"
+	"errors"
 	"slices"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/boozec/rahanna/internal/logger"
@@ -12,23 +16,82 @@ import (
 type MoveType string
 
 const (
-	AbandonGameMessage    MoveType = "abandon"
-	MoveGameMessage       MoveType = "new-move"
-	RestoreAckGameMessage MoveType = "restore-ack"
-	RestoreGameMessage    MoveType = "restore"
-	DefineTurnMessage     MoveType = "define-turn"
+	AbandonGameMessage        MoveType = "abandon"
+	MoveGameMessage           MoveType = "new-move"
+	RestoreAckGameMessage     MoveType = "restore-ack"
+	RestoreGameMessage        MoveType = "restore"
+	DefineTurnMessage         MoveType = "define-turn"
+	RejectMoveGameMessage     MoveType = "reject-move"
+	ReadyGameMessage          MoveType = "ready"
+	CommitGameMessage         MoveType = "commit"
+	RevealGameMessage         MoveType = "reveal"
+	HeartbeatGameMessage      MoveType = "heartbeat"
+	ChatGameMessage           MoveType = "chat"
+	ProposeGameMessage        MoveType = "propose"
+	AnswerGameMessage         MoveType = "answer"
+	ProposalResultGameMessage MoveType = "proposal-result"
+	PongGameMessage           MoveType = "pong"
+	PublicKeyGameMessage      MoveType = "public-key"
+	ConsultGameMessage        MoveType = "consult"
+	BughouseMoveGameMessage   MoveType = "bughouse-move"
+	RollbackGameMessage       MoveType = "rollback"
 )
 
+// Payload of a `RejectMoveGameMessage`: the refused move and the reason why
+// it has been refused
+type MoveRejection struct {
+	Move   string `json:"move"`
+	Reason string `json:"reason"`
+}
+
+// Payload of a `RollbackGameMessage`: the refused move, which its sender
+// rolled back, and the number of moves played before it
+type MoveRollback struct {
+	Move string `json:"move"`
+	Ply  int    `json:"ply"`
+}
+
+// The role of the local peer in a game
+type Role int
+
+const (
+	PlayerRole Role = iota
+	SpectatorRole
+)
+
+// Messages a spectator is allowed to send
+var spectatorMessages = []MoveType{RestoreGameMessage, HeartbeatGameMessage, PongGameMessage}
+
+// Returned when a spectator tries to send a message reserved to players
+var ErrSpectatorMessage = errors.New("spectators can only watch the game")
+
+// Payload of a `PongGameMessage`: the timestamp of the heartbeat it answers
+// and the time of the sender when it answered, both in Unix nanoseconds
+type pong struct {
+	Ping int64 `json:"ping"`
+	At   int64 `json:"at"`
+}
+
 type GameMove struct {
-	Source  p2p.NetworkID `json:"source"`
-	Type    []byte        `json:"type"`
-	Payload []byte        `json:"payload"`
+	Source     p2p.NetworkID `json:"source"`
+	Type       []byte        `json:"type"`
+	Payload    []byte        `json:"payload"`
+	ReceivedAt time.Time     `json:"-"`
 }
 
 type GameNetwork struct {
-	server *p2p.TCPNetwork
-	me     p2p.NetworkID
-	peers  []p2p.NetworkID
+	sync.Mutex
+
+	server     *p2p.TCPNetwork
+	me         p2p.NetworkID
+	role       Role
+	peers      []p2p.NetworkID
+	spectators []p2p.NetworkID
+	lastSeen   map[p2p.NetworkID]time.Time
+	rtt        map[p2p.NetworkID]time.Duration
+	offset     map[p2p.NetworkID]time.Duration
+	beating    bool
+	done       chan struct{}
 }
 
 // Wrapper to a `TCPNetwork`
@@ -42,45 +105,272 @@ func NewGameNetwork(localID string, address string, onHandshake p2p.NetworkHands
 	}
 	server := p2p.NewTCPNetwork(p2p.NetworkID(localID), opts)
 	return &GameNetwork{
-		server: server,
-		me:     p2p.NetworkID(localID),
+		server:   server,
+		me:       p2p.NetworkID(localID),
+		lastSeen: make(map[p2p.NetworkID]time.Time),
+		rtt:      make(map[p2p.NetworkID]time.Duration),
+		offset:   make(map[p2p.NetworkID]time.Duration),
+		done:     make(chan struct{}),
 	}
 }
 
+// Wrapper to a `TCPNetwork` for a peer which only watches the game: it receives
+// moves, turns and snapshots, but it can never send moves.
+func NewSpectatorNetwork(localID string, address string, logger *zap.Logger) *GameNetwork {
+	n := NewGameNetwork(localID, address, p2p.DefaultHandshake, p2p.DefaultHandshake, logger)
+	n.role = SpectatorRole
+	return n
+}
+
+func (n *GameNetwork) Role() Role {
+	return n.role
+}
+
+// Returns a copy of the peers, safe to range over while peers are added
 func (n *GameNetwork) Peers() []p2p.NetworkID {
-	return n.peers
+	n.Lock()
+	defer n.Unlock()
+
+	return slices.Clone(n.peers)
 }
 
 func (n *GameNetwork) Me() p2p.NetworkID {
 	return n.me
 }
 
-// Send a message to all peers
+// Returns a copy of the spectators, safe to range over while spectators are
+// added
+func (n *GameNetwork) Spectators() []p2p.NetworkID {
+	n.Lock()
+	defer n.Unlock()
+
+	return slices.Clone(n.spectators)
+}
+
+// Returns true if `peer` is watching the game
+func (n *GameNetwork) IsSpectator(peer p2p.NetworkID) bool {
+	n.Lock()
+	defer n.Unlock()
+
+	return slices.Contains(n.spectators, peer)
+}
+
+// Returns an error if the local peer is not allowed to send `messageType`
+func (n *GameNetwork) canSend(messageType []byte) error {
+	if n.role == SpectatorRole && !slices.Contains(spectatorMessages, MoveType(messageType)) {
+		return ErrSpectatorMessage
+	}
+	return nil
+}
+
+// Send a message to all peers and spectators
 func (n *GameNetwork) SendAll(messageType []byte, payload []byte) error {
-	for _, peer := range n.peers {
+	if err := n.canSend(messageType); err != nil {
+		return err
+	}
+
+	for _, peer := range n.Peers() {
 		n.server.Send(peer, messageType, payload)
 	}
 
+	for _, spectator := range n.Spectators() {
+		n.server.Send(spectator, messageType, payload)
+	}
+
 	return nil
 }
 
 // Send a message to only one peer
 func (n *GameNetwork) Send(peer p2p.NetworkID, messageType []byte, payload []byte) error {
+	if err := n.canSend(messageType); err != nil {
+		return err
+	}
+
 	return n.server.Send(peer, messageType, payload)
 }
 
+// Returns true if the link to `peer` is up
+func (n *GameNetwork) IsConnected(peer p2p.NetworkID) bool {
+	return n.server.IsConnected(peer)
+}
+
+// Returns true if the links to all the peers are up
+func (n *GameNetwork) AllPeersConnected() bool {
+	for _, peer := range n.Peers() {
+		if !n.server.IsConnected(peer) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (n *GameNetwork) AddPeer(remoteID p2p.NetworkID, addr string) {
+	n.Lock()
 	if exists := slices.Contains(n.peers, remoteID); !exists {
 		n.peers = append(n.peers, remoteID)
 	}
+
+	// A new peer has some time to connect before being unreachable
+	if _, exists := n.lastSeen[remoteID]; !exists {
+		n.lastSeen[remoteID] = time.Now()
+	}
+	n.Unlock()
+
 	n.server.AddPeer(remoteID, addr)
 }
 
+// Add a peer which only watches the game
+func (n *GameNetwork) AddSpectator(remoteID p2p.NetworkID, addr string) {
+	n.Lock()
+	if exists := slices.Contains(n.spectators, remoteID); !exists {
+		n.spectators = append(n.spectators, remoteID)
+	}
+	n.Unlock()
+
+	n.server.AddPeer(remoteID, addr)
+}
+
+// Returns true if a spectator can send a message of type `t`: chat, and the
+// messages it needs to watch the game
+func isSpectatorMessage(t MoveType) bool {
+	return t == ChatGameMessage || slices.Contains(spectatorMessages, t)
+}
+
+// Set the function called for every received message. Heartbeats and their
+// answers are handled by the network itself and never reach `f`, as the
+// messages of spectators reserved to players.
 func (n *GameNetwork) AddReceiveFunction(f p2p.NetworkMessageReceiveFunc) {
-	n.server.OnReceiveFn = f
+	n.server.OnReceiveFn = func(msg p2p.Message) {
+		now := time.Now()
+
+		n.Lock()
+		n.lastSeen[msg.Source] = now
+		n.Unlock()
+
+		switch MoveType(msg.Type) {
+		case HeartbeatGameMessage:
+			n.answerHeartbeat(msg, now)
+		case PongGameMessage:
+			n.savePong(msg, now)
+		default:
+			if n.IsSpectator(msg.Source) && !isSpectatorMessage(MoveType(msg.Type)) {
+				return
+			}
+			f(msg)
+		}
+	}
+}
+
+// Sends back the timestamp of a heartbeat with the local time
+func (n *GameNetwork) answerHeartbeat(msg p2p.Message, now time.Time) {
+	ping, err := strconv.ParseInt(string(msg.Payload), 10, 64)
+	if err != nil {
+		return
+	}
+
+	payload, err := json.Marshal(pong{Ping: ping, At: now.UnixNano()})
+	if err != nil {
+		return
+	}
+
+	n.server.Send(msg.Source, []byte(string(PongGameMessage)), payload)
+}
+
+// Measures the round trip time and the clock offset of the peer from the
+// answer to one of our heartbeats
+func (n *GameNetwork) savePong(msg p2p.Message, now time.Time) {
+	var answer pong
+	if err := json.Unmarshal(msg.Payload, &answer); err != nil {
+		return
+	}
+
+	ping := time.Unix(0, answer.Ping)
+	rtt := now.Sub(ping)
+	if rtt < 0 {
+		return
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	// Smooth the samples like TCP does
+	if previous, ok := n.rtt[msg.Source]; ok {
+		rtt = (7*previous + rtt) / 8
+	}
+	n.rtt[msg.Source] = rtt
+	n.offset[msg.Source] = time.Unix(0, answer.At).Sub(ping.Add(now.Sub(ping) / 2))
+}
+
+// Returns the round trip time to `peer`, 0 if unknown
+func (n *GameNetwork) RTT(peer p2p.NetworkID) time.Duration {
+	n.Lock()
+	defer n.Unlock()
+
+	return n.rtt[peer]
+}
+
+// Returns how much the clock of `peer` is ahead of the local one, 0 if unknown
+func (n *GameNetwork) ClockOffset(peer p2p.NetworkID) time.Duration {
+	n.Lock()
+	defer n.Unlock()
+
+	return n.offset[peer]
+}
+
+// Send a heartbeat to all peers every `interval`, until the network is closed
+func (n *GameNetwork) StartHeartbeat(interval time.Duration) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.beating {
+		return
+	}
+	n.beating = true
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				n.SendAll([]byte(string(HeartbeatGameMessage)), []byte(strconv.FormatInt(time.Now().UnixNano(), 10)))
+			case <-n.done:
+				return
+			}
+		}
+	}()
+}
+
+// Returns the last time a message arrived from `peer`
+func (n *GameNetwork) LastSeen(peer p2p.NetworkID) time.Time {
+	n.Lock()
+	defer n.Unlock()
+
+	return n.lastSeen[peer]
+}
+
+// Returns true if a message arrived from `peer` in the last `timeout`
+func (n *GameNetwork) IsReachable(peer p2p.NetworkID, timeout time.Duration) bool {
+	return time.Since(n.LastSeen(peer)) <= timeout
+}
+
+// Stops the heartbeats, once
+func (n *GameNetwork) stopHeartbeat() {
+	n.Lock()
+	defer n.Unlock()
+
+	select {
+	case <-n.done:
+	default:
+		close(n.done)
+	}
 }
 
 func (n *GameNetwork) Close() error {
+	n.stopHeartbeat()
+
 	err := n.server.Close()
 	logger, _ := logger.GetLogger()
 
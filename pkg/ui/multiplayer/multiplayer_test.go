@@ -0,0 +1,51 @@
+package multiplayer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// TestAddSpectatorWhileBeating tests that spectators can join while the
+// heartbeats are sent to everyone. Run it with `-race`.
+func TestAddSpectatorWhileBeating(t *testing.T) {
+	n := NewGameNetwork("peer-1", "127.0.0.1:0", p2p.DefaultHandshake, p2p.DefaultHandshake, zap.NewNop())
+	defer n.stopHeartbeat()
+
+	n.StartHeartbeat(time.Millisecond)
+
+	// Without address, the spectators are never dialed
+	for i := range 50 {
+		n.AddSpectator(p2p.NetworkID(fmt.Sprintf("spectator-%d", i)), "")
+		time.Sleep(100 * time.Microsecond)
+	}
+
+	assert.Len(t, n.Spectators(), 50)
+	assert.True(t, n.IsSpectator("spectator-49"))
+}
+
+// TestSpectatorAbandonIgnored tests that a spectator can't end the game.
+func TestSpectatorAbandonIgnored(t *testing.T) {
+	n := NewGameNetwork("peer-1", "127.0.0.1:0", p2p.DefaultHandshake, p2p.DefaultHandshake, zap.NewNop())
+	n.AddPeer("peer-2", "")
+	n.AddSpectator("spectator-1", "")
+
+	var received []p2p.Message
+	n.AddReceiveFunction(func(msg p2p.Message) {
+		received = append(received, msg)
+	})
+
+	n.server.OnReceiveFn(p2p.Message{Source: "spectator-1", Type: []byte(AbandonGameMessage), Payload: []byte("🏳️")})
+	assert.Empty(t, received)
+
+	n.server.OnReceiveFn(p2p.Message{Source: "spectator-1", Type: []byte(ChatGameMessage)})
+	n.server.OnReceiveFn(p2p.Message{Source: "peer-2", Type: []byte(AbandonGameMessage), Payload: []byte("🏳️")})
+	if assert.Len(t, received, 2) {
+		assert.Equal(t, p2p.NetworkID("spectator-1"), received[0].Source)
+		assert.Equal(t, []byte(AbandonGameMessage), received[1].Type)
+	}
+}
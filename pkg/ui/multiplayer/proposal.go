@@ -0,0 +1,170 @@
+package multiplayer
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/notnil/chess"
+)
+
+// What a player asks to the other players
+type ProposalKind string
+
+const (
+	// End the game with a draw, accepted by the opponents
+	DrawProposal ProposalKind = "draw"
+
+	// Undo the last move of the proposer's team, accepted by the opponents
+	TakebackProposal ProposalKind = "takeback"
+
+	// Lose the game, accepted by the teammates of the proposer
+	ResignProposal ProposalKind = "resign"
+
+	// Claim a draw allowed by the rules, validated by every other player
+	ClaimProposal ProposalKind = "claim"
+)
+
+type ProposalStatus string
+
+const (
+	PendingProposal  ProposalStatus = "pending"
+	AcceptedProposal ProposalStatus = "accepted"
+	DeclinedProposal ProposalStatus = "declined"
+	ExpiredProposal  ProposalStatus = "expired"
+)
+
+// Payload of a `ProposeGameMessage`. `Ply` is the number of moves played when
+// the proposal has been made, `Method` is the draw method of a claim.
+type Proposal struct {
+	ID       string        `json:"id"`
+	Kind     ProposalKind  `json:"kind"`
+	Proposer p2p.NetworkID `json:"proposer"`
+	Ply      int           `json:"ply"`
+	Method   string        `json:"method,omitempty"`
+}
+
+// Payload of an `AnswerGameMessage`
+type ProposalAnswer struct {
+	ID       string `json:"id"`
+	Accepted bool   `json:"accepted"`
+}
+
+// Payload of a `ProposalResultGameMessage`, sent by the proposer when the
+// negotiation is over
+type ProposalResult struct {
+	ID     string         `json:"id"`
+	Status ProposalStatus `json:"status"`
+}
+
+// Negotiation collects the answers to a proposal until its deadline. The
+// proposal is accepted only if every responder accepts it, and declined as soon
+// as one of them refuses it.
+type Negotiation struct {
+	Proposal   Proposal
+	responders []p2p.NetworkID
+	answers    map[p2p.NetworkID]bool
+	deadline   time.Time
+}
+
+func NewNegotiation(proposal Proposal, responders []p2p.NetworkID, deadline time.Time) *Negotiation {
+	return &Negotiation{
+		Proposal:   proposal,
+		responders: responders,
+		answers:    make(map[p2p.NetworkID]bool),
+		deadline:   deadline,
+	}
+}
+
+// Returns true if `peer` has to answer the proposal
+func (n *Negotiation) IsResponder(peer p2p.NetworkID) bool {
+	return slices.Contains(n.responders, peer)
+}
+
+// Returns true if `peer` already answered the proposal
+func (n *Negotiation) Answered(peer p2p.NetworkID) bool {
+	_, ok := n.answers[peer]
+	return ok
+}
+
+func (n *Negotiation) Deadline() time.Time {
+	return n.deadline
+}
+
+// Saves the answer of `peer`. An answer can't be changed.
+func (n *Negotiation) Answer(peer p2p.NetworkID, accepted bool) error {
+	if !n.IsResponder(peer) {
+		return fmt.Errorf("%s can't answer the %s proposal of %s", peer, n.Proposal.Kind, n.Proposal.Proposer)
+	}
+
+	if previous, ok := n.answers[peer]; ok && previous != accepted {
+		return fmt.Errorf("%s changed its answer to the %s proposal", peer, n.Proposal.Kind)
+	}
+
+	n.answers[peer] = accepted
+	return nil
+}
+
+// Returns the status of the negotiation at the time `now`
+func (n *Negotiation) Status(now time.Time) ProposalStatus {
+	accepted := 0
+	for _, answer := range n.answers {
+		if !answer {
+			return DeclinedProposal
+		}
+		accepted++
+	}
+
+	if accepted == len(n.responders) {
+		return AcceptedProposal
+	}
+
+	if now.After(n.deadline) {
+		return ExpiredProposal
+	}
+
+	return PendingProposal
+}
+
+// Draws a player can claim, which are validated by the rules
+var claimableMethods = []chess.Method{chess.ThreefoldRepetition, chess.FiftyMoveRule}
+
+// Returns the draws which can be claimed in the current position of `game`
+func ClaimableDraws(game *chess960.Game) []chess.Method {
+	var draws []chess.Method
+	for _, method := range game.EligibleDraws() {
+		if slices.Contains(claimableMethods, method) {
+			draws = append(draws, method)
+		}
+	}
+
+	return draws
+}
+
+// Returns an error if `method` is not a draw which can be claimed in `game`
+func ValidateClaim(game *chess960.Game, method string) error {
+	for _, draw := range ClaimableDraws(game) {
+		if draw.String() == method {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("draw by %s can't be claimed in this position", method)
+}
+
+// Returns the number of moves to undo to take back the last move of the team
+// playing `color`, or 0 if that team did not move yet
+func TakebackPlies(game *chess960.Game, color chess.Color) int {
+	plies := 1
+	if game.Position().Turn() == color {
+		plies = 2
+	}
+
+	if plies > len(game.Moves()) {
+		return 0
+	}
+
+	return plies
+}
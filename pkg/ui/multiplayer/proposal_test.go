@@ -0,0 +1,91 @@
+package multiplayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDrawNegotiation(deadline time.Time) *Negotiation {
+	proposal := Proposal{ID: "game-1-draw-4", Kind: DrawProposal, Proposer: "game-1", Ply: 4}
+	return NewNegotiation(proposal, []p2p.NetworkID{"game-2", "game-4"}, deadline)
+}
+
+// TestNegotiationAccepted tests that every responder must accept.
+func TestNegotiationAccepted(t *testing.T) {
+	now := time.Now()
+	n := newDrawNegotiation(now.Add(time.Minute))
+
+	assert.Equal(t, PendingProposal, n.Status(now))
+
+	assert.NoError(t, n.Answer("game-2", true))
+	assert.True(t, n.Answered("game-2"))
+	assert.Equal(t, PendingProposal, n.Status(now))
+
+	assert.NoError(t, n.Answer("game-4", true))
+	assert.Equal(t, AcceptedProposal, n.Status(now))
+
+	// An accepted proposal does not expire
+	assert.Equal(t, AcceptedProposal, n.Status(now.Add(time.Hour)))
+}
+
+// TestNegotiationDeclined tests that one refusal declines the proposal.
+func TestNegotiationDeclined(t *testing.T) {
+	now := time.Now()
+	n := newDrawNegotiation(now.Add(time.Minute))
+
+	assert.NoError(t, n.Answer("game-2", true))
+	assert.NoError(t, n.Answer("game-4", false))
+	assert.Equal(t, DeclinedProposal, n.Status(now))
+
+	assert.Error(t, n.Answer("game-4", true), "Expected error changing an answer")
+	assert.Error(t, n.Answer("game-3", true), "Expected error answering from the proposer's team")
+	assert.False(t, n.IsResponder("game-1"))
+}
+
+// TestNegotiationExpired tests that an unanswered proposal expires.
+func TestNegotiationExpired(t *testing.T) {
+	now := time.Now()
+	n := newDrawNegotiation(now.Add(time.Minute))
+
+	assert.NoError(t, n.Answer("game-2", true))
+	assert.Equal(t, PendingProposal, n.Status(now))
+	assert.Equal(t, ExpiredProposal, n.Status(now.Add(2*time.Minute)))
+}
+
+// TestTakebackPlies tests that a takeback undoes the last move of the team,
+// and the reply of the opponents if they already moved.
+func TestTakebackPlies(t *testing.T) {
+	game := chess960.NewStandardGame()
+	assert.Equal(t, 0, TakebackPlies(game, chess.White))
+
+	assert.NoError(t, game.MoveStr("e2e4"))
+	assert.Equal(t, 1, TakebackPlies(game, chess.White))
+	assert.Equal(t, 0, TakebackPlies(game, chess.Black))
+
+	assert.NoError(t, game.MoveStr("e7e5"))
+	assert.Equal(t, 2, TakebackPlies(game, chess.White))
+	assert.Equal(t, 1, TakebackPlies(game, chess.Black))
+}
+
+// TestValidateClaim tests that only the draws allowed by the position can be
+// claimed, and never a draw offer.
+func TestValidateClaim(t *testing.T) {
+	game := chess960.NewStandardGame()
+	assert.Error(t, ValidateClaim(game, chess.ThreefoldRepetition.String()))
+	assert.Error(t, ValidateClaim(game, chess.DrawOffer.String()))
+
+	for range 2 {
+		for _, move := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+			assert.NoError(t, game.MoveStr(move))
+		}
+	}
+
+	assert.Equal(t, []chess.Method{chess.ThreefoldRepetition}, ClaimableDraws(game))
+	assert.NoError(t, ValidateClaim(game, chess.ThreefoldRepetition.String()))
+	assert.Error(t, ValidateClaim(game, chess.FiftyMoveRule.String()))
+}
@@ -0,0 +1,63 @@
+package multiplayer
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Signer signs the moves of the local peer. Its public key is sent to the
+// other peers before the game starts.
+type Signer struct {
+	public  ed25519.PublicKey
+	private ed25519.PrivateKey
+}
+
+// Payload of a `MoveGameMessage`: the move, the number of moves played before
+// it and the time it has been sent, signed by the sender
+type SignedMove struct {
+	Move      string    `json:"move"`
+	Ply       int       `json:"ply"`
+	SentAt    time.Time `json:"sent_at"`
+	Signature []byte    `json:"signature"`
+}
+
+func NewSigner() (*Signer, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{public: public, private: private}, nil
+}
+
+func (s *Signer) PublicKey() []byte {
+	return s.public
+}
+
+// Returns the signed payload of `move`
+func (s *Signer) SignMove(move string, ply int, sentAt time.Time) SignedMove {
+	signed := SignedMove{Move: move, Ply: ply, SentAt: sentAt}
+	signed.Signature = ed25519.Sign(s.private, signed.message())
+
+	return signed
+}
+
+func (m SignedMove) message() []byte {
+	return fmt.Appendf(nil, "%s|%d|%d", m.Move, m.Ply, m.SentAt.UnixNano())
+}
+
+// Checks the signature of the move with the public key of its sender
+func (m SignedMove) Verify(publicKey []byte) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key")
+	}
+
+	if !ed25519.Verify(publicKey, m.message(), m.Signature) {
+		return fmt.Errorf("invalid signature of move `%s`", m.Move)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package multiplayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSignedMove tests that forged moves and timestamps are refused.
+func TestSignedMove(t *testing.T) {
+	signer, err := NewSigner()
+	assert.NoError(t, err)
+
+	signed := signer.SignMove("e2e4", 0, time.Now())
+	assert.NoError(t, signed.Verify(signer.PublicKey()))
+
+	forged := signed
+	forged.SentAt = signed.SentAt.Add(-time.Second)
+	assert.Error(t, forged.Verify(signer.PublicKey()), "Expected error with a forged timestamp")
+
+	forged = signed
+	forged.Move = "e2e3"
+	assert.Error(t, forged.Verify(signer.PublicKey()), "Expected error with a forged move")
+
+	other, err := NewSigner()
+	assert.NoError(t, err)
+	assert.Error(t, signed.Verify(other.PublicKey()), "Expected error with the key of another peer")
+}
@@ -0,0 +1,190 @@
+package multiplayer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/boozec/rahanna/pkg/bughouse"
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/p2p"
+)
+
+// GameSnapshot is the full state of a game, sent by every peer to a peer which
+// is restoring the game.
+type GameSnapshot struct {
+	StartFEN string        `json:"start_fen"`
+	Moves    []string      `json:"moves"`
+	FEN      string        `json:"fen"`
+	Turn     p2p.NetworkID `json:"turn"`
+	Seed     []byte        `json:"seed,omitempty"`
+	Turns    []TurnRecord  `json:"turns,omitempty"`
+	Clock    *ClockState   `json:"clock,omitempty"`
+
+	// Key which signs the moves of the sender, for peers which joined late
+	Key []byte `json:"key,omitempty"`
+
+	// History of both boards in bughouse games
+	Bughouse []bughouse.Move `json:"bughouse,omitempty"`
+}
+
+// Returns the snapshot of `game`, where `turn` has the move
+func NewSnapshot(game *chess960.Game, turn p2p.NetworkID, seed []byte) GameSnapshot {
+	var moves []string
+	for _, move := range game.Moves() {
+		moves = append(moves, move.String())
+	}
+
+	return GameSnapshot{
+		StartFEN: game.StartFEN(),
+		Moves:    moves,
+		FEN:      game.FEN(),
+		Turn:     turn,
+		Seed:     seed,
+	}
+}
+
+// Replays the moves of the snapshot from its starting position. It fails if a
+// move is illegal or if the final position is not the declared one.
+func (s GameSnapshot) Replay() (*chess960.Game, error) {
+	game, err := chess960.NewGame(s.StartFEN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid starting position: %v", err)
+	}
+
+	for i, move := range s.Moves {
+		if err := game.MoveStr(move); err != nil {
+			return nil, fmt.Errorf("illegal move `%s` at ply %d: %v", move, i, err)
+		}
+	}
+
+	if game.FEN() != s.FEN {
+		return nil, fmt.Errorf("replayed position `%s` differs from `%s`", game.FEN(), s.FEN)
+	}
+
+	if _, err := bughouse.Replay(s.Bughouse); err != nil {
+		return nil, fmt.Errorf("invalid bughouse history: %v", err)
+	}
+
+	return game, nil
+}
+
+// Replays `moves`, separated by spaces, from `startFEN`, the standard starting
+// position when empty, as the moves of a game stored by the API
+func ReplayMoves(startFEN string, moves string) (*chess960.Game, error) {
+	game := chess960.NewStandardGame()
+	if startFEN != "" {
+		var err error
+		if game, err = chess960.NewGame(startFEN); err != nil {
+			return nil, fmt.Errorf("invalid starting position: %v", err)
+		}
+	}
+
+	for i, move := range strings.Fields(moves) {
+		if err := game.MoveStr(move); err != nil {
+			return nil, fmt.Errorf("illegal move `%s` at ply %d: %v", move, i, err)
+		}
+	}
+
+	return game, nil
+}
+
+// Returns true if the history of `s` extends the history of `other` (or
+// vice versa) from the same starting position with the same shared seed.
+func (s GameSnapshot) ConsistentWith(other GameSnapshot) bool {
+	if s.StartFEN != other.StartFEN || !bytes.Equal(s.Seed, other.Seed) {
+		return false
+	}
+
+	if !isPrefix(s.Moves, other.Moves) {
+		return false
+	}
+
+	// The two boards of a bughouse game go on independently
+	for _, board := range []int{bughouse.BoardA, bughouse.BoardB} {
+		if !isPrefix(boardHistory(s.Bughouse, board), boardHistory(other.Bughouse, board)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns the moves of `history` played on `board`
+func boardHistory(history []bughouse.Move, board int) []bughouse.Move {
+	var moves []bughouse.Move
+	for _, move := range history {
+		if move.Board == board {
+			moves = append(moves, move)
+		}
+	}
+
+	return moves
+}
+
+// Returns true if the shorter of `a` and `b` is a prefix of the other one
+func isPrefix[T comparable](a, b []T) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	return slices.Equal(a, b[:len(a)])
+}
+
+// Returns the number of moves of the snapshot, on any board
+func (s GameSnapshot) length() int {
+	return len(s.Moves) + len(s.Bughouse)
+}
+
+// Picks the longest history which is consistent with all the snapshots. Peers
+// can be behind by some moves, but they can't disagree on the played ones.
+func SelectSnapshot(snapshots []GameSnapshot) (GameSnapshot, error) {
+	if len(snapshots) == 0 {
+		return GameSnapshot{}, errors.New("no snapshot received")
+	}
+
+	best := -1
+	for i, candidate := range snapshots {
+		consistent := true
+		for _, other := range snapshots {
+			if !candidate.ConsistentWith(other) {
+				consistent = false
+				break
+			}
+		}
+
+		if consistent && (best == -1 || candidate.length() > snapshots[best].length()) {
+			best = i
+		}
+	}
+
+	if best == -1 {
+		return GameSnapshot{}, errors.New("peers disagree on the game history")
+	}
+
+	selected := snapshots[best]
+
+	// Peers can be ahead on different boards of a bughouse game, so each board
+	// goes on from its longest history
+	var merged []bughouse.Move
+	for _, board := range []int{bughouse.BoardA, bughouse.BoardB} {
+		var longest []bughouse.Move
+		for _, snapshot := range snapshots {
+			if moves := boardHistory(snapshot.Bughouse, board); len(moves) > len(longest) {
+				longest = moves
+			}
+		}
+		merged = append(merged, longest...)
+	}
+	selected.Bughouse = merged
+
+	for _, snapshot := range snapshots {
+		if !selected.ConsistentWith(snapshot) {
+			return GameSnapshot{}, errors.New("peers disagree on the game history")
+		}
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,124 @@
+package multiplayer
+
+import (
+	"testing"
+
+	"github.com/boozec/rahanna/pkg/bughouse"
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSnapshot(t *testing.T, moves ...string) GameSnapshot {
+	game := chess960.NewStandardGame()
+	for _, move := range moves {
+		assert.NoError(t, game.MoveStr(move))
+	}
+
+	return NewSnapshot(game, "game-1", nil)
+}
+
+// TestReplaySnapshot tests that only coherent snapshots can be replayed.
+func TestReplaySnapshot(t *testing.T) {
+	snapshot := newSnapshot(t, "e2e4", "e7e5", "g1f3")
+
+	game, err := snapshot.Replay()
+	assert.NoError(t, err)
+	assert.Len(t, game.Moves(), 3)
+
+	forged := snapshot
+	forged.Moves = []string{"e2e4", "e7e5", "g1g3"}
+	_, err = forged.Replay()
+	assert.Error(t, err, "Expected error replaying an illegal move")
+
+	forged = snapshot
+	forged.FEN = newSnapshot(t, "e2e4").FEN
+	_, err = forged.Replay()
+	assert.Error(t, err, "Expected error when the final position differs")
+}
+
+// TestSelectSnapshot tests that the longest consistent history is chosen.
+func TestSelectSnapshot(t *testing.T) {
+	behind := newSnapshot(t, "e2e4")
+	updated := newSnapshot(t, "e2e4", "e7e5", "g1f3")
+	middle := newSnapshot(t, "e2e4", "e7e5")
+
+	selected, err := SelectSnapshot([]GameSnapshot{behind, updated, middle})
+	assert.NoError(t, err)
+	assert.Equal(t, updated.Moves, selected.Moves)
+
+	// Only the history shared by everyone can be trusted
+	forked := newSnapshot(t, "e2e4", "c7c5", "g1f3")
+	selected, err = SelectSnapshot([]GameSnapshot{behind, updated, forked})
+	assert.NoError(t, err)
+	assert.Equal(t, behind.Moves, selected.Moves)
+
+	_, err = SelectSnapshot([]GameSnapshot{updated, forked})
+	assert.Error(t, err, "Expected error when peers disagree")
+
+	_, err = SelectSnapshot(nil)
+	assert.Error(t, err)
+}
+
+// TestSelectBughouseSnapshot tests that the histories of both boards are
+// compared.
+func TestSelectBughouseSnapshot(t *testing.T) {
+	behind := newSnapshot(t)
+	behind.Bughouse = []bughouse.Move{{Board: bughouse.BoardA, Move: "e2e4"}}
+
+	updated := newSnapshot(t)
+	updated.Bughouse = append(behind.Bughouse, bughouse.Move{Board: bughouse.BoardB, Move: "d2d4"})
+
+	selected, err := SelectSnapshot([]GameSnapshot{behind, updated})
+	assert.NoError(t, err)
+	assert.Equal(t, updated.Bughouse, selected.Bughouse)
+
+	// Peers ahead on different boards agree on the moves of both
+	aheadA := newSnapshot(t)
+	aheadA.Bughouse = []bughouse.Move{
+		{Board: bughouse.BoardA, Move: "e2e4"},
+		{Board: bughouse.BoardA, Ply: 1, Move: "e7e5"},
+	}
+
+	aheadB := newSnapshot(t)
+	aheadB.Bughouse = []bughouse.Move{
+		{Board: bughouse.BoardB, Move: "d2d4"},
+		{Board: bughouse.BoardA, Move: "e2e4"},
+	}
+
+	selected, err = SelectSnapshot([]GameSnapshot{aheadA, aheadB})
+	assert.NoError(t, err)
+	assert.Equal(t, append(aheadA.Bughouse, aheadB.Bughouse[0]), selected.Bughouse)
+
+	forked := newSnapshot(t)
+	forked.Bughouse = []bughouse.Move{{Board: bughouse.BoardB, Move: "e2e4"}}
+
+	_, err = SelectSnapshot([]GameSnapshot{updated, forked})
+	assert.Error(t, err, "Expected error when peers disagree")
+
+	forged := newSnapshot(t)
+	forged.Bughouse = []bughouse.Move{{Board: bughouse.BoardA, Move: "e7e5"}}
+	_, err = forged.Replay()
+	assert.Error(t, err)
+}
+
+// TestReplayMoves tests that the moves stored by the API are replayed from
+// their starting position.
+func TestReplayMoves(t *testing.T) {
+	game, err := ReplayMoves("", "e2e4 e7e5 g1f3")
+	assert.NoError(t, err)
+	assert.Len(t, game.Moves(), 3)
+	assert.Equal(t, chess960.StandardFEN, game.StartFEN())
+
+	fen, err := chess960.StartingFEN(0)
+	assert.NoError(t, err)
+	game, err = ReplayMoves(fen, "")
+	assert.NoError(t, err)
+	assert.Equal(t, fen, game.StartFEN())
+	assert.Empty(t, game.Moves())
+
+	_, err = ReplayMoves("", "e2e4 e2e4")
+	assert.Error(t, err)
+
+	_, err = ReplayMoves("not a fen", "e2e4")
+	assert.Error(t, err)
+}
@@ -0,0 +1,246 @@
+package multiplayer
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+)
+
+// Rating of the players without a rating
+const DefaultRating = 1500
+
+// What a strategy knows about the game when it picks the seat of a move
+type TurnContext struct {
+	Ply        int                   // Index of the move, starting from 0
+	Candidates []p2p.NetworkID       // Seats of the color to move, in seat order
+	Seed       []byte                // Shared seed, nil until every secret is revealed
+	Ratings    map[p2p.NetworkID]int // Rating of the player behind each seat
+}
+
+// TurnStrategy decides which seat of a team plays each move. Every peer runs
+// the same strategy, so it must only depend on the context.
+type TurnStrategy interface {
+	// Returns the seat which plays the `ctx.Ply`-th move
+	PickTurn(ctx TurnContext) p2p.NetworkID
+
+	// Returns an error if `source` can't assign the `ctx.Ply`-th move to `seat`
+	CheckTurn(ctx TurnContext, source p2p.NetworkID, seat p2p.NetworkID) error
+
+	// Returns true if the picks depend on the shared seed
+	UsesSeed() bool
+
+	// Returns true if every player of the team votes the move
+	TeamVotes() bool
+
+	// Returns true if the picked seat can hand the move over to a teammate
+	CanHandOver() bool
+}
+
+// Builds a strategy. `arg` is the number suffixed to the strategy name, 0 if
+// the name has no suffix.
+type StrategyFactory func(arg int) (TurnStrategy, error)
+
+var strategies = make(map[string]StrategyFactory)
+
+// Makes a strategy available with `name`. A registered name can be followed
+// by a number, such as `alternate-2`, which is passed to the factory.
+func RegisterStrategy(name string, factory StrategyFactory) {
+	if _, exists := strategies[name]; exists {
+		panic(fmt.Sprintf("strategy %s already registered", name))
+	}
+	strategies[name] = factory
+}
+
+// Returns the strategy called `name`
+func NewStrategy(name string) (TurnStrategy, error) {
+	if factory, exists := strategies[name]; exists {
+		return factory(0)
+	}
+
+	if i := strings.LastIndex(name, "-"); i != -1 {
+		if arg, err := strconv.Atoi(name[i+1:]); err == nil {
+			if factory, exists := strategies[name[:i]]; exists {
+				return factory(arg)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unknown move choose strategy `%s`", name)
+}
+
+// Returns the names of the registered strategies
+func Strategies() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Factory of the strategies without parameters
+func withoutArg(strategy TurnStrategy) StrategyFactory {
+	return func(arg int) (TurnStrategy, error) {
+		if arg != 0 {
+			return nil, fmt.Errorf("strategy does not accept a parameter")
+		}
+		return strategy, nil
+	}
+}
+
+func init() {
+	RegisterStrategy("sequential", withoutArg(AlternateStrategy{Every: 1}))
+	RegisterStrategy("random", withoutArg(RandomStrategy{}))
+	RegisterStrategy("consultation", withoutArg(ConsultationStrategy{}))
+	RegisterStrategy("weighted", withoutArg(WeightedStrategy{}))
+	RegisterStrategy("captain", withoutArg(CaptainStrategy{}))
+	RegisterStrategy("alternate", func(arg int) (TurnStrategy, error) {
+		if arg < 1 {
+			return nil, fmt.Errorf("alternate strategy needs a positive number of moves")
+		}
+		return AlternateStrategy{Every: arg}, nil
+	})
+}
+
+// Strategies whose seat is fully determined by the context
+type pickedStrategy struct{}
+
+func (pickedStrategy) UsesSeed() bool    { return false }
+func (pickedStrategy) TeamVotes() bool   { return false }
+func (pickedStrategy) CanHandOver() bool { return false }
+
+// Checks that `seat` is the one picked by `strategy`
+func checkPick(strategy TurnStrategy, ctx TurnContext, source p2p.NetworkID, seat p2p.NetworkID) error {
+	if expected := strategy.PickTurn(ctx); expected != seat {
+		return fmt.Errorf("%s assigned the turn to %s, but %s must move", source, seat, expected)
+	}
+	return nil
+}
+
+// The players of a team take turns every `Every` moves of the team. With
+// `Every` equal to 1 it is the sequential strategy.
+type AlternateStrategy struct {
+	pickedStrategy
+	Every int
+}
+
+func (s AlternateStrategy) PickTurn(ctx TurnContext) p2p.NetworkID {
+	if len(ctx.Candidates) == 0 {
+		return p2p.EmptyNetworkID
+	}
+	return ctx.Candidates[(ctx.Ply/2/s.Every)%len(ctx.Candidates)]
+}
+
+func (s AlternateStrategy) CheckTurn(ctx TurnContext, source p2p.NetworkID, seat p2p.NetworkID) error {
+	return checkPick(s, ctx, source, seat)
+}
+
+// The player of each move is picked from the shared seed
+type RandomStrategy struct {
+	pickedStrategy
+}
+
+func (s RandomStrategy) PickTurn(ctx TurnContext) p2p.NetworkID {
+	return PickTurn(ctx.Seed, ctx.Ply, ctx.Candidates)
+}
+
+func (s RandomStrategy) CheckTurn(ctx TurnContext, source p2p.NetworkID, seat p2p.NetworkID) error {
+	return checkPick(s, ctx, source, seat)
+}
+
+func (RandomStrategy) UsesSeed() bool { return true }
+
+// The player of each move is picked from the shared seed, with a probability
+// proportional to its rating
+type WeightedStrategy struct {
+	pickedStrategy
+}
+
+func (s WeightedStrategy) PickTurn(ctx TurnContext) p2p.NetworkID {
+	if len(ctx.Candidates) == 0 {
+		return p2p.EmptyNetworkID
+	}
+
+	weights := make([]uint64, len(ctx.Candidates))
+	var total uint64
+	for i, candidate := range ctx.Candidates {
+		rating, exists := ctx.Ratings[candidate]
+		if !exists {
+			rating = DefaultRating
+		}
+		weights[i] = uint64(max(rating, 1))
+		total += weights[i]
+	}
+
+	value := seedValue(ctx.Seed, ctx.Ply) % total
+	for i, weight := range weights {
+		if value < weight {
+			return ctx.Candidates[i]
+		}
+		value -= weight
+	}
+
+	return ctx.Candidates[len(ctx.Candidates)-1]
+}
+
+func (s WeightedStrategy) CheckTurn(ctx TurnContext, source p2p.NetworkID, seat p2p.NetworkID) error {
+	return checkPick(s, ctx, source, seat)
+}
+
+func (WeightedStrategy) UsesSeed() bool { return true }
+
+// Every player of the team votes the move, the captain plays it
+type ConsultationStrategy struct {
+	pickedStrategy
+}
+
+// The captain is the first seat of the team
+func (s ConsultationStrategy) PickTurn(ctx TurnContext) p2p.NetworkID {
+	if len(ctx.Candidates) == 0 {
+		return p2p.EmptyNetworkID
+	}
+	return ctx.Candidates[0]
+}
+
+func (s ConsultationStrategy) CheckTurn(ctx TurnContext, source p2p.NetworkID, seat p2p.NetworkID) error {
+	return checkPick(s, ctx, source, seat)
+}
+
+func (ConsultationStrategy) TeamVotes() bool { return true }
+
+// The captain of the team gets every move and decides whether to play it or
+// to hand it over to a teammate
+type CaptainStrategy struct {
+	pickedStrategy
+}
+
+// The captain is the first seat of the team
+func (s CaptainStrategy) PickTurn(ctx TurnContext) p2p.NetworkID {
+	if len(ctx.Candidates) == 0 {
+		return p2p.EmptyNetworkID
+	}
+	return ctx.Candidates[0]
+}
+
+func (s CaptainStrategy) CheckTurn(ctx TurnContext, source p2p.NetworkID, seat p2p.NetworkID) error {
+	captain := s.PickTurn(ctx)
+	if seat == captain {
+		return nil
+	}
+
+	if source != captain {
+		return fmt.Errorf("%s assigned the turn to %s, but only the captain %s can hand it over", source, seat, captain)
+	}
+
+	if !slices.Contains(ctx.Candidates, seat) {
+		return fmt.Errorf("%s handed the turn over to %s, which is not a teammate", source, seat)
+	}
+
+	return nil
+}
+
+func (CaptainStrategy) CanHandOver() bool { return true }
@@ -0,0 +1,95 @@
+package multiplayer
+
+import (
+	"testing"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the context of the `ply`-th move of a four players game
+func pairContext(ply int) TurnContext {
+	candidates := []p2p.NetworkID{"game-1", "game-3"}
+	if ply%2 == 1 {
+		candidates = []p2p.NetworkID{"game-2", "game-4"}
+	}
+
+	return TurnContext{Ply: ply, Candidates: candidates, Seed: []byte("shared seed")}
+}
+
+// TestNewStrategy tests the lookup of the registered strategies.
+func TestNewStrategy(t *testing.T) {
+	for _, name := range []string{"sequential", "random", "consultation", "weighted", "captain", "alternate-3"} {
+		_, err := NewStrategy(name)
+		assert.NoError(t, err, name)
+	}
+
+	for _, name := range []string{"", "unknown", "alternate", "alternate-0", "random-2", "captain-"} {
+		_, err := NewStrategy(name)
+		assert.Error(t, err, name)
+	}
+
+	assert.Contains(t, Strategies(), "alternate")
+}
+
+// TestSequentialStrategy tests that the seats move in order.
+func TestSequentialStrategy(t *testing.T) {
+	strategy, _ := NewStrategy("sequential")
+
+	var seats []p2p.NetworkID
+	for ply := 0; ply < 8; ply++ {
+		seats = append(seats, strategy.PickTurn(pairContext(ply)))
+	}
+
+	assert.Equal(t, []p2p.NetworkID{
+		"game-1", "game-2", "game-3", "game-4",
+		"game-1", "game-2", "game-3", "game-4",
+	}, seats)
+
+	assert.NoError(t, strategy.CheckTurn(pairContext(2), "game-2", "game-3"))
+	assert.Error(t, strategy.CheckTurn(pairContext(2), "game-2", "game-1"))
+}
+
+// TestAlternateStrategy tests that every player of a team plays N moves in a
+// row.
+func TestAlternateStrategy(t *testing.T) {
+	strategy, _ := NewStrategy("alternate-2")
+
+	var seats []p2p.NetworkID
+	for ply := 0; ply < 8; ply += 2 {
+		seats = append(seats, strategy.PickTurn(pairContext(ply)))
+	}
+
+	assert.Equal(t, []p2p.NetworkID{"game-1", "game-1", "game-3", "game-3"}, seats)
+}
+
+// TestWeightedStrategy tests that the best rated players move more often.
+func TestWeightedStrategy(t *testing.T) {
+	strategy, _ := NewStrategy("weighted")
+
+	counts := make(map[p2p.NetworkID]int)
+	for ply := 0; ply < 2000; ply += 2 {
+		ctx := pairContext(ply)
+		ctx.Ratings = map[p2p.NetworkID]int{"game-1": 2700, "game-3": 900}
+
+		seat := strategy.PickTurn(ctx)
+		assert.Equal(t, seat, strategy.PickTurn(ctx))
+		assert.NoError(t, strategy.CheckTurn(ctx, "game-2", seat))
+		counts[seat]++
+	}
+
+	assert.Greater(t, counts["game-1"], 2*counts["game-3"])
+	assert.Greater(t, counts["game-3"], 0)
+}
+
+// TestCaptainStrategy tests that only the captain can hand the move over.
+func TestCaptainStrategy(t *testing.T) {
+	strategy, _ := NewStrategy("captain")
+	ctx := pairContext(2)
+
+	assert.Equal(t, p2p.NetworkID("game-1"), strategy.PickTurn(ctx))
+	assert.NoError(t, strategy.CheckTurn(ctx, "game-4", "game-1"))
+	assert.NoError(t, strategy.CheckTurn(ctx, "game-1", "game-3"))
+	assert.Error(t, strategy.CheckTurn(ctx, "game-4", "game-3"))
+	assert.Error(t, strategy.CheckTurn(ctx, "game-1", "game-2"))
+}
@@ -0,0 +1,683 @@
+package multiplayer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/notnil/chess"
+)
+
+// Interval used to announce the readiness of a virtual peer until the game
+// starts
+const virtualReadyInterval = time.Second
+
+// Moves which can wait for their turn at the same time
+const virtualQueuedMoves = 8
+
+// Engine chooses the moves of a virtual peer
+type Engine interface {
+	// Returns the move of the player to move, in UCI notation
+	BestMove(game *chess960.Game) (string, error)
+}
+
+// What a virtual peer knows about the game it joins
+type VirtualGame struct {
+	Name       string                   // Name of the game, used for the seats IDs
+	Seats      int                      // Number of players, 2 or 4
+	MoveChoose string                   // Strategy of the turns
+	StartFEN   string                   // Empty for the standard starting position
+	Chess960   bool                     // The starting position is drawn from the shared seed
+	Addresses  map[p2p.NetworkID]string // Address of the other seats
+	Ratings    map[p2p.NetworkID]int    // Rating of the player behind each seat
+	Restore    bool                     // The game is already started, ask the others for its state
+}
+
+// The part of `GameNetwork` used by a virtual peer
+type virtualNetwork interface {
+	Me() p2p.NetworkID
+	Send(peer p2p.NetworkID, messageType []byte, payload []byte) error
+	SendAll(messageType []byte, payload []byte) error
+	AddPeer(remoteID p2p.NetworkID, addr string)
+	AddReceiveFunction(f p2p.NetworkMessageReceiveFunc)
+	AllPeersConnected() bool
+}
+
+// VirtualPeer is a player without a user interface: it joins a game as any
+// other peer and an engine chooses its moves.
+type VirtualPeer struct {
+	sync.Mutex
+
+	network  virtualNetwork
+	engine   Engine
+	config   VirtualGame
+	strategy TurnStrategy
+	signer   *Signer
+	entropy  *Entropy
+
+	game       *chess960.Game
+	turn       p2p.NetworkID
+	seed       []byte
+	publicKeys map[p2p.NetworkID][]byte
+	queued     []virtualMove
+	proposals  map[string]Proposal
+	restoring  bool
+	searching  bool
+	done       chan struct{}
+	closed     bool
+}
+
+func NewVirtualPeer(network virtualNetwork, engine Engine) *VirtualPeer {
+	return &VirtualPeer{
+		network:    network,
+		engine:     engine,
+		game:       chess960.NewStandardGame(),
+		publicKeys: make(map[p2p.NetworkID][]byte),
+		proposals:  make(map[string]Proposal),
+		done:       make(chan struct{}),
+	}
+}
+
+// Connects to `peer` before joining the game, so the peer can see this seat
+// while it waits for the other players
+func (v *VirtualPeer) Connect(peer p2p.NetworkID, addr string) {
+	v.network.AddPeer(peer, addr)
+}
+
+// Joins the game: connects to every seat and plays until the game ends
+func (v *VirtualPeer) Join(config VirtualGame) error {
+	strategy, err := NewStrategy(config.MoveChoose)
+	if err != nil {
+		return err
+	}
+
+	v.Lock()
+	joined := v.signer != nil
+	v.Unlock()
+
+	if joined {
+		return errors.New("the virtual peer already joined a game")
+	}
+
+	if strategy.TeamVotes() {
+		return fmt.Errorf("a virtual peer can't play with the %s strategy", config.MoveChoose)
+	}
+
+	game := chess960.NewStandardGame()
+	if config.StartFEN != "" {
+		if game, err = chess960.NewGame(config.StartFEN); err != nil {
+			return err
+		}
+	}
+
+	signer, err := NewSigner()
+	if err != nil {
+		return err
+	}
+
+	v.Lock()
+	v.config = config
+	v.strategy = strategy
+	v.signer = signer
+	v.game = game
+	v.restoring = config.Restore
+
+	if v.usesSharedSeed() && !config.Restore {
+		if v.entropy, err = NewEntropy(v.network.Me()); err != nil {
+			v.Unlock()
+			return err
+		}
+	}
+	v.Unlock()
+
+	for _, seat := range v.seats() {
+		if addr := config.Addresses[seat]; seat != v.network.Me() && addr != "" {
+			v.network.AddPeer(seat, addr)
+		}
+	}
+
+	v.network.AddReceiveFunction(func(msg p2p.Message) {
+		v.Lock()
+		defer v.Unlock()
+
+		v.handle(msg)
+	})
+
+	go v.announce()
+
+	return nil
+}
+
+// Stops playing and closes the network and the engine of the peer
+func (v *VirtualPeer) Close() error {
+	v.Lock()
+	v.stop()
+	v.Unlock()
+
+	// External engines run in their own process
+	if closer, ok := v.engine.(io.Closer); ok {
+		closer.Close()
+	}
+
+	if closer, ok := v.network.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (v *VirtualPeer) stop() {
+	if !v.closed {
+		v.closed = true
+		close(v.done)
+	}
+}
+
+// Returns a copy of the game played by the peer
+func (v *VirtualPeer) Game() *chess960.Game {
+	v.Lock()
+	defer v.Unlock()
+
+	return v.game.Clone()
+}
+
+// Announces the readiness until the game starts, since messages can be lost
+// while the other peers are not listening yet
+func (v *VirtualPeer) announce() {
+	ticker := time.NewTicker(virtualReadyInterval)
+	defer ticker.Stop()
+
+	for {
+		if v.network.AllPeersConnected() {
+			v.Lock()
+			started := v.turn != p2p.EmptyNetworkID && (!v.usesSharedSeed() || v.seed != nil)
+			if v.restoring {
+				v.network.SendAll([]byte(string(RestoreGameMessage)), []byte(v.network.Me()))
+				v.network.SendAll([]byte(string(PublicKeyGameMessage)), v.signer.PublicKey())
+			} else if !started {
+				v.network.SendAll([]byte(string(ReadyGameMessage)), []byte(v.network.Me()))
+				v.network.SendAll([]byte(string(PublicKeyGameMessage)), v.signer.PublicKey())
+				if v.entropy != nil {
+					v.network.SendAll([]byte(string(CommitGameMessage)), v.entropy.Commitment())
+				}
+				v.revealSecret()
+			}
+			v.Unlock()
+
+			if started {
+				return
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-v.done:
+			return
+		}
+	}
+}
+
+// Returns the network IDs of all the seats of the game
+func (v *VirtualPeer) seats() []p2p.NetworkID {
+	var seats []p2p.NetworkID
+	for i := 1; i <= v.config.Seats; i++ {
+		seats = append(seats, p2p.NetworkID(fmt.Sprintf("%s-%d", v.config.Name, i)))
+	}
+	return seats
+}
+
+// Returns the color played by the team of `seat`. Odd seats are white.
+func (v *VirtualPeer) seatColor(seat p2p.NetworkID) chess.Color {
+	index := slices.Index(v.seats(), seat)
+	switch {
+	case index < 0:
+		return chess.NoColor
+	case index%2 == 0:
+		return chess.White
+	default:
+		return chess.Black
+	}
+}
+
+func (v *VirtualPeer) usesSharedSeed() bool {
+	return v.strategy.UsesSeed() || v.config.Chess960
+}
+
+// Returns what the strategy knows about the `ply`-th move
+func (v *VirtualPeer) turnContext(ply int) TurnContext {
+	color := v.game.Positions()[0].Turn()
+	if ply%2 == 1 {
+		color = color.Other()
+	}
+
+	var candidates []p2p.NetworkID
+	for _, seat := range v.seats() {
+		if v.seatColor(seat) == color {
+			candidates = append(candidates, seat)
+		}
+	}
+
+	return TurnContext{Ply: ply, Candidates: candidates, Seed: v.seed, Ratings: v.config.Ratings}
+}
+
+// Handles a message from another peer. The caller holds the lock.
+func (v *VirtualPeer) handle(msg p2p.Message) {
+	if v.closed {
+		return
+	}
+
+	switch MoveType(msg.Type) {
+	case AbandonGameMessage:
+		v.stop()
+	case ReadyGameMessage:
+		v.revealSecret()
+	case CommitGameMessage:
+		v.handleCommit(msg)
+	case RevealGameMessage:
+		v.handleReveal(msg)
+	case PublicKeyGameMessage:
+		v.storePublicKey(msg.Source, msg.Payload)
+	case DefineTurnMessage:
+		v.handleTurn(msg)
+	case MoveGameMessage:
+		v.handleMove(msg)
+	case RejectMoveGameMessage:
+		v.handleRejection(msg)
+	case RollbackGameMessage:
+		v.handleRollback(msg)
+	case RestoreGameMessage:
+		// The restoring peer signs its moves with a new key
+		delete(v.publicKeys, msg.Source)
+		v.sendSnapshot(msg.Source)
+	case RestoreAckGameMessage:
+		v.handleSnapshot(msg)
+	case ProposeGameMessage:
+		v.handleProposal(msg)
+	case ProposalResultGameMessage:
+		v.handleProposalResult(msg)
+	}
+}
+
+// Reveals the local secret once every seat has committed to its own
+func (v *VirtualPeer) revealSecret() {
+	if v.entropy != nil {
+		v.entropy.RevealSecret(v.network, v.seats())
+	}
+}
+
+func (v *VirtualPeer) handleCommit(msg p2p.Message) {
+	if v.entropy == nil || v.entropy.ReceiveCommitment(v.network, msg.Source, msg.Payload) != nil {
+		return
+	}
+
+	v.revealSecret()
+}
+
+func (v *VirtualPeer) handleReveal(msg p2p.Message) {
+	if v.entropy == nil || v.seed != nil {
+		return
+	}
+
+	seed, err := v.entropy.ReceiveSecret(msg.Source, msg.Payload, v.seats())
+	if err != nil || seed == nil {
+		return
+	}
+	v.seed = seed
+
+	if v.config.Chess960 && len(v.game.Moves()) == 0 {
+		fen, err := StartPositionFEN(seed)
+		if err != nil {
+			return
+		}
+		if game, err := chess960.NewGame(fen); err == nil {
+			v.game = game
+		}
+	}
+
+	// The turn could have arrived before the seed
+	v.play()
+}
+
+// Starts the search of a move if the peer has the turn
+func (v *VirtualPeer) play() {
+	if v.searching || v.turn != v.network.Me() || v.game.Outcome() != chess.NoOutcome {
+		return
+	}
+
+	if v.usesSharedSeed() && v.seed == nil {
+		return
+	}
+
+	v.searching = true
+	go v.search(v.game.Clone())
+}
+
+// Asks the engine the move for `game` without holding the lock, since the
+// search can take its time, and plays it if the game did not change meanwhile
+func (v *VirtualPeer) search(game *chess960.Game) {
+	move, err := v.engine.BestMove(game)
+
+	v.Lock()
+	defer v.Unlock()
+
+	v.searching = false
+	if v.closed {
+		return
+	}
+
+	// A takeback or a restore changed the position: search it again
+	if len(v.game.Moves()) != len(game.Moves()) || v.game.FEN() != game.FEN() {
+		v.play()
+		return
+	}
+
+	if err != nil {
+		v.report(fmt.Errorf("the engine can't find a move: %v", err))
+		return
+	}
+
+	v.move(move)
+}
+
+// Plays the move chosen by the engine, then assigns the next turn
+func (v *VirtualPeer) move(move string) {
+	ply := len(v.game.Moves())
+	if err := v.game.MoveStr(move); err != nil {
+		v.report(fmt.Errorf("the engine chose an illegal move: %v", err))
+		return
+	}
+
+	payload, err := json.Marshal(v.signer.SignMove(move, ply, time.Now()))
+	if err != nil {
+		v.report(err)
+		return
+	}
+
+	v.network.SendAll([]byte(string(MoveGameMessage)), payload)
+
+	if v.game.Outcome() != chess.NoOutcome {
+		return
+	}
+
+	v.turn = v.strategy.PickTurn(v.turnContext(len(v.game.Moves())))
+	v.network.SendAll([]byte(string(DefineTurnMessage)), []byte(v.turn))
+
+	v.play()
+}
+
+// Stores the signing key of the seat `peer`, which can't change it during the
+// game
+func (v *VirtualPeer) storePublicKey(peer p2p.NetworkID, key []byte) {
+	if !slices.Contains(v.seats(), peer) || len(key) == 0 {
+		return
+	}
+
+	if known, ok := v.publicKeys[peer]; ok {
+		if !bytes.Equal(known, key) {
+			v.report(fmt.Errorf("%s changed its signing key", peer))
+		}
+		return
+	}
+
+	v.publicKeys[peer] = key
+}
+
+// Saves the turn assigned by another seat, which must follow the strategy of
+// the game, then plays the moves which waited for it
+func (v *VirtualPeer) handleTurn(msg p2p.Message) {
+	v.turn = p2p.NetworkID(msg.Payload)
+
+	if !v.strategy.UsesSeed() || v.seed != nil {
+		ctx := v.turnContext(len(v.game.Moves()))
+		if err := v.strategy.CheckTurn(ctx, msg.Source, v.turn); err != nil {
+			v.turn = v.strategy.PickTurn(ctx)
+		}
+	}
+
+	v.playQueuedMoves()
+	v.play()
+}
+
+// A move received from a seat
+type virtualMove struct {
+	source p2p.NetworkID
+	signed SignedMove
+}
+
+func (v *VirtualPeer) handleMove(msg p2p.Message) {
+	var signed SignedMove
+	if err := json.Unmarshal(msg.Payload, &signed); err != nil {
+		return
+	}
+
+	v.receiveMove(virtualMove{source: msg.Source, signed: signed}, true)
+}
+
+// Plays the queued moves whose turn is now known. The ones of the current ply
+// are played or refused, the later ones wait again.
+func (v *VirtualPeer) playQueuedMoves() {
+	for {
+		i := slices.IndexFunc(v.queued, func(move virtualMove) bool {
+			return move.signed.Ply <= len(v.game.Moves())
+		})
+		if i < 0 {
+			return
+		}
+
+		move := v.queued[i]
+		v.queued = slices.Delete(v.queued, i, i+1)
+		v.receiveMove(move, false)
+	}
+}
+
+// Plays a move received from a seat, or refuses it. If `canWait`, a move whose
+// turn is not known yet is queued instead, as in the game view.
+func (v *VirtualPeer) receiveMove(move virtualMove, canWait bool) {
+	if !slices.Contains(v.seats(), move.source) {
+		return
+	}
+
+	ply := len(v.game.Moves())
+	signed := move.signed
+
+	// The move after the next one, or the next one from another seat, can
+	// arrive before its turn
+	queue := signed.Ply > ply || signed.Ply == ply && move.source != v.turn
+	if canWait && queue && len(v.queued) < virtualQueuedMoves {
+		v.queued = append(v.queued, move)
+		return
+	}
+
+	// Only the seat to move can move, with its signing key
+	var err error
+	if signed.Ply != ply {
+		err = fmt.Errorf("move %d sent at move %d", signed.Ply, ply)
+	} else if move.source != v.turn {
+		err = fmt.Errorf("not the turn of %s", move.source)
+	} else if key, ok := v.publicKeys[move.source]; !ok {
+		err = fmt.Errorf("no signing key from %s", move.source)
+	} else {
+		err = signed.Verify(key)
+	}
+	if err == nil {
+		err = v.game.MoveStr(signed.Move)
+	}
+
+	if err != nil {
+		payload, _ := json.Marshal(MoveRejection{Move: signed.Move, Reason: err.Error()})
+		v.network.Send(move.source, []byte(string(RejectMoveGameMessage)), payload)
+	}
+}
+
+// A peer refused our last move: roll it back everywhere and take the turn
+// again
+func (v *VirtualPeer) handleRejection(msg p2p.Message) {
+	var rejection MoveRejection
+	if err := json.Unmarshal(msg.Payload, &rejection); err != nil {
+		return
+	}
+
+	moves := v.game.Moves()
+	if len(moves) == 0 || moves[len(moves)-1].String() != rejection.Move || v.seatColor(v.network.Me()) != v.game.Positions()[len(moves)-1].Turn() {
+		return
+	}
+
+	ply := len(moves) - 1
+	v.rollback(ply)
+	v.dropQueuedMoves(ply)
+
+	payload, _ := json.Marshal(MoveRollback{Move: rejection.Move, Ply: ply})
+	v.network.SendAll([]byte(string(RollbackGameMessage)), payload)
+
+	v.turn = v.network.Me()
+	v.network.SendAll([]byte(string(DefineTurnMessage)), []byte(v.turn))
+	v.report(fmt.Errorf("move `%s` rejected: %s", rejection.Move, rejection.Reason))
+}
+
+// Another peer refused the last move of a seat, which rolled it back: roll it
+// back too if its team played it
+func (v *VirtualPeer) handleRollback(msg p2p.Message) {
+	var rollback MoveRollback
+	if err := json.Unmarshal(msg.Payload, &rollback); err != nil {
+		return
+	}
+
+	v.dropQueuedMoves(rollback.Ply)
+
+	moves := v.game.Moves()
+	if rollback.Ply < 0 || len(moves) != rollback.Ply+1 || moves[rollback.Ply].String() != rollback.Move {
+		return
+	}
+
+	if v.seatColor(msg.Source) != v.game.Positions()[rollback.Ply].Turn() {
+		return
+	}
+
+	v.rollback(rollback.Ply)
+	v.turn = msg.Source
+}
+
+// Tells the players in the chat why the peer stopped playing, since it has no
+// user interface to show the error
+func (v *VirtualPeer) report(err error) {
+	payload, _ := json.Marshal(ChatMessage{Channel: AllChannel, Text: err.Error(), Timestamp: time.Now()})
+	v.network.SendAll([]byte(string(ChatGameMessage)), payload)
+}
+
+// Forgets the queued moves played after the `ply`-th one, which are built on
+// a move rolled back
+func (v *VirtualPeer) dropQueuedMoves(ply int) {
+	v.queued = slices.DeleteFunc(v.queued, func(move virtualMove) bool {
+		return move.signed.Ply >= ply
+	})
+}
+
+// Replays only the first `n` moves of the game
+func (v *VirtualPeer) rollback(n int) {
+	if game, err := v.game.Rollback(n); err == nil {
+		v.game = game
+	}
+}
+
+func (v *VirtualPeer) sendSnapshot(peer p2p.NetworkID) {
+	snapshot := NewSnapshot(v.game, v.turn, v.seed)
+	snapshot.Key = v.signer.PublicKey()
+
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return
+	}
+
+	v.network.Send(peer, []byte(string(RestoreAckGameMessage)), payload)
+}
+
+// Restores the game from the snapshot of another seat, if it is ahead. Every
+// seat sends its signing key with its snapshot.
+func (v *VirtualPeer) handleSnapshot(msg p2p.Message) {
+	var snapshot GameSnapshot
+	if err := json.Unmarshal(msg.Payload, &snapshot); err != nil {
+		return
+	}
+	v.storePublicKey(msg.Source, snapshot.Key)
+
+	if !v.restoring || !slices.Contains(v.seats(), msg.Source) {
+		return
+	}
+
+	game, err := snapshot.Replay()
+	if err != nil {
+		return
+	}
+
+	v.game = game
+	v.turn = snapshot.Turn
+	v.seed = snapshot.Seed
+	v.restoring = false
+
+	v.play()
+}
+
+// Answers a proposal: the rules validate the claims, the teammate can resign,
+// but the engine never agrees to draws or takebacks
+func (v *VirtualPeer) handleProposal(msg p2p.Message) {
+	var proposal Proposal
+	if err := json.Unmarshal(msg.Payload, &proposal); err != nil || proposal.Proposer != msg.Source {
+		return
+	}
+
+	// The result of the proposal is needed even if we don't answer it
+	v.proposals[proposal.ID] = proposal
+	sameTeam := v.seatColor(msg.Source) == v.seatColor(v.network.Me())
+
+	var accepted bool
+	switch proposal.Kind {
+	case ClaimProposal:
+		accepted = ValidateClaim(v.game, proposal.Method) == nil
+	case ResignProposal:
+		if !sameTeam {
+			return
+		}
+		accepted = true
+	default:
+		if sameTeam {
+			return
+		}
+	}
+
+	payload, _ := json.Marshal(ProposalAnswer{ID: proposal.ID, Accepted: accepted})
+	v.network.Send(msg.Source, []byte(string(AnswerGameMessage)), payload)
+}
+
+// The opponents accepted a takeback of our team: undo its last move
+func (v *VirtualPeer) handleProposalResult(msg p2p.Message) {
+	var result ProposalResult
+	if err := json.Unmarshal(msg.Payload, &result); err != nil {
+		return
+	}
+
+	proposal, ok := v.proposals[result.ID]
+	delete(v.proposals, result.ID)
+	if !ok || proposal.Proposer != msg.Source || result.Status != AcceptedProposal {
+		return
+	}
+
+	switch proposal.Kind {
+	case TakebackProposal:
+		plies := TakebackPlies(v.game, v.seatColor(proposal.Proposer))
+		if ply := len(v.game.Moves()); ply == proposal.Ply && plies > 0 {
+			v.rollback(ply - plies)
+			v.turn = v.strategy.PickTurn(v.turnContext(len(v.game.Moves())))
+			v.play()
+		}
+	default:
+		// Draws and resignations end the game
+		v.stop()
+	}
+}
@@ -0,0 +1,296 @@
+package multiplayer
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/stretchr/testify/assert"
+)
+
+// Network which records the sent messages and delivers the received ones
+type fakeNetwork struct {
+	sync.Mutex
+
+	me      p2p.NetworkID
+	receive p2p.NetworkMessageReceiveFunc
+	sent    []p2p.Message
+}
+
+func (n *fakeNetwork) Me() p2p.NetworkID { return n.me }
+
+func (n *fakeNetwork) Send(peer p2p.NetworkID, messageType []byte, payload []byte) error {
+	return n.SendAll(messageType, payload)
+}
+
+func (n *fakeNetwork) SendAll(messageType []byte, payload []byte) error {
+	n.Lock()
+	defer n.Unlock()
+
+	n.sent = append(n.sent, p2p.Message{Type: messageType, Source: n.me, Payload: payload})
+	return nil
+}
+
+func (n *fakeNetwork) AddPeer(p2p.NetworkID, string) {}
+
+func (n *fakeNetwork) AddReceiveFunction(f p2p.NetworkMessageReceiveFunc) { n.receive = f }
+
+func (n *fakeNetwork) AllPeersConnected() bool { return true }
+
+// Returns the payloads of the sent messages of type `moveType`
+func (n *fakeNetwork) sentOf(moveType MoveType) [][]byte {
+	n.Lock()
+	defer n.Unlock()
+
+	var payloads [][]byte
+	for _, msg := range n.sent {
+		if MoveType(msg.Type) == moveType {
+			payloads = append(payloads, msg.Payload)
+		}
+	}
+	return payloads
+}
+
+// Engine which plays the first legal move
+type firstMoveEngine struct{}
+
+func (firstMoveEngine) BestMove(game *chess960.Game) (string, error) {
+	return game.ValidMoves()[0].String(), nil
+}
+
+// Engine which can't find a move
+type failingEngine struct{}
+
+func (failingEngine) BestMove(*chess960.Game) (string, error) {
+	return "", errors.New("engine crashed")
+}
+
+// Engine which plays the first legal move once it is released
+type blockingEngine struct {
+	release chan struct{}
+}
+
+func (e blockingEngine) BestMove(game *chess960.Game) (string, error) {
+	<-e.release
+	return firstMoveEngine{}.BestMove(game)
+}
+
+func signedMove(t *testing.T, signer *Signer, move string, ply int) []byte {
+	payload, err := json.Marshal(signer.SignMove(move, ply, time.Now()))
+	assert.NoError(t, err)
+	return payload
+}
+
+// Sends the signing key of `seat` to the virtual peer and gives it the first
+// turn, as the first seat does when the game starts
+func startGame(t *testing.T, network *fakeNetwork, seat p2p.NetworkID) *Signer {
+	signer, err := NewSigner()
+	assert.NoError(t, err)
+
+	network.receive(p2p.Message{Type: []byte(PublicKeyGameMessage), Source: seat, Payload: signer.PublicKey()})
+	network.receive(p2p.Message{Type: []byte(DefineTurnMessage), Source: seat, Payload: []byte(seat)})
+
+	return signer
+}
+
+// TestVirtualPeerPlays tests that a virtual peer answers its turn with a move
+// and gives the turn back.
+func TestVirtualPeerPlays(t *testing.T) {
+	network := &fakeNetwork{me: "game-2"}
+	peer := NewVirtualPeer(network, firstMoveEngine{})
+	defer peer.Close()
+
+	assert.NoError(t, peer.Join(VirtualGame{Name: "game", Seats: 2, MoveChoose: "sequential"}))
+
+	signer := startGame(t, network, "game-1")
+	network.receive(p2p.Message{Type: []byte(MoveGameMessage), Source: "game-1", Payload: signedMove(t, signer, "e2e4", 0)})
+	network.receive(p2p.Message{Type: []byte(DefineTurnMessage), Source: "game-1", Payload: []byte("game-2")})
+
+	// The engine searches in the background
+	assert.Eventually(t, func() bool {
+		return len(network.sentOf(DefineTurnMessage)) > 0
+	}, time.Second, time.Millisecond)
+
+	moves := network.sentOf(MoveGameMessage)
+	assert.Len(t, moves, 1)
+
+	var signed SignedMove
+	assert.NoError(t, json.Unmarshal(moves[0], &signed))
+	assert.Equal(t, 1, signed.Ply)
+	assert.Len(t, peer.Game().Moves(), 2)
+
+	turns := network.sentOf(DefineTurnMessage)
+	assert.Equal(t, [][]byte{[]byte("game-1")}, turns)
+}
+
+// TestVirtualPeerSearchUnlocked tests that the peer keeps handling messages
+// while the engine searches its move.
+func TestVirtualPeerSearchUnlocked(t *testing.T) {
+	network := &fakeNetwork{me: "game-1"}
+	engine := blockingEngine{release: make(chan struct{})}
+	peer := NewVirtualPeer(network, engine)
+	defer peer.Close()
+
+	assert.NoError(t, peer.Join(VirtualGame{Name: "game", Seats: 2, MoveChoose: "sequential"}))
+	network.receive(p2p.Message{Type: []byte(DefineTurnMessage), Source: "game-2", Payload: []byte("game-1")})
+
+	draw, _ := json.Marshal(Proposal{ID: "1", Kind: DrawProposal, Proposer: "game-2"})
+	network.receive(p2p.Message{Type: []byte(ProposeGameMessage), Source: "game-2", Payload: draw})
+	assert.Len(t, network.sentOf(AnswerGameMessage), 1)
+	assert.Empty(t, peer.Game().Moves())
+
+	close(engine.release)
+	assert.Eventually(t, func() bool {
+		return len(network.sentOf(MoveGameMessage)) == 1
+	}, time.Second, time.Millisecond)
+}
+
+// TestVirtualPeerReportsEngineError tests that the players are told when the
+// engine can't move.
+func TestVirtualPeerReportsEngineError(t *testing.T) {
+	network := &fakeNetwork{me: "game-1"}
+	peer := NewVirtualPeer(network, failingEngine{})
+	defer peer.Close()
+
+	assert.NoError(t, peer.Join(VirtualGame{Name: "game", Seats: 2, MoveChoose: "sequential"}))
+	network.receive(p2p.Message{Type: []byte(DefineTurnMessage), Source: "game-2", Payload: []byte("game-1")})
+
+	assert.Eventually(t, func() bool {
+		return len(network.sentOf(ChatGameMessage)) == 1
+	}, time.Second, time.Millisecond)
+
+	var chat ChatMessage
+	assert.NoError(t, json.Unmarshal(network.sentOf(ChatGameMessage)[0], &chat))
+	assert.Contains(t, chat.Text, "engine crashed")
+	assert.Empty(t, network.sentOf(MoveGameMessage))
+}
+
+// TestVirtualPeerRejectsIllegalMove tests that an illegal move is refused.
+func TestVirtualPeerRejectsIllegalMove(t *testing.T) {
+	network := &fakeNetwork{me: "game-2"}
+	peer := NewVirtualPeer(network, firstMoveEngine{})
+	defer peer.Close()
+
+	assert.NoError(t, peer.Join(VirtualGame{Name: "game", Seats: 2, MoveChoose: "sequential"}))
+
+	signer := startGame(t, network, "game-1")
+	network.receive(p2p.Message{Type: []byte(MoveGameMessage), Source: "game-1", Payload: signedMove(t, signer, "e2e5", 0)})
+
+	assert.Len(t, network.sentOf(RejectMoveGameMessage), 1)
+	assert.Empty(t, peer.Game().Moves())
+}
+
+// TestVirtualPeerVerifiesMoves tests that a move is refused without the key of
+// its sender, with a forged signature or out of turn.
+func TestVirtualPeerVerifiesMoves(t *testing.T) {
+	network := &fakeNetwork{me: "game-4"}
+	peer := NewVirtualPeer(network, firstMoveEngine{})
+	defer peer.Close()
+
+	assert.NoError(t, peer.Join(VirtualGame{Name: "game", Seats: 4, MoveChoose: "sequential"}))
+	network.receive(p2p.Message{Type: []byte(DefineTurnMessage), Source: "game-1", Payload: []byte("game-1")})
+
+	signer, _ := NewSigner()
+	network.receive(p2p.Message{Type: []byte(MoveGameMessage), Source: "game-1", Payload: signedMove(t, signer, "e2e4", 0)})
+	assert.Len(t, network.sentOf(RejectMoveGameMessage), 1, "Expected a move without a key to be refused")
+
+	forger, _ := NewSigner()
+	network.receive(p2p.Message{Type: []byte(PublicKeyGameMessage), Source: "game-1", Payload: signer.PublicKey()})
+	network.receive(p2p.Message{Type: []byte(MoveGameMessage), Source: "game-1", Payload: signedMove(t, forger, "e2e4", 0)})
+	assert.Len(t, network.sentOf(RejectMoveGameMessage), 2, "Expected a forged move to be refused")
+	assert.Empty(t, peer.Game().Moves())
+
+	// The teammate of the seat to move can't take its turn and play for it
+	partner, _ := NewSigner()
+	network.receive(p2p.Message{Type: []byte(PublicKeyGameMessage), Source: "game-3", Payload: partner.PublicKey()})
+	network.receive(p2p.Message{Type: []byte(DefineTurnMessage), Source: "game-3", Payload: []byte("game-3")})
+	network.receive(p2p.Message{Type: []byte(MoveGameMessage), Source: "game-3", Payload: signedMove(t, partner, "e2e4", 0)})
+	assert.Empty(t, peer.Game().Moves())
+
+	network.receive(p2p.Message{Type: []byte(MoveGameMessage), Source: "game-1", Payload: signedMove(t, signer, "e2e4", 0)})
+	assert.Len(t, peer.Game().Moves(), 1)
+
+	// The move of the teammate waited for a turn which never came
+	network.receive(p2p.Message{Type: []byte(DefineTurnMessage), Source: "game-1", Payload: []byte("game-2")})
+	assert.Len(t, network.sentOf(RejectMoveGameMessage), 3, "Expected a move out of turn to be refused")
+	assert.Len(t, peer.Game().Moves(), 1)
+}
+
+// TestVirtualPeerQueuesMoves tests that a move which arrives before its turn
+// waits for it.
+func TestVirtualPeerQueuesMoves(t *testing.T) {
+	network := &fakeNetwork{me: "game-4"}
+	peer := NewVirtualPeer(network, blockingEngine{release: make(chan struct{})})
+	defer peer.Close()
+
+	assert.NoError(t, peer.Join(VirtualGame{Name: "game", Seats: 4, MoveChoose: "sequential"}))
+
+	white := startGame(t, network, "game-1")
+	black, _ := NewSigner()
+	network.receive(p2p.Message{Type: []byte(PublicKeyGameMessage), Source: "game-2", Payload: black.PublicKey()})
+
+	// The move of game-2 overtakes the one of game-1 and its turn
+	network.receive(p2p.Message{Type: []byte(MoveGameMessage), Source: "game-2", Payload: signedMove(t, black, "e7e5", 1)})
+	network.receive(p2p.Message{Type: []byte(MoveGameMessage), Source: "game-1", Payload: signedMove(t, white, "e2e4", 0)})
+	assert.Len(t, peer.Game().Moves(), 1)
+
+	network.receive(p2p.Message{Type: []byte(DefineTurnMessage), Source: "game-1", Payload: []byte("game-2")})
+	assert.Len(t, peer.Game().Moves(), 2)
+	assert.Empty(t, network.sentOf(RejectMoveGameMessage))
+}
+
+// TestVirtualPeerRollsBack tests that the peer rolls back a move refused by
+// another peer when its sender asks to.
+func TestVirtualPeerRollsBack(t *testing.T) {
+	network := &fakeNetwork{me: "game-2"}
+	peer := NewVirtualPeer(network, firstMoveEngine{})
+	defer peer.Close()
+
+	assert.NoError(t, peer.Join(VirtualGame{Name: "game", Seats: 2, MoveChoose: "sequential"}))
+
+	signer := startGame(t, network, "game-1")
+	network.receive(p2p.Message{Type: []byte(MoveGameMessage), Source: "game-1", Payload: signedMove(t, signer, "e2e4", 0)})
+	assert.Len(t, peer.Game().Moves(), 1)
+
+	rollback, _ := json.Marshal(MoveRollback{Move: "e2e4", Ply: 0})
+	network.receive(p2p.Message{Type: []byte(RollbackGameMessage), Source: "game-2", Payload: rollback})
+	assert.Len(t, peer.Game().Moves(), 1, "Expected only the team of the move to roll it back")
+
+	network.receive(p2p.Message{Type: []byte(RollbackGameMessage), Source: "game-1", Payload: rollback})
+	assert.Empty(t, peer.Game().Moves())
+}
+
+// TestVirtualPeerDeclinesDraw tests that the engine never agrees to a draw
+// offer, but accepts the resignation of its teammate.
+func TestVirtualPeerDeclinesDraw(t *testing.T) {
+	network := &fakeNetwork{me: "game-4"}
+	peer := NewVirtualPeer(network, firstMoveEngine{})
+	defer peer.Close()
+
+	assert.NoError(t, peer.Join(VirtualGame{Name: "game", Seats: 4, MoveChoose: "sequential"}))
+
+	draw, _ := json.Marshal(Proposal{ID: "1", Kind: DrawProposal, Proposer: "game-1"})
+	network.receive(p2p.Message{Type: []byte(ProposeGameMessage), Source: "game-1", Payload: draw})
+
+	resign, _ := json.Marshal(Proposal{ID: "2", Kind: ResignProposal, Proposer: "game-2"})
+	network.receive(p2p.Message{Type: []byte(ProposeGameMessage), Source: "game-2", Payload: resign})
+
+	var answers []ProposalAnswer
+	for _, payload := range network.sentOf(AnswerGameMessage) {
+		var answer ProposalAnswer
+		assert.NoError(t, json.Unmarshal(payload, &answer))
+		answers = append(answers, answer)
+	}
+
+	assert.Equal(t, []ProposalAnswer{{ID: "1", Accepted: false}, {ID: "2", Accepted: true}}, answers)
+}
+
+// TestVirtualPeerConsultation tests that a virtual peer can't vote moves.
+func TestVirtualPeerConsultation(t *testing.T) {
+	peer := NewVirtualPeer(&fakeNetwork{me: "game-2"}, firstMoveEngine{})
+	assert.Error(t, peer.Join(VirtualGame{Name: "game", Seats: 4, MoveChoose: "consultation"}))
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// Directory where the local data of the games is stored, next to `.rahannarc`
+const baseDir = ".rahanna"
+
+// Returns the directory of the local data of a game
+func GameDir(gameID int) string {
+	return filepath.Join(baseDir, "games", strconv.Itoa(gameID))
+}
+
+// Appends `v` as a JSON line to the file `name` of the game
+func Append(gameID int, name string, v any) error {
+	dir := GameDir(gameID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// Reads all the JSON lines of the file `name` of the game. A missing file is
+// an empty list.
+func Load[T any](gameID int, name string) ([]T, error) {
+	f, err := os.Open(filepath.Join(GameDir(gameID), name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var values []T
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var v T
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, scanner.Err()
+}
+
+// Returns the directory of an offline game, which is never sent to the API
+func LocalGameDir(id int) string {
+	return filepath.Join(baseDir, "local", strconv.Itoa(id))
+}
+
+// Returns the IDs of the offline games, in ascending order
+func LocalGameIDs() ([]int, error) {
+	entries, err := os.ReadDir(filepath.Join(baseDir, "local"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for _, entry := range entries {
+		if id, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	return ids, nil
+}
+
+// Writes `v` as JSON to the file `name` of `dir`, replacing its content
+func Write(dir string, name string, v any) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// Written aside and renamed, so a crash never leaves half a file
+	tmp := filepath.Join(dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(dir, name))
+}
+
+// Reads the JSON file `name` of `dir`
+func Read[T any](dir string, name string) (T, error) {
+	var v T
+
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return v, err
+	}
+
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
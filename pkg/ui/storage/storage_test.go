@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type entry struct {
+	Text string `json:"text"`
+}
+
+// TestAppendLoad tests that the appended lines are loaded back in order.
+func TestAppendLoad(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	values, err := Load[entry](1, "chat.jsonl")
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+
+	assert.NoError(t, Append(1, "chat.jsonl", entry{Text: "hello"}))
+	assert.NoError(t, Append(1, "chat.jsonl", entry{Text: "world"}))
+
+	values, err = Load[entry](1, "chat.jsonl")
+	assert.NoError(t, err)
+	assert.Equal(t, []entry{{Text: "hello"}, {Text: "world"}}, values)
+
+	values, err = Load[entry](2, "chat.jsonl")
+	assert.NoError(t, err)
+	assert.Empty(t, values, "Expected games to have separate files")
+}
+
+// TestWriteRead tests that a written file is replaced and read back.
+func TestWriteRead(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	_, err := Read[entry](LocalGameDir(1), "game.json")
+	assert.Error(t, err)
+
+	assert.NoError(t, Write(LocalGameDir(1), "game.json", entry{Text: "hello"}))
+	assert.NoError(t, Write(LocalGameDir(1), "game.json", entry{Text: "world"}))
+
+	value, err := Read[entry](LocalGameDir(1), "game.json")
+	assert.NoError(t, err)
+	assert.Equal(t, entry{Text: "world"}, value)
+}
+
+// TestLocalGameIDs tests that the offline games are listed in order.
+func TestLocalGameIDs(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	ids, err := LocalGameIDs()
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	for _, id := range []int{10, 2, 1} {
+		assert.NoError(t, Write(LocalGameDir(id), "game.json", entry{}))
+	}
+
+	ids, err = LocalGameIDs()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 10}, ids)
+}
@@ -168,6 +168,10 @@ func (m AuthModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
+		case "alt+o", "alt+O":
+			// Offline games need no account
+			return m, SwitchModelCmd(NewLocalPlayModel(m.width, m.height))
+
 		case "alt+2":
 			// Switch to sign-up tab
 			if m.activeTab != SignUpTab {
@@ -213,6 +217,7 @@ func (m AuthModel) View() string {
 	ui := lipgloss.JoinVertical(lipgloss.Center,
 		getTabsRow([]string{"Sign In", "Sign Up"}, m.activeTab),
 		windowStyle.Width(getFormWidth(width)).Render(tabContent),
+		lipgloss.NewStyle().MarginTop(1).Render(fmt.Sprintf("%s %s", altCodeStyle.Render("Alt+O"), "Play offline")),
 	)
 
 	// Center logo and form in available space
@@ -0,0 +1,410 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
+)
+
+// Pieces offered by the promotion picker, in this order
+var promotionPieces = []chess.PieceType{chess.Queen, chess.Rook, chess.Bishop, chess.Knight}
+
+// boardModel draws the board with colored squares. When focused, a move is
+// chosen on it with a cursor: select a piece, then one of the squares it can
+// reach.
+type boardModel struct {
+	keys boardKeyMap
+
+	// Index of the theme in `boardThemes`
+	theme int
+
+	// Pieces are drawn with letters instead of the chess glyphs
+	ascii bool
+
+	// The cursor and the selection are shown only when focused
+	focused bool
+
+	board *chess.Board
+	moves []*chess.Move
+
+	// The board is seen from this side
+	orientation chess.Color
+
+	cursor   chess.Square
+	selected chess.Square
+
+	// Square where the mouse button was pressed, while dragging a piece
+	dragFrom chess.Square
+
+	// Moves of the promotion picker, empty when it is closed
+	promotions []*chess.Move
+	promotion  int
+
+	// Squares highlighted besides the cursor, as the premoves
+	marked map[chess.Square]bool
+
+	// Last move played and square of the king in check, if any
+	lastMove *chess.Move
+	check    chess.Square
+}
+
+func newBoardModel() boardModel {
+	return boardModel{
+		keys:        defaultBoardKeyMap,
+		theme:       getBoardTheme(),
+		ascii:       useASCIIPieces(),
+		board:       chess.NewGame().Position().Board(),
+		orientation: chess.White,
+		cursor:      chess.E2,
+		selected:    chess.NoSquare,
+		dragFrom:    chess.NoSquare,
+		check:       chess.NoSquare,
+	}
+}
+
+// Sets the position shown and the moves which can be chosen on it. The
+// cursor stays where it is, the selection is cleared.
+func (b boardModel) SetPosition(board *chess.Board, moves []*chess.Move, orientation chess.Color) boardModel {
+	if orientation != b.orientation && orientation != chess.NoColor {
+		b.cursor = flipSquare(b.cursor)
+		b.orientation = orientation
+	}
+
+	b.board = board
+	b.moves = moves
+	b.selected = chess.NoSquare
+	b.dragFrom = chess.NoSquare
+	b.promotions = nil
+
+	return b
+}
+
+// Sets the squares highlighted besides the cursor
+func (b boardModel) SetMarked(marked map[chess.Square]bool) boardModel {
+	b.marked = marked
+	return b
+}
+
+// Sets the last move played and the square of the king in check, or
+// `chess.NoSquare`
+func (b boardModel) SetHighlights(lastMove *chess.Move, check chess.Square) boardModel {
+	b.lastMove = lastMove
+	b.check = check
+	return b
+}
+
+// Shows or hides the cursor and the selection
+func (b boardModel) SetFocused(focused bool) boardModel {
+	b.focused = focused
+	return b
+}
+
+// Switches to the next theme
+func (b boardModel) NextTheme() boardModel {
+	b.theme = (b.theme + 1) % len(boardThemes)
+	return b
+}
+
+// Returns the name of the theme
+func (b boardModel) ThemeName() string {
+	return boardThemes[b.theme].name
+}
+
+// Returns the square seen on the other side of the board
+func flipSquare(sq chess.Square) chess.Square {
+	return chess.NewSquare(chess.H1.File()-sq.File(), chess.H8.Rank()-sq.Rank())
+}
+
+// Returns the moves of the piece on `from`
+func (b boardModel) movesFrom(from chess.Square) []*chess.Move {
+	var moves []*chess.Move
+	for _, move := range b.moves {
+		if move.S1() == from {
+			moves = append(moves, move)
+		}
+	}
+
+	return moves
+}
+
+// Returns the moves from the selected square to `to`: more than one for a
+// promotion
+func (b boardModel) movesTo(to chess.Square) []*chess.Move {
+	var moves []*chess.Move
+	for _, move := range b.movesFrom(b.selected) {
+		if move.S2() == to {
+			moves = append(moves, move)
+		}
+	}
+
+	return moves
+}
+
+// Moves the cursor by `files` and `ranks`, as seen by the player
+func (b boardModel) moveCursor(files, ranks int) boardModel {
+	if b.orientation == chess.Black {
+		files, ranks = -files, -ranks
+	}
+
+	file := min(max(int(b.cursor.File())+files, 0), 7)
+	rank := min(max(int(b.cursor.Rank())+ranks, 0), 7)
+	b.cursor = chess.NewSquare(chess.File(file), chess.Rank(rank))
+
+	return b
+}
+
+// Handles a key. Returns the move chosen, in UCI notation, or an empty string.
+func (b boardModel) Update(msg tea.KeyMsg) (boardModel, string) {
+	if len(b.promotions) > 0 {
+		return b.updatePromotion(msg)
+	}
+
+	switch {
+	case key.Matches(msg, b.keys.Up):
+		b = b.moveCursor(0, 1)
+	case key.Matches(msg, b.keys.Down):
+		b = b.moveCursor(0, -1)
+	case key.Matches(msg, b.keys.Left):
+		b = b.moveCursor(-1, 0)
+	case key.Matches(msg, b.keys.Right):
+		b = b.moveCursor(1, 0)
+	case key.Matches(msg, b.keys.Cancel):
+		b.selected = chess.NoSquare
+	case key.Matches(msg, b.keys.Select):
+		if b.selected != chess.NoSquare && len(b.movesTo(b.cursor)) > 0 {
+			return b.moveTo(b.cursor)
+		}
+
+		// Select another piece, or nothing if it can't move
+		b.selected = chess.NoSquare
+		if len(b.movesFrom(b.cursor)) > 0 {
+			b.selected = b.cursor
+		}
+	}
+
+	return b, ""
+}
+
+// Plays the selected piece to `to`, or opens the promotion picker. Returns the
+// move chosen, in UCI notation, or an empty string.
+func (b boardModel) moveTo(to chess.Square) (boardModel, string) {
+	moves := b.movesTo(to)
+	switch len(moves) {
+	case 0:
+		return b, ""
+	case 1:
+		b.selected = chess.NoSquare
+		return b, moves[0].String()
+	}
+
+	b.promotions = sortPromotions(moves)
+	b.promotion = 0
+
+	return b, ""
+}
+
+// Handles a press of the mouse button on `sq`: selects a piece, or plays the
+// selected one there. Returns the move chosen, in UCI notation, or an error if
+// the move is illegal.
+func (b boardModel) Press(sq chess.Square) (boardModel, string, error) {
+	b.cursor = sq
+	b.dragFrom = chess.NoSquare
+
+	// The piece of a promotion is picked, not played on the board
+	if len(b.promotions) > 0 {
+		return b, "", nil
+	}
+
+	if b.selected != chess.NoSquare && sq != b.selected && len(b.movesTo(sq)) > 0 {
+		b, move := b.moveTo(sq)
+		return b, move, nil
+	}
+
+	if len(b.movesFrom(sq)) > 0 {
+		b.selected = sq
+		b.dragFrom = sq
+		return b, "", nil
+	}
+
+	from := b.selected
+	b.selected = chess.NoSquare
+
+	if from != chess.NoSquare && sq != from {
+		return b, "", fmt.Errorf("illegal move %s → %s", from, sq)
+	}
+	if b.board.Piece(sq) != chess.NoPiece {
+		return b, "", fmt.Errorf("no legal moves from %s", sq)
+	}
+
+	return b, "", nil
+}
+
+// Handles a release of the mouse button on `sq`: drops the dragged piece there
+func (b boardModel) Release(sq chess.Square) (boardModel, string, error) {
+	from := b.dragFrom
+	b.dragFrom = chess.NoSquare
+
+	if from == chess.NoSquare || sq == from || b.selected != from {
+		return b, "", nil
+	}
+
+	b.cursor = sq
+	if len(b.movesTo(sq)) == 0 {
+		return b, "", fmt.Errorf("illegal move %s → %s", from, sq)
+	}
+
+	b, move := b.moveTo(sq)
+	return b, move, nil
+}
+
+// Picks the `i`-th piece of the promotion picker
+func (b boardModel) PickPromotion(i int) (boardModel, string) {
+	if i < 0 || i >= len(b.promotions) {
+		return b, ""
+	}
+
+	move := b.promotions[i]
+	b.promotions = nil
+	b.selected = chess.NoSquare
+
+	return b, move.String()
+}
+
+// Handles a key of the promotion picker
+func (b boardModel) updatePromotion(msg tea.KeyMsg) (boardModel, string) {
+	switch {
+	case key.Matches(msg, b.keys.Left), key.Matches(msg, b.keys.Up):
+		b.promotion = (b.promotion + len(b.promotions) - 1) % len(b.promotions)
+	case key.Matches(msg, b.keys.Right), key.Matches(msg, b.keys.Down):
+		b.promotion = (b.promotion + 1) % len(b.promotions)
+	case key.Matches(msg, b.keys.Cancel):
+		b.promotions = nil
+	case key.Matches(msg, b.keys.Select):
+		return b.PickPromotion(b.promotion)
+	}
+
+	return b, ""
+}
+
+// Returns the promotion moves sorted as `promotionPieces`
+func sortPromotions(moves []*chess.Move) []*chess.Move {
+	var sorted []*chess.Move
+	for _, piece := range promotionPieces {
+		for _, move := range moves {
+			if move.Promo() == piece {
+				sorted = append(sorted, move)
+			}
+		}
+	}
+
+	return sorted
+}
+
+// Returns the glyph, or the letter, of `piece`
+func (b boardModel) pieceString(piece chess.Piece) string {
+	if b.ascii {
+		return asciiPiece(piece)
+	}
+	return piece.String()
+}
+
+// Returns true if `sq` is a light square
+func isLightSquare(sq chess.Square) bool {
+	return (int(sq.File())+int(sq.Rank()))%2 == 1
+}
+
+// Renders a square of the board
+func (b boardModel) renderSquare(sq chess.Square, targets map[chess.Square]bool) string {
+	theme := boardThemes[b.theme]
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#000000")).Background(theme.dark)
+	if isLightSquare(sq) {
+		style = style.Background(theme.light)
+	}
+
+	cell := " "
+	piece := b.board.Piece(sq)
+	if piece != chess.NoPiece {
+		cell = b.pieceString(piece)
+	}
+
+	switch {
+	case b.focused && sq == b.cursor:
+		style = style.Background(theme.cursor)
+	case b.focused && sq == b.selected:
+		style = style.Background(theme.selected)
+	case targets[sq] && piece != chess.NoPiece:
+		style = style.Background(theme.target).Foreground(theme.light)
+	case targets[sq]:
+		cell = "•"
+		style = style.Foreground(theme.target)
+	case sq == b.check:
+		style = style.Background(theme.check)
+	case b.marked[sq]:
+		style = style.Background(theme.marked)
+	case b.lastMove != nil && (sq == b.lastMove.S1() || sq == b.lastMove.S2()):
+		style = style.Background(theme.lastMove)
+	}
+
+	return style.Render(" " + cell + " ")
+}
+
+// Renders the line of the promotion picker
+func (b boardModel) renderPromotion() string {
+	color := b.board.Piece(b.selected).Color()
+	cursorStyle := lipgloss.NewStyle().Background(boardThemes[b.theme].cursor).Foreground(lipgloss.Color("#000000"))
+
+	pieces := make([]string, len(b.promotions))
+	for i, move := range b.promotions {
+		piece := " " + b.pieceString(chess.NewPiece(move.Promo(), color)) + " "
+		if i == b.promotion {
+			piece = cursorStyle.Render(piece)
+		}
+		pieces[i] = piece
+	}
+
+	return promotionPrefix + strings.Join(pieces, "")
+}
+
+func (b boardModel) View() string {
+	targets := make(map[chess.Square]bool)
+	if b.focused {
+		for _, move := range b.movesFrom(b.selected) {
+			targets[move.S2()] = true
+		}
+	}
+
+	files := "abcdefgh"
+	ranks := []chess.Rank{chess.Rank8, chess.Rank7, chess.Rank6, chess.Rank5, chess.Rank4, chess.Rank3, chess.Rank2, chess.Rank1}
+	if b.orientation == chess.Black {
+		files = "hgfedcba"
+		for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+			ranks[i], ranks[j] = ranks[j], ranks[i]
+		}
+	}
+
+	var sb strings.Builder
+	for _, rank := range ranks {
+		sb.WriteString(rank.String() + " ")
+		for _, file := range files {
+			sq := chess.NewSquare(chess.File(file-'a'), rank)
+			sb.WriteString(b.renderSquare(sq, targets))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("  ")
+	for _, file := range files {
+		sb.WriteString(fmt.Sprintf(" %c ", file))
+	}
+
+	lines := []string{sb.String()}
+	if len(b.promotions) > 0 {
+		lines = append(lines, b.renderPromotion())
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Center, lines...)
+}
@@ -0,0 +1,54 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// boardKeyMap defines the key bindings for the board where moves are chosen.
+type boardKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Left   key.Binding
+	Right  key.Binding
+	Select key.Binding
+	Cancel key.Binding
+}
+
+var defaultBoardKeyMap = boardKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "Up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "Down"),
+	),
+	Left: key.NewBinding(
+		key.WithKeys("left", "h"),
+		key.WithHelp("←/h", "Left"),
+	),
+	Right: key.NewBinding(
+		key.WithKeys("right", "l"),
+		key.WithHelp("→/l", "Right"),
+	),
+	Select: key.NewBinding(
+		key.WithKeys("enter", " "),
+		key.WithHelp(" Enter", "Select piece / square"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("   Esc", "Cancel selection"),
+	),
+}
+
+func (b boardModel) renderNavigationButtons() string {
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		fmt.Sprintf("%s %s", altCodeStyle.Render("  ←↑↓→"), "Move the cursor (or hjkl)"),
+		fmt.Sprintf("%s %s", altCodeStyle.Render(b.keys.Select.Help().Key), b.keys.Select.Help().Desc),
+		fmt.Sprintf("%s %s", altCodeStyle.Render(b.keys.Cancel.Help().Key), b.keys.Cancel.Help().Desc),
+	)
+}
@@ -0,0 +1,84 @@
+package views
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/notnil/chess"
+)
+
+// Width of a square drawn by `boardModel`, in cells
+const squareWidth = 3
+
+// Prefix of the promotion picker drawn by `boardModel`
+const promotionPrefix = "Promote to "
+
+// Position of a board drawn by `boardModel` on the screen
+type boardLayout struct {
+	orientation chess.Color
+
+	// Cell of the top left square
+	top  int
+	left int
+
+	// Cell of the first piece of the promotion picker, -1 without picker
+	promotionTop  int
+	promotionLeft int
+}
+
+// Finds the board in the rendered `view`, from the line of its files. The
+// board is seen from the `orientation` side.
+func locateBoard(view string, orientation chess.Color) (boardLayout, bool) {
+	files := " a  b  c  d  e  f  g  h "
+	if orientation == chess.Black {
+		files = " h  g  f  e  d  c  b  a "
+	}
+
+	layout := boardLayout{orientation: orientation, promotionTop: -1, promotionLeft: -1}
+
+	lines := strings.Split(ansi.Strip(view), "\n")
+	found := false
+	for y, line := range lines {
+		if !found {
+			if i := strings.Index(line, files); i >= 0 && y >= 8 {
+				layout.top = y - 8
+				layout.left = ansi.StringWidth(line[:i])
+				found = true
+			}
+			continue
+		}
+
+		// The picker is right below the files
+		if i := strings.Index(line, promotionPrefix); i >= 0 {
+			layout.promotionTop = y
+			layout.promotionLeft = ansi.StringWidth(line[:i]) + len(promotionPrefix)
+		}
+		break
+	}
+
+	return layout, found
+}
+
+// Returns the square at the cell `x`, `y` of the screen
+func (l boardLayout) squareAt(x, y int) (chess.Square, bool) {
+	row, col := y-l.top, x-l.left
+	if row < 0 || row >= 8 || col < 0 || col >= 8*squareWidth {
+		return chess.NoSquare, false
+	}
+	col /= squareWidth
+
+	if l.orientation == chess.Black {
+		return chess.NewSquare(chess.File(7-col), chess.Rank(row)), true
+	}
+	return chess.NewSquare(chess.File(col), chess.Rank(7-row)), true
+}
+
+// Returns the index of the piece of the promotion picker at the cell `x`, `y`
+func (l boardLayout) promotionAt(x, y int) (int, bool) {
+	if l.promotionTop < 0 || y != l.promotionTop || x < l.promotionLeft {
+		return 0, false
+	}
+
+	i := (x - l.promotionLeft) / squareWidth
+	return i, i < len(promotionPieces)
+}
@@ -0,0 +1,124 @@
+package views
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the character drawn at the cell `x`, `y` of `view`
+func cellAt(view string, x, y int) string {
+	lines := strings.Split(ansi.Strip(view), "\n")
+	if y < 0 || y >= len(lines) {
+		return ""
+	}
+
+	column := 0
+	for _, r := range lines[y] {
+		if column == x {
+			return string(r)
+		}
+		column += ansi.StringWidth(string(r))
+	}
+
+	return ""
+}
+
+// TestLocateBoard tests that every square is found under the cell where it is
+// drawn, wherever the board is placed and from both sides.
+func TestLocateBoard(t *testing.T) {
+	tests := []struct {
+		name        string
+		width       int
+		orientation chess.Color
+	}{
+		{"narrow white", 30, chess.White},
+		{"narrow black", 30, chess.Black},
+		{"wide white", 80, chess.White},
+		{"wide black", 80, chess.Black},
+		{"offset white", 121, chess.White},
+		{"offset black", 121, chess.Black},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := newBoardModel().SetPosition(chess.NewGame().Position().Board(), nil, tt.orientation)
+			view := lipgloss.Place(tt.width, 14, lipgloss.Center, lipgloss.Center, board.View())
+
+			layout, ok := locateBoard(view, tt.orientation)
+			assert.True(t, ok)
+			assert.Equal(t, -1, layout.promotionTop)
+
+			// The rank is written at the left of each row, the file below
+			// the center of each column
+			for row := range 8 {
+				for col := range 8 {
+					x, y := layout.left+col*squareWidth+1, layout.top+row
+
+					sq, ok := layout.squareAt(x, y)
+					assert.True(t, ok)
+					assert.Equal(t, cellAt(view, layout.left-2, y), sq.Rank().String())
+					assert.Equal(t, cellAt(view, x, layout.top+8), sq.File().String())
+				}
+			}
+		})
+	}
+}
+
+// TestSquareAtOutside tests that the cells around the board are no square.
+func TestSquareAtOutside(t *testing.T) {
+	layout := boardLayout{orientation: chess.White, top: 2, left: 10}
+
+	tests := []struct {
+		x, y int
+		sq   chess.Square
+		ok   bool
+	}{
+		{10, 2, chess.A8, true},
+		{12, 2, chess.A8, true},
+		{13, 2, chess.B8, true},
+		{33, 9, chess.H1, true},
+		{9, 2, chess.NoSquare, false},
+		{34, 9, chess.NoSquare, false},
+		{10, 1, chess.NoSquare, false},
+		{10, 10, chess.NoSquare, false},
+	}
+
+	for _, tt := range tests {
+		sq, ok := layout.squareAt(tt.x, tt.y)
+		assert.Equal(t, tt.ok, ok, "cell %d, %d", tt.x, tt.y)
+		assert.Equal(t, tt.sq, sq, "cell %d, %d", tt.x, tt.y)
+	}
+
+	layout.orientation = chess.Black
+	sq, _ := layout.squareAt(10, 2)
+	assert.Equal(t, chess.H1, sq)
+}
+
+// TestLocatePromotion tests that the pieces of the promotion picker are found
+// right after its prefix.
+func TestLocatePromotion(t *testing.T) {
+	view := strings.Join([]string{
+		"8 " + strings.Repeat(" ", 24),
+		"7", "6", "5", "4", "3", "2", "1",
+		"   a  b  c  d  e  f  g  h ",
+		"   " + promotionPrefix + " ♕  ♖  ♗  ♘ ",
+	}, "\n")
+
+	layout, ok := locateBoard(view, chess.White)
+	assert.True(t, ok)
+	assert.Equal(t, 9, layout.promotionTop)
+
+	for i := range promotionPieces {
+		picked, ok := layout.promotionAt(layout.promotionLeft+i*squareWidth+1, 9)
+		assert.True(t, ok)
+		assert.Equal(t, i, picked)
+	}
+
+	_, ok = layout.promotionAt(layout.promotionLeft+len(promotionPieces)*squareWidth, 9)
+	assert.False(t, ok)
+}
@@ -0,0 +1,127 @@
+package views
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
+)
+
+// Colors of the board
+type boardTheme struct {
+	name string
+
+	light lipgloss.Color
+	dark  lipgloss.Color
+
+	lastMove lipgloss.Color
+	check    lipgloss.Color
+	cursor   lipgloss.Color
+	selected lipgloss.Color
+	target   lipgloss.Color
+	marked   lipgloss.Color
+}
+
+// Themes of the board. The first one is the default.
+var boardThemes = []boardTheme{
+	{
+		name:     "green",
+		light:    lipgloss.Color("#eeeed2"),
+		dark:     lipgloss.Color("#769656"),
+		lastMove: lipgloss.Color("#baca44"),
+		check:    lipgloss.Color("#e74c3c"),
+		cursor:   highlightColor,
+		selected: lipgloss.Color("#d35400"),
+		target:   lipgloss.Color("#2c3e50"),
+		marked:   lipgloss.Color("#f1c40f"),
+	},
+	{
+		name:     "brown",
+		light:    lipgloss.Color("#f0d9b5"),
+		dark:     lipgloss.Color("#b58863"),
+		lastMove: lipgloss.Color("#cdd26a"),
+		check:    lipgloss.Color("#e74c3c"),
+		cursor:   highlightColor,
+		selected: lipgloss.Color("#d35400"),
+		target:   lipgloss.Color("#3e2723"),
+		marked:   lipgloss.Color("#f1c40f"),
+	},
+	{
+		name:     "blue",
+		light:    lipgloss.Color("#dee3e6"),
+		dark:     lipgloss.Color("#8ca2ad"),
+		lastMove: lipgloss.Color("#a9c1d9"),
+		check:    lipgloss.Color("#e74c3c"),
+		cursor:   highlightColor,
+		selected: lipgloss.Color("#d35400"),
+		target:   lipgloss.Color("#1a237e"),
+		marked:   lipgloss.Color("#f1c40f"),
+	},
+	{
+		name:     "gray",
+		light:    lipgloss.Color("#bdbdbd"),
+		dark:     lipgloss.Color("#757575"),
+		lastMove: lipgloss.Color("#9e9d24"),
+		check:    lipgloss.Color("#e74c3c"),
+		cursor:   highlightColor,
+		selected: lipgloss.Color("#d35400"),
+		target:   lipgloss.Color("#000000"),
+		marked:   lipgloss.Color("#f1c40f"),
+	},
+}
+
+// Returns the index of the theme set with RAHANNA_BOARD_THEME, the default one
+// if it is not set or unknown
+func getBoardTheme() int {
+	name := os.Getenv("RAHANNA_BOARD_THEME")
+	for i, theme := range boardThemes {
+		if theme.name == name {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// Returns true if the terminal can't draw the chess glyphs: the Linux console
+// or a locale which is not UTF-8. RAHANNA_BOARD_PIECES forces `ascii` or
+// `unicode`.
+func useASCIIPieces() bool {
+	switch os.Getenv("RAHANNA_BOARD_PIECES") {
+	case "ascii":
+		return true
+	case "unicode":
+		return false
+	}
+
+	if os.Getenv("TERM") == "linux" {
+		return true
+	}
+
+	// The first locale variable set wins, as for the C library
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := strings.ToUpper(os.Getenv(name)); locale != "" {
+			return !strings.Contains(locale, "UTF-8") && !strings.Contains(locale, "UTF8")
+		}
+	}
+
+	return false
+}
+
+// Returns the letter of `piece` as in FEN: uppercase for white
+func asciiPiece(piece chess.Piece) string {
+	letter := map[chess.PieceType]string{
+		chess.King:   "k",
+		chess.Queen:  "q",
+		chess.Rook:   "r",
+		chess.Bishop: "b",
+		chess.Knight: "n",
+		chess.Pawn:   "p",
+	}[piece.Type()]
+
+	if piece.Color() == chess.White {
+		return strings.ToUpper(letter)
+	}
+	return letter
+}
@@ -0,0 +1,58 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/boozec/rahanna/pkg/bughouse"
+	"github.com/notnil/chess"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUseASCIIPieces tests that the glyphs are drawn only on terminals which
+// can show them, unless they are forced.
+func TestUseASCIIPieces(t *testing.T) {
+	cases := []struct {
+		pieces, term, lcAll, lang string
+		ascii                     bool
+	}{
+		{"", "xterm-256color", "", "en_US.UTF-8", false},
+		{"", "xterm-256color", "", "en_US.utf8", false},
+		{"", "xterm-256color", "", "C", true},
+		{"", "xterm-256color", "C", "en_US.UTF-8", true},
+		{"", "xterm-256color", "", "", false},
+		{"", "linux", "", "en_US.UTF-8", true},
+		{"ascii", "xterm-256color", "", "en_US.UTF-8", true},
+		{"unicode", "linux", "", "C", false},
+	}
+
+	for _, c := range cases {
+		t.Setenv("RAHANNA_BOARD_PIECES", c.pieces)
+		t.Setenv("TERM", c.term)
+		t.Setenv("LC_ALL", c.lcAll)
+		t.Setenv("LC_CTYPE", "")
+		t.Setenv("LANG", c.lang)
+
+		assert.Equal(t, c.ascii, useASCIIPieces(), "%+v", c)
+	}
+}
+
+// TestASCIIPiece tests that the pieces are written as in FEN.
+func TestASCIIPiece(t *testing.T) {
+	assert.Equal(t, "K", asciiPiece(chess.WhiteKing))
+	assert.Equal(t, "N", asciiPiece(chess.WhiteKnight))
+	assert.Equal(t, "q", asciiPiece(chess.BlackQueen))
+	assert.Equal(t, "p", asciiPiece(chess.BlackPawn))
+}
+
+// TestRenderPocket tests that the pockets are drawn with the pieces of the board.
+func TestRenderPocket(t *testing.T) {
+	pocket := bughouse.Pocket{chess.Knight: 2, chess.Queen: 1}
+
+	board := newBoardModel()
+	board.ascii = true
+	assert.Equal(t, "Q N×2", renderPocket(board, pocket, chess.White))
+	assert.Equal(t, "-", renderPocket(board, bughouse.Pocket{}, chess.Black))
+
+	board.ascii = false
+	assert.Equal(t, "♛ ♞×2", renderPocket(board, pocket, chess.Black))
+}
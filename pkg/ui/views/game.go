@@ -2,13 +2,17 @@ package views
 
 import (
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/bughouse"
+	"github.com/boozec/rahanna/pkg/chess960"
 	"github.com/boozec/rahanna/pkg/p2p"
 	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	"github.com/boozec/rahanna/pkg/ui/storage"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/notnil/chess"
@@ -30,46 +34,87 @@ type GameModel struct {
 	currentGameID      int
 	game               *database.Game
 	network            *multiplayer.GameNetwork
-	chessGame          *chess.Game
+	chessGame          *chess960.Game
 	incomingMoves      chan multiplayer.GameMove
 	turn               p2p.NetworkID
 	availableMovesList list.Model
+	pendingMove        string
+	queuedMoves        []ChessMoveMsg
+	premoves           []string
+	illegalMoves       map[p2p.NetworkID]int
+	ready              map[p2p.NetworkID]bool
+	entropy            *multiplayer.Entropy
+	seed               []byte
+	turnRecords        []multiplayer.TurnRecord
+	restoring          bool
+	restoreSnapshots   map[p2p.NetworkID]multiplayer.GameSnapshot
+	pausedSeats        []p2p.NetworkID
+	pausedAt           time.Time
+	forfeitGrace       time.Duration
+	chat               []multiplayer.ChatMessage
+	chatChannel        multiplayer.ChatChannel
+	chatting           bool
+	typing             bool
+	moveInput          textinput.Model
+	chatInput          textinput.Model
+	chatView           viewport.Model
+	negotiation        *multiplayer.Negotiation
+	consultation       *multiplayer.Consultation
+	notice             string
+	clock              *multiplayer.Clock
+	flagFell           bool
+	signer             *multiplayer.Signer
+	publicKeys         map[p2p.NetworkID][]byte
+	bughouse           *bughouse.Match
+	bots               []*multiplayer.VirtualPeer
+	board              boardModel
+	boardFocused       bool
+	reviewing          bool
+	reviewPly          int
 }
 
 // NewGameModel creates a new GameModel.
 func NewGameModel(width, height int, currentGameID int, network *multiplayer.GameNetwork, restore bool) GameModel {
-	listDelegate := list.NewDefaultDelegate()
-	listDelegate.ShowDescription = false
-	listDelegate.Styles.SelectedTitle = lipgloss.NewStyle().
-		Border(lipgloss.NormalBorder(), false, false, false, true).
-		BorderForeground(highlightColor).
-		Foreground(highlightColor).
-		Padding(0, 0, 0, 1)
-
-	moveList := list.New([]list.Item{}, listDelegate, width/4, height/2)
-	moveList.Styles.Title = lipgloss.NewStyle().
-		Background(highlightColor).
-		Foreground(lipgloss.Color("230")).
-		Padding(0, 1)
-	moveList.DisableQuitKeybindings()
-
-	return GameModel{
+	chat, err := storage.Load[multiplayer.ChatMessage](currentGameID, chatTranscriptFile)
+
+	signer, signerErr := multiplayer.NewSigner()
+	if err == nil {
+		err = signerErr
+	}
+
+	m := GameModel{
+		err:                err,
 		width:              width,
 		height:             height,
 		keys:               defaultGameKeyMap,
 		currentGameID:      currentGameID,
 		network:            network,
-		chessGame:          chess.NewGame(chess.UseNotation(chess.UCINotation{})),
+		chessGame:          chess960.NewStandardGame(),
 		incomingMoves:      make(chan multiplayer.GameMove),
-		availableMovesList: moveList,
+		availableMovesList: createMovesList(width, height),
 		restore:            restore,
+		illegalMoves:       make(map[p2p.NetworkID]int),
+		ready:              make(map[p2p.NetworkID]bool),
+		forfeitGrace:       getForfeitGrace(),
+		chat:               chat,
+		chatChannel:        multiplayer.AllChannel,
+		chatInput:          createChatInput(width),
+		moveInput:          createMoveInput(width),
+		chatView:           createChatView(width),
+		signer:             signer,
+		publicKeys:         make(map[p2p.NetworkID][]byte),
+		board:              newBoardModel(),
 	}
+	m.refreshChat()
+
+	return m
 }
 
 // Init initializes the GameModel.
 func (m GameModel) Init() tea.Cmd {
 	ClearScreen()
-	return tea.Batch(textinput.Blink, m.getGame(), m.getMoves(), m.updateMovesListCmd())
+	m.network.StartHeartbeat(heartbeatInterval)
+	return tea.Batch(textinput.Blink, tea.EnableMouseCellMotion, m.getGame(), m.getMoves(), m.updateMovesListCmd(), m.gameTickCmd())
 }
 
 // Update handles incoming messages and updates the GameModel.
@@ -78,6 +123,16 @@ func (m GameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, exit
 	}
 
+	// While chatting, keys are typed in the chat input
+	if msg, ok := msg.(tea.KeyMsg); ok && m.chatting {
+		return m.handleChatKeyMsg(msg)
+	}
+
+	// While typing a move, keys are typed in the move input
+	if msg, ok := msg.(tea.KeyMsg); ok && m.typing {
+		return m.handleMoveInputKeyMsg(msg)
+	}
+
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
 
@@ -90,19 +145,49 @@ func (m GameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		m, cmd = m.handleKeyMsg(msg)
 		cmds = append(cmds, cmd)
+	case tea.MouseMsg:
+		m, cmd = m.handleMouseMsg(msg)
+		cmds = append(cmds, cmd)
 	case ChessMoveMsg:
 		m, cmd = m.handleChessMoveMsg(msg)
 		cmds = append(cmds, cmd)
+	case RejectedMoveMsg:
+		m, cmd = m.handleRejectedMoveMsg(msg)
+		cmds = append(cmds, cmd)
+	case RollbackMsg:
+		m, cmd = m.handleRollbackMsg(msg)
+		cmds = append(cmds, cmd)
 	case SendRestoreMsg:
-		cmd = m.handleSendRestoreMsg(p2p.NetworkID(msg))
+		m, cmd = m.handleSendRestoreMsg(p2p.NetworkID(msg))
 		cmds = append(cmds, cmd)
-	case RestoreMoves:
-		cmd = m.handleRestoreMoves(msg)
+	case RestoreSnapshotMsg:
+		m, cmd = m.handleRestoreSnapshotMsg(msg)
+		cmds = append(cmds, cmd)
+	case RestoreTimeoutMsg:
+		m, cmd = m.handleRestoreTimeoutMsg()
 		cmds = append(cmds, cmd)
 	case database.Game:
 		m.userID, m.err = getUserID()
 		m, cmd = m.handleDatabaseGameMsg(msg)
 		cmds = append(cmds, cmd, m.updateMovesListCmd())
+	case PeersConnectedMsg:
+		m, cmd = m.handlePeersConnectedMsg()
+		cmds = append(cmds, cmd)
+	case AnnounceReadyMsg:
+		m, cmd = m.handleAnnounceReadyMsg()
+		cmds = append(cmds, cmd)
+	case PeerReadyMsg:
+		m, cmd = m.handlePeerReadyMsg(p2p.NetworkID(msg))
+		cmds = append(cmds, cmd)
+	case PeerCommitMsg:
+		m, cmd = m.handlePeerCommitMsg(msg)
+		cmds = append(cmds, cmd)
+	case PeerRevealMsg:
+		m, cmd = m.handlePeerRevealMsg(msg)
+		cmds = append(cmds, cmd)
+	case GameTickMsg:
+		m, cmd = m.handleGameTickMsg(msg)
+		cmds = append(cmds, cmd)
 	case SaveTurnMsg:
 		m, cmd = m.handleSaveTurnMsg(msg)
 		cmds = append(cmds, cmd)
@@ -116,40 +201,80 @@ func (m GameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 
+		m.closeBots()
 		m.err = m.network.Close()
+
+		if err := m.saveHistory(); err != nil {
+			m.err = err
+		}
 	case RestoreGameMsg:
-		m.network.SendAll([]byte(string(multiplayer.RestoreGameMessage)), []byte(m.network.Me()))
-		m.restore = false
+		m, cmd = m.handleRestoreGameMsg()
+		cmds = append(cmds, cmd)
+	case ChatMsg:
+		m, cmd = m.handleChatMsg(msg)
+		cmds = append(cmds, cmd)
+	case BughouseMoveMsg:
+		m, cmd = m.handleBughouseMoveMsg(msg)
+		cmds = append(cmds, cmd)
+	case ConsultVoteMsg:
+		m, cmd = m.handleConsultVoteMsg(msg)
+		cmds = append(cmds, cmd)
+	case PeerKeyMsg:
+		m, cmd = m.handlePeerKeyMsg(msg)
+		cmds = append(cmds, cmd)
+	case ProposalMsg:
+		m, cmd = m.handleProposalMsg(msg)
+		cmds = append(cmds, cmd)
+	case ProposalAnswerMsg:
+		m, cmd = m.handleProposalAnswerMsg(msg)
+		cmds = append(cmds, cmd)
+	case ProposalResultMsg:
+		m, cmd = m.handleProposalResultMsg(msg)
+		cmds = append(cmds, cmd)
 
 	case error:
 		m.err = msg
 	}
 
-	if m.isMyTurn() {
+	// On the board, keys move the cursor instead of the list
+	if msg, ok := msg.(tea.KeyMsg); ok && m.usesBoard() {
+		m, cmd = m.handleBoardKeyMsg(msg)
+		cmds = append(cmds, cmd)
+		return m, tea.Batch(cmds...)
+	}
+
+	// Enter applies the filter of the moves instead of choosing one
+	filtering := m.availableMovesList.FilterState() == list.Filtering
+
+	// Nobody moves while a takeback is negotiated
+	if m.isMyTurn() && !m.isPaused() && !m.isTakingBack() {
 		m.availableMovesList, cmd = m.availableMovesList.Update(msg)
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			if msg.Type == tea.KeyEnter {
+			if msg.Type == tea.KeyEnter && !filtering {
 				selectedItem := m.availableMovesList.SelectedItem()
-				if selectedItem != nil {
-					moveStr := strings.Replace(selectedItem.(item).Title(), " → ", "", 1)
-					moveStr = strings.Replace(moveStr, " ", "", 1)
-					err := m.chessGame.MoveStr(moveStr)
-					if err != nil {
-						m.err = err
-					} else {
-						m.network.SendAll([]byte(string(multiplayer.MoveGameMessage)), []byte(moveStr))
-						m.err = nil
-					}
-					cmds = append(cmds, m.getMoves(), m.updateMovesListCmd(), m.sendNewTurnCmd())
-
-					if m.chessGame.Outcome() != chess.NoOutcome {
-						cmds = append(cmds, m.endGame(m.chessGame.Outcome().String(), false))
-					}
+				if selectedItem != nil && selectedItem.(item).claim != chess.NoMethod {
+					var claimCmd tea.Cmd
+					m, claimCmd = m.claimDraw(selectedItem.(item).claim)
+					cmds = append(cmds, claimCmd)
+				} else if selectedItem != nil {
+					var moveCmd tea.Cmd
+					m, moveCmd = m.chooseMove(selectedItem.(item).move)
+					cmds = append(cmds, moveCmd)
 				}
 			}
 		}
 		cmds = append(cmds, cmd)
+	} else if m.canPremove() {
+		m.availableMovesList, cmd = m.availableMovesList.Update(msg)
+		cmds = append(cmds, cmd)
+
+		if msg, ok := msg.(tea.KeyMsg); ok && msg.Type == tea.KeyEnter && !filtering {
+			if selectedItem, ok := m.availableMovesList.SelectedItem().(item); ok {
+				m, cmd = m.queuePremove(selectedItem.move)
+				cmds = append(cmds, cmd)
+			}
+		}
 	}
 
 	return m, tea.Batch(cmds...)
@@ -178,9 +303,16 @@ func (m GameModel) View() string {
 	var availableMovesListView string
 
 	if m.game.Outcome == chess.NoOutcome.String() {
-		if m.isMyTurn() {
+		if m.isPaused() {
+			availableMovesListView = listStyle.Render(m.renderPause(listWidth, listHeight))
+		} else if m.isMyTurn() {
+			m.availableMovesList.SetSize(listWidth, listHeight-2)
+			availableMovesListView = listStyle.Render(m.availableMovesList.View())
+		} else if m.canPremove() {
 			m.availableMovesList.SetSize(listWidth, listHeight-2)
 			availableMovesListView = listStyle.Render(m.availableMovesList.View())
+		} else if m.isSpectator() {
+			availableMovesListView = listStyle.Render(lipgloss.Place(listWidth, listHeight, lipgloss.Center, lipgloss.Center, "Spectating"))
 		} else {
 			availableMovesListView = listStyle.Render(lipgloss.Place(listWidth, listHeight, lipgloss.Center, lipgloss.Center, "Wait your turn"))
 		}
@@ -209,37 +341,28 @@ func (m GameModel) View() string {
 				lipgloss.NewStyle().Background(highlightColor).Foreground(lipgloss.Color("230")).Padding(0, 1).MarginBottom(1).Render("Result"),
 				outcome,
 				m.game.Outcome,
+				altCodeStyle.Render(m.game.Method),
+				m.renderTurnsAudit(),
 			),
 		)
 	}
 
-	var movesListStr string
-
-	for i, move := range m.chessGame.Moves() {
-		s1 := move.S1().String()
-		s2 := move.S2().String()
-		var promo string
-
-		if move.Promo().String() != "" {
-			promo = " " + move.Promo().String()
-		}
-
-		if i%2 == 0 {
-			movesListStr += altCodeStyle.Render(fmt.Sprintf("[%d]", i/2)) + fmt.Sprintf(" %s → %s%s", s1, s2, promo)
-		} else {
-			movesListStr += fmt.Sprintf(", %s → %s%s\n", s1, s2, promo)
+	// The material is counted in the position shown
+	shown := m.chessGame
+	if m.reviewing {
+		if game, err := m.chessGame.Rollback(m.shownPly()); err == nil {
+			shown = game
 		}
 	}
 
-	// TODO: a faster solution withoout strings.Split and strings.Join
-	moves := strings.Split(movesListStr, "\n")
-	start := 0
-	if len(moves) > 10 {
-		start = len(moves) - 10 - 1
+	movesListStr := lipgloss.JoinVertical(lipgloss.Left,
+		renderNotation(m.chessGame.Notation(), m.shownPly(), m.reviewing),
+		renderMaterial(shown, m.board),
+	)
+	if m.isBughouse() {
+		movesListStr = lastLines(m.bughouseNotation())
 	}
 
-	movesListStr = strings.Join(moves[start:], "\n")
-
 	var errorStr string
 	if m.err != nil {
 		errorStr = m.err.Error()
@@ -273,6 +396,32 @@ func (m GameModel) View() string {
 		playersHeader = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#f1c40f")).
 			Render(fmt.Sprintf("♔ %s - %s vs ♚ %s - %s", players[0], players[2], players[1], players[3]))
+	case database.BughouseGameType:
+		players = append(players, m.game.Player3.Username, m.game.Player4.Username)
+
+		switch m.userID {
+		case m.game.Player3.ID:
+			players[2] += " (YOU)"
+		case m.game.Player4.ID:
+			players[3] += " (YOU)"
+		}
+
+		playersHeader = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#f1c40f")).
+			Render(fmt.Sprintf("%s - %s vs %s - %s (bughouse)", players[0], players[2], players[1], players[3]))
+	}
+
+	if m.isSpectator() {
+		playersHeader += altCodeStyle.Render("  (SPECTATING)")
+	}
+
+	if clocks := m.renderClocks(); clocks != "" {
+		playersHeader = lipgloss.JoinVertical(lipgloss.Center, playersHeader, clocks)
+	}
+
+	boardView := m.renderBoard()
+	if m.isBughouse() {
+		boardView = m.renderBughouseBoards()
 	}
 
 	content := lipgloss.JoinVertical(
@@ -281,9 +430,7 @@ func (m GameModel) View() string {
 		lipgloss.JoinHorizontal(
 			lipgloss.Top,
 			availableMovesListView,
-			boardStyle.Render(
-				m.chessGame.Position().Board().Draw(),
-			),
+			boardStyle.Render(boardView),
 			notationStyle.Render(
 				lipgloss.JoinVertical(
 					lipgloss.Left,
@@ -294,6 +441,12 @@ func (m GameModel) View() string {
 		),
 	)
 
+	if m.typing {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, m.renderMoveInput(formWidth))
+	}
+
+	content = lipgloss.JoinVertical(lipgloss.Left, content, m.renderChat(formWidth))
+
 	windowContent := m.buildWindowContent(content, formWidth)
 	buttons := m.renderNavigationButtons()
 
@@ -301,6 +454,8 @@ func (m GameModel) View() string {
 		lipgloss.Center,
 		getLogo(m.width),
 		windowContent,
+		m.renderNegotiation(),
+		m.renderConsultation(),
 		errorStyle.Width(formWidth/2).Render(errorStr),
 		lipgloss.NewStyle().MarginTop(2).Render(buttons),
 	)
@@ -3,11 +3,12 @@ package views
 import (
 	"encoding/json"
 	"fmt"
-	"math/rand"
+	"net/http"
 	"os"
-	"time"
+	"strings"
 
 	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/bughouse"
 	"github.com/boozec/rahanna/pkg/p2p"
 	"github.com/boozec/rahanna/pkg/ui/multiplayer"
 	tea "github.com/charmbracelet/bubbletea"
@@ -16,50 +17,21 @@ import (
 
 func (m GameModel) handleDatabaseGameMsg(msg database.Game) (GameModel, tea.Cmd) {
 	m.game = &msg
+	m = m.initClock()
 
-	var cmd tea.Cmd
-
-	peers := map[int]string{
-		1: m.game.IP1,
-		2: m.game.IP2,
-	}
-
-	if m.game.Type == database.PairGameType {
-		peers[3] = m.game.IP3
-		peers[4] = m.game.IP4
+	if _, err := multiplayer.NewStrategy(string(m.game.MoveChoose)); err != nil {
+		m.err = err
 	}
 
-	myPlayerNum := -1
-	switch m.network.Me() {
-	case m.playerPeer(1):
-		myPlayerNum = 1
-	case m.playerPeer(2):
-		myPlayerNum = 2
-	case m.playerPeer(3):
-		myPlayerNum = 3
-	case m.playerPeer(4):
-		myPlayerNum = 4
+	if m.isBughouse() && m.bughouse == nil {
+		m.bughouse = bughouse.NewMatch()
 	}
 
-	// Add all peers to every other peer
-	for playerNum, ip := range peers {
-		if playerNum != myPlayerNum && ip != "" {
-			m.network.AddPeer(m.playerPeer(playerNum), ip)
-		}
-	}
+	m = m.setStartPosition(m.game.StartFEN)
 
-	if myPlayerNum == 1 && m.turn == p2p.EmptyNetworkID {
-		// FIXME: use another way instead of sleep
-		time.Sleep(2 * time.Second)
-		if m.game.MoveChoose == database.RandomChooseType {
-			players := []int{1, 3}
-			m.turn = m.playerPeer(players[rand.Intn(len(players))])
-		} else {
-			m.turn = m.playerPeer(1)
-		}
-		m.network.SendAll([]byte(string(multiplayer.DefineTurnMessage)), []byte(string(m.turn)))
+	var cmd tea.Cmd
 
-	}
+	m.connectPeers()
 
 	if m.restore {
 		cmd = func() tea.Msg {
@@ -69,6 +41,17 @@ func (m GameModel) handleDatabaseGameMsg(msg database.Game) (GameModel, tea.Cmd)
 		cmd = func() tea.Msg {
 			return EndGameMsg{}
 		}
+	} else if m.turn == p2p.EmptyNetworkID {
+		if m.usesSharedSeed() && m.entropy == nil {
+			m.entropy, m.err = multiplayer.NewEntropy(m.network.Me())
+		}
+
+		if err := m.joinBots(); err != nil {
+			m.err = err
+		}
+
+		// The first turn is defined once every seat is ready
+		cmd = m.waitPeersCmd()
 	}
 
 	return m, cmd
@@ -108,7 +91,10 @@ type EndGameMsg struct {
 
 type RestoreGameMsg struct{}
 
-func (m *GameModel) endGame(outcome string, abandon bool) tea.Cmd {
+// Reports the outcome of the game and how it has been reached
+func (m *GameModel) endGame(outcome string, method string, abandon bool) tea.Cmd {
+	moves := strings.Join(m.snapshot().Moves, " ")
+
 	return func() tea.Msg {
 		var game database.Game
 
@@ -121,6 +107,8 @@ func (m *GameModel) endGame(outcome string, abandon bool) tea.Cmd {
 		// Prepare request payload
 		payload, err := json.Marshal(map[string]string{
 			"outcome": outcome,
+			"method":  method,
+			"moves":   moves,
 		})
 
 		// Send API request
@@ -131,6 +119,17 @@ func (m *GameModel) endGame(outcome string, abandon bool) tea.Cmd {
 		}
 		defer resp.Body.Close()
 
+		// Another player could have reported a different end first
+		if resp.StatusCode != http.StatusOK {
+			var response struct {
+				Error string `json:"error"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				return fmt.Errorf("HTTP error: %d, unable to decode body", resp.StatusCode)
+			}
+			return fmt.Errorf("can't end the game: %s", response.Error)
+		}
+
 		if err := json.NewDecoder(resp.Body).Decode(&game); err != nil {
 			return err
 		}
@@ -0,0 +1,134 @@
+package views
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Returns true if the move is chosen on the board instead of the list. The
+// bughouse boards have drops, which are only in the list.
+func (m GameModel) usesBoard() bool {
+	if !m.boardFocused || m.isBughouse() || m.reviewing {
+		return false
+	}
+
+	return (m.isMyTurn() && !m.isPaused() && !m.isTakingBack()) || m.canPremove()
+}
+
+// Renders the board of the game, with the queued premoves. The cursor is
+// shown when the moves are chosen on it.
+func (m GameModel) renderBoard() string {
+	if m.reviewing {
+		return m.renderHistoryBoard()
+	}
+
+	board := m.board
+	if !m.usesBoard() {
+		board = board.SetPosition(m.chessGame.Position().Board(), nil, m.seatColor(m.network.Me()))
+		board = board.SetMarked(m.premoveSquares())
+	}
+
+	view := board.SetHighlights(gameHighlights(m.chessGame)).SetFocused(m.usesBoard()).View()
+	if len(m.premoves) > 0 {
+		view = lipgloss.JoinVertical(lipgloss.Center, view, m.renderPremoves())
+	}
+
+	return view
+}
+
+// Switches between the board and the list to choose the moves
+func (m GameModel) toggleBoard() (GameModel, tea.Cmd) {
+	m.boardFocused = !m.boardFocused
+	return m, m.updateMovesListCmd()
+}
+
+// Shows the position after the premoves on the board, with their squares
+func (m GameModel) updatePremoveBoard() GameModel {
+	game, err := m.premoveGame()
+	if err != nil {
+		m.board = m.board.SetPosition(m.chessGame.Position().Board(), nil, m.seatColor(m.network.Me()))
+	} else {
+		m.board = m.board.SetPosition(game.Position().Board(), game.ValidMoves(), m.seatColor(m.network.Me()))
+	}
+	m.board = m.board.SetMarked(m.premoveSquares())
+
+	return m
+}
+
+// Plays, votes or drops the move chosen on our turn
+func (m GameModel) chooseMove(moveStr string) (GameModel, tea.Cmd) {
+	if m.isBughouse() {
+		return m.playBughouseMove(moveStr)
+	} else if m.isConsulting() {
+		return m.voteMove(moveStr)
+	}
+
+	return m.playMove(moveStr)
+}
+
+// Chooses the move on our turn, or queues it as a premove
+func (m GameModel) boardMove(moveStr string) (GameModel, tea.Cmd) {
+	if moveStr == "" {
+		return m, nil
+	}
+
+	if m.isMyTurn() {
+		return m.chooseMove(moveStr)
+	}
+
+	return m.queuePremove(moveStr)
+}
+
+func (m GameModel) handleBoardKeyMsg(msg tea.KeyMsg) (GameModel, tea.Cmd) {
+	var moveStr string
+	m.board, moveStr = m.board.Update(msg)
+
+	return m.boardMove(moveStr)
+}
+
+// Plays with the mouse: click a piece then its target, or drag it. A click on
+// the board moves the choice of the moves from the list to the board.
+func (m GameModel) handleMouseMsg(msg tea.MouseMsg) (GameModel, tea.Cmd) {
+	if msg.Action == tea.MouseActionMotion || (msg.Action == tea.MouseActionPress && msg.Button != tea.MouseButtonLeft) {
+		return m, nil
+	}
+
+	// The squares are found where the board would be drawn
+	view := m
+	view.boardFocused = true
+	if !view.usesBoard() {
+		return m, nil
+	}
+
+	layout, ok := locateBoard(view.View(), m.board.orientation)
+	if !ok {
+		return m, nil
+	}
+
+	var moveStr string
+	var err error
+
+	if i, ok := layout.promotionAt(msg.X, msg.Y); ok && msg.Action == tea.MouseActionPress {
+		m.board, moveStr = m.board.PickPromotion(i)
+		return m.boardMove(moveStr)
+	}
+
+	sq, ok := layout.squareAt(msg.X, msg.Y)
+	if !ok {
+		return m, nil
+	}
+	m.boardFocused = true
+
+	if msg.Action == tea.MouseActionPress {
+		m.board, moveStr, err = m.board.Press(sq)
+	} else {
+		m.board, moveStr, err = m.board.Release(sq)
+	}
+
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	return m.boardMove(moveStr)
+}
@@ -0,0 +1,40 @@
+package views
+
+import (
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+)
+
+// Makes the computer players join the game, now that every seat is known
+func (m GameModel) joinBots() error {
+	addresses := map[p2p.NetworkID]string{
+		m.playerPeer(1): m.game.IP1,
+		m.playerPeer(2): m.game.IP2,
+		m.playerPeer(3): m.game.IP3,
+		m.playerPeer(4): m.game.IP4,
+	}
+
+	for _, bot := range m.bots {
+		err := bot.Join(multiplayer.VirtualGame{
+			Name:       m.game.Name,
+			Seats:      len(m.seats()),
+			MoveChoose: string(m.game.MoveChoose),
+			StartFEN:   m.game.StartFEN,
+			Chess960:   m.game.Chess960,
+			Addresses:  addresses,
+			Ratings:    m.seatRatings(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stops the computer players of the game
+func (m GameModel) closeBots() {
+	for _, bot := range m.bots {
+		bot.Close()
+	}
+}
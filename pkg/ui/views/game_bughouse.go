@@ -0,0 +1,232 @@
+package views
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/bughouse"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
+)
+
+// Catch for `BughouseMoveGameMessage` message from multiplayer
+type BughouseMoveMsg struct {
+	Source p2p.NetworkID
+	Move   bughouse.Move
+}
+
+// Returns true if the game is played on two linked boards
+func (m GameModel) isBughouse() bool {
+	return m.game != nil && m.game.Type == database.BughouseGameType
+}
+
+// Returns the number of the seat of `peer`, 0 if it is not a player
+func (m GameModel) seatNumber(peer p2p.NetworkID) int {
+	for i, seat := range m.seats() {
+		if seat == peer {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// Returns true if the local player has to move on its board
+func (m GameModel) isMyBughouseTurn() bool {
+	seat := m.seatNumber(m.network.Me())
+	if m.bughouse == nil || seat == 0 || m.bughouse.Outcome() != chess.NoOutcome {
+		return false
+	}
+
+	board, color := bughouse.SeatBoard(seat)
+	return m.bughouse.Board(board).Turn() == color
+}
+
+// Returns the moves and drops of the local player as items of the moves list
+func (m GameModel) bughouseItems() []list.Item {
+	board, _ := bughouse.SeatBoard(m.seatNumber(m.network.Me()))
+
+	var items []list.Item
+	b := m.bughouse.Board(board)
+	for _, move := range b.ValidMoves() {
+		items = append(items, item{title: b.SAN(move), move: move})
+	}
+
+	return items
+}
+
+// Plays a local move on the board of the local player and sends it to everyone
+func (m GameModel) playBughouseMove(moveStr string) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd()}
+
+	board, _ := bughouse.SeatBoard(m.seatNumber(m.network.Me()))
+	played, err := m.bughouse.Move(board, moveStr)
+	if err != nil {
+		m.err = err
+		return m, tea.Batch(cmds...)
+	}
+
+	payload, err := json.Marshal(played)
+	if err != nil {
+		m.err = err
+		return m, tea.Batch(cmds...)
+	}
+
+	m.network.SendAll([]byte(string(multiplayer.BughouseMoveGameMessage)), payload)
+	m.err = nil
+
+	if m.bughouse.Outcome() != chess.NoOutcome {
+		cmds = append(cmds, m.endGame(m.bughouse.Outcome().String(), m.bughouse.Method(), false))
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+func (m GameModel) handleBughouseMoveMsg(msg BughouseMoveMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd()}
+
+	seat := m.seatNumber(msg.Source)
+	if m.bughouse == nil || seat == 0 {
+		m.err = fmt.Errorf("ignored move `%s` from %s: not a player", msg.Move.Move, msg.Source)
+		return m, tea.Batch(cmds...)
+	}
+
+	// The move can come before the previous ones of its board, so the turn is
+	// the one of its ply
+	board, color := bughouse.SeatBoard(seat)
+	if board != msg.Move.Board || msg.Move.Color() != color {
+		m.err = fmt.Errorf("ignored move `%s` from %s: not its turn", msg.Move.Move, msg.Source)
+		return m, tea.Batch(cmds...)
+	}
+
+	if err := m.bughouse.Receive(msg.Move); err != nil {
+		m.err = fmt.Errorf("illegal move `%s` from %s: %v", msg.Move.Move, msg.Source, err)
+		return m, tea.Batch(cmds...)
+	}
+
+	m.err = nil
+
+	if m.bughouse.Outcome() != chess.NoOutcome && !m.isSpectator() {
+		cmds = append(cmds, m.endGame(m.bughouse.Outcome().String(), m.bughouse.Method(), false))
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// Returns the pieces of a pocket, such as `♕ ♘×2`, drawn as on `board`
+func renderPocket(board boardModel, pocket bughouse.Pocket, color chess.Color) string {
+	var pieces []string
+	for _, pieceType := range []chess.PieceType{chess.Queen, chess.Rook, chess.Bishop, chess.Knight, chess.Pawn} {
+		count := pocket[pieceType]
+		if count == 0 {
+			continue
+		}
+
+		piece := board.pieceString(chess.NewPiece(pieceType, color))
+		if count > 1 {
+			piece += fmt.Sprintf("×%d", count)
+		}
+		pieces = append(pieces, piece)
+	}
+
+	if len(pieces) == 0 {
+		return "-"
+	}
+
+	return strings.Join(pieces, " ")
+}
+
+// Returns the side `board` is seen from: the color of the local player on its
+// board, the color of the partner on the other one. Spectators see White.
+func (m GameModel) bughouseOrientation(board int) chess.Color {
+	seat := m.seatNumber(m.network.Me())
+	if seat == 0 {
+		return chess.White
+	}
+
+	myBoard, color := bughouse.SeatBoard(seat)
+	if board == myBoard {
+		return color
+	}
+	return color.Other()
+}
+
+// Renders both boards with the pockets of their players
+func (m GameModel) renderBughouseBoards() string {
+	if m.bughouse == nil {
+		return ""
+	}
+
+	render := func(board int, name string) string {
+		b := m.bughouse.Board(board)
+		view := m.board.
+			SetFocused(false).
+			SetMarked(nil).
+			SetPosition(b.Position().Board(), nil, m.bughouseOrientation(board)).
+			SetHighlights(nil, kingInCheck(b.Position()))
+
+		// The players of each board, by color
+		players := map[chess.Color]p2p.NetworkID{chess.White: m.playerPeer(1), chess.Black: m.playerPeer(2)}
+		if board == bughouse.BoardB {
+			players = map[chess.Color]p2p.NetworkID{chess.White: m.playerPeer(4), chess.Black: m.playerPeer(3)}
+		}
+
+		player := func(color chess.Color) string {
+			name := m.peerName(players[color])
+			if b.Turn() == color {
+				name = "▸ " + name
+			}
+			return fmt.Sprintf("%s %s %s", view.pieceString(chess.NewPiece(chess.King, color)), name, renderPocket(view, b.Pocket(color), color))
+		}
+
+		// The side the board is seen from is at the bottom
+		bottom := view.orientation
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			altCodeStyle.Render("Board "+name),
+			player(bottom.Other()),
+			view.View(),
+			player(bottom),
+		)
+	}
+
+	return lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		render(bughouse.BoardA, "A"),
+		"   ",
+		render(bughouse.BoardB, "B"),
+	)
+}
+
+// Returns the moves of each board, one per line
+func (m GameModel) bughouseNotation() string {
+	if m.bughouse == nil {
+		return ""
+	}
+
+	notation := map[int][]string{
+		bughouse.BoardA: m.bughouse.Board(bughouse.BoardA).Notation(),
+		bughouse.BoardB: m.bughouse.Board(bughouse.BoardB).Notation(),
+	}
+
+	var lines []string
+	for _, move := range m.bughouse.History() {
+		board := "A"
+		if move.Board == bughouse.BoardB {
+			board = "B"
+		}
+		san := move.Move
+		if move.Ply < len(notation[move.Board]) {
+			san = notation[move.Board][move.Ply]
+		}
+		lines = append(lines, altCodeStyle.Render(fmt.Sprintf("[%d]", move.Ply))+fmt.Sprintf(" %s: %s", board, san))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,229 @@
+package views
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	"github.com/boozec/rahanna/pkg/ui/storage"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// File of the chat transcript in the local directory of the game
+	chatTranscriptFile = "chat.jsonl"
+
+	// Number of chat lines visible in the chat pane
+	chatHeight = 5
+)
+
+// Catch for `ChatGameMessage` message from multiplayer
+type ChatMsg multiplayer.ChatMessage
+
+func createChatInput(width int) textinput.Model {
+	chatInput := textinput.New()
+	chatInput.Prompt = "> "
+	chatInput.TextStyle = inputStyle
+	chatInput.Placeholder = "Say something"
+	chatInput.CharLimit = multiplayer.MaxChatLength
+	chatInput.Width = getFormWidth(width) - 6
+
+	return chatInput
+}
+
+func createChatView(width int) viewport.Model {
+	chatView := viewport.New(getFormWidth(width)-4, chatHeight)
+
+	// Only keys which can't be typed in the chat input scroll the chat
+	chatView.KeyMap = viewport.KeyMap{
+		PageDown: key.NewBinding(key.WithKeys("pgdown")),
+		PageUp:   key.NewBinding(key.WithKeys("pgup")),
+		Down:     key.NewBinding(key.WithKeys("down")),
+		Up:       key.NewBinding(key.WithKeys("up")),
+	}
+
+	return chatView
+}
+
+// Returns the other players of the team of the local peer
+func (m GameModel) teammates() []p2p.NetworkID {
+	if m.game == nil || m.isSpectator() {
+		return nil
+	}
+
+	var teammates []p2p.NetworkID
+	color := m.seatColor(m.network.Me())
+	for _, seat := range m.seats() {
+		if seat != m.network.Me() && m.seatColor(seat) == color {
+			teammates = append(teammates, seat)
+		}
+	}
+
+	return teammates
+}
+
+// Returns the username of the player or spectator behind `peer`
+func (m GameModel) peerName(peer p2p.NetworkID) string {
+	if m.game == nil {
+		return string(peer)
+	}
+
+	players := []*database.User{&m.game.Player1, m.game.Player2, m.game.Player3, m.game.Player4}
+	for i, player := range players {
+		if player != nil && peer == m.playerPeer(i+1) {
+			return player.Username
+		}
+	}
+
+	for _, spectator := range m.game.Spectators {
+		if peer == m.spectatorPeer(spectator) {
+			return spectator.User.Username
+		}
+	}
+
+	return string(peer)
+}
+
+func (m GameModel) handleChatKeyMsg(msg tea.KeyMsg) (GameModel, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.ChatClose):
+		m.chatting = false
+		m.chatInput.Blur()
+		return m, nil
+	case key.Matches(msg, m.keys.ChatChannel):
+		if m.chatChannel == multiplayer.AllChannel && len(m.teammates()) > 0 {
+			m.chatChannel = multiplayer.TeamChannel
+		} else {
+			m.chatChannel = multiplayer.AllChannel
+		}
+		return m, nil
+	case key.Matches(msg, m.keys.ChatSend):
+		return m.sendChat(), nil
+	}
+
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+
+	m.chatView, cmd = m.chatView.Update(msg)
+	cmds = append(cmds, cmd)
+
+	m.chatInput, cmd = m.chatInput.Update(msg)
+	cmds = append(cmds, cmd)
+
+	return m, tea.Batch(cmds...)
+}
+
+// Sends the text of the chat input to the selected channel
+func (m GameModel) sendChat() GameModel {
+	text := strings.TrimSpace(m.chatInput.Value())
+	if text == "" {
+		return m
+	}
+
+	message := multiplayer.ChatMessage{
+		Channel:   m.chatChannel,
+		Author:    m.network.Me(),
+		Text:      text,
+		Timestamp: time.Now(),
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	if message.Channel == multiplayer.TeamChannel {
+		for _, teammate := range m.teammates() {
+			m.err = m.network.Send(teammate, []byte(string(multiplayer.ChatGameMessage)), payload)
+		}
+	} else {
+		m.err = m.network.SendAll([]byte(string(multiplayer.ChatGameMessage)), payload)
+	}
+
+	m.chatInput.Reset()
+
+	return m.appendChat(message)
+}
+
+// For `ChatGameMessage` from multiplayer it adds the message to the chat.
+// Team messages are accepted only from a teammate.
+func (m GameModel) handleChatMsg(msg ChatMsg) (GameModel, tea.Cmd) {
+	message := multiplayer.ChatMessage(msg)
+
+	if message.Channel == multiplayer.TeamChannel && !slices.Contains(m.teammates(), message.Author) {
+		return m, m.getMoves()
+	}
+
+	message.Text = strings.TrimSpace(message.Text)
+	if runes := []rune(message.Text); len(runes) > multiplayer.MaxChatLength {
+		message.Text = string(runes[:multiplayer.MaxChatLength])
+	}
+
+	return m.appendChat(message), m.getMoves()
+}
+
+// Adds a message to the chat and to the local transcript of the game
+func (m GameModel) appendChat(message multiplayer.ChatMessage) GameModel {
+	m.chat = append(m.chat, message)
+
+	if err := storage.Append(m.currentGameID, chatTranscriptFile, message); err != nil {
+		m.err = err
+	}
+
+	m.refreshChat()
+
+	return m
+}
+
+// Renders the chat messages in the chat pane and scrolls to the last one
+func (m *GameModel) refreshChat() {
+	lineStyle := lipgloss.NewStyle().Width(m.chatView.Width)
+
+	var lines []string
+	for _, message := range m.chat {
+		var channel string
+		if message.Channel == multiplayer.TeamChannel {
+			channel = " (team)"
+		}
+
+		lines = append(lines, lineStyle.Render(fmt.Sprintf("%s %s%s: %s",
+			altCodeStyle.Render(message.Timestamp.Format("15:04")),
+			m.peerName(message.Author),
+			channel,
+			message.Text,
+		)))
+	}
+
+	m.chatView.SetContent(strings.Join(lines, "\n"))
+	m.chatView.GotoBottom()
+}
+
+func (m GameModel) renderChat(width int) string {
+	title := "Chat"
+	if m.chatChannel == multiplayer.TeamChannel {
+		title = "Team chat"
+	}
+
+	content := []string{
+		lipgloss.NewStyle().Background(highlightColor).Foreground(lipgloss.Color("230")).Padding(0, 1).Render(title),
+		m.chatView.View(),
+	}
+
+	if m.chatting {
+		content = append(content, m.chatInput.View())
+	}
+
+	return lipgloss.NewStyle().Width(width).Padding(0, 1).Render(
+		lipgloss.JoinVertical(lipgloss.Left, content...),
+	)
+}
@@ -0,0 +1,75 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/notnil/chess"
+)
+
+// Titles of the draws a player can claim from the moves list
+var claimTitles = map[chess.Method]string{
+	chess.ThreefoldRepetition: "Claim threefold repetition",
+	chess.FiftyMoveRule:       "Claim fifty-move rule",
+}
+
+// Returns the items of the moves list to claim a draw in `game`
+func claimItems(game *chess960.Game) []list.Item {
+	var items []list.Item
+	for _, method := range multiplayer.ClaimableDraws(game) {
+		items = append(items, item{title: claimTitles[method], claim: method})
+	}
+
+	return items
+}
+
+// Returns the draws which can be claimed in the current position
+func (m GameModel) claimableDraws() []chess.Method {
+	return multiplayer.ClaimableDraws(m.chessGame)
+}
+
+// Returns the items of the moves list to claim a draw
+func (m GameModel) claimItems() []list.Item {
+	return claimItems(m.chessGame)
+}
+
+// Returns an error if `method` is not a draw which can be claimed now
+func (m GameModel) validateClaim(method string) error {
+	return multiplayer.ValidateClaim(m.chessGame, method)
+}
+
+// Asks every other player to validate the claim of a draw
+func (m GameModel) claimDraw(method chess.Method) (GameModel, tea.Cmd) {
+	if err := m.validateClaim(method.String()); err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	return m.proposeMethod(multiplayer.ClaimProposal, method.String())
+}
+
+// Ends the game with the draw validated by everyone. The claimant reports it.
+func (m GameModel) applyClaim(proposal multiplayer.Proposal) (GameModel, tea.Cmd) {
+	for _, method := range m.claimableDraws() {
+		if method.String() != proposal.Method {
+			continue
+		}
+
+		if err := m.chessGame.Draw(method); err != nil {
+			m.err = err
+			return m, nil
+		}
+
+		if proposal.Proposer == m.network.Me() {
+			return m, m.endGame(chess.Draw.String(), proposal.Method, true)
+		}
+
+		return m, nil
+	}
+
+	m.err = fmt.Errorf("draw by %s can't be claimed in this position", proposal.Method)
+	return m, nil
+}
@@ -0,0 +1,197 @@
+package views
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
+)
+
+// Method of the games ended because a color ran out of time
+const timeoutMethod = "Timeout"
+
+// Catch for `PublicKeyGameMessage` message from multiplayer
+type PeerKeyMsg struct {
+	Source p2p.NetworkID
+	Key    []byte
+}
+
+// Creates the clock of a timed game
+func (m GameModel) initClock() GameModel {
+	if m.clock != nil || m.game == nil || !m.game.Clock.IsTimed() {
+		return m
+	}
+
+	tc := m.game.Clock
+	m.clock = multiplayer.NewClock(
+		time.Duration(tc.Base)*time.Second,
+		time.Duration(tc.Increment)*time.Second,
+		time.Duration(tc.Delay)*time.Second,
+	)
+
+	return m
+}
+
+// Sends the key used to sign the local moves to everyone
+func (m GameModel) sendPublicKey() {
+	if m.signer == nil {
+		return
+	}
+	m.network.SendAll([]byte(string(multiplayer.PublicKeyGameMessage)), m.signer.PublicKey())
+}
+
+// Stores the signing key of the seat `peer`, which can't change it during the
+// game. Returns true if the key was not known.
+func (m GameModel) storePublicKey(peer p2p.NetworkID, key []byte) (GameModel, bool) {
+	if !slices.Contains(m.seats(), peer) {
+		return m, false
+	}
+
+	if known, ok := m.publicKeys[peer]; ok {
+		if !bytes.Equal(known, key) {
+			m.err = fmt.Errorf("%s changed its signing key", peer)
+		}
+		return m, false
+	}
+
+	m.publicKeys[peer] = key
+
+	return m, true
+}
+
+func (m GameModel) handlePeerKeyMsg(msg PeerKeyMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves()}
+
+	m, stored := m.storePublicKey(msg.Source, msg.Key)
+	if !stored {
+		return m, tea.Batch(cmds...)
+	}
+
+	// The peer could have lost our key
+	if m.signer != nil {
+		m.network.Send(msg.Source, []byte(string(multiplayer.PublicKeyGameMessage)), m.signer.PublicKey())
+	}
+
+	return m.defineFirstTurn(), tea.Batch(cmds...)
+}
+
+// Returns the payload of the local move played at the `ply`-th move
+func (m GameModel) signMove(move string, ply int, at time.Time) ([]byte, error) {
+	signed := multiplayer.SignedMove{Move: move, Ply: ply, SentAt: at}
+	if m.signer != nil {
+		signed = m.signer.SignMove(move, ply, at)
+	}
+
+	return json.Marshal(signed)
+}
+
+// Checks the signature of a received move with the key its sender sent before
+// the first turn. A move without a known key is refused.
+func (m GameModel) verifyMove(msg ChessMoveMsg) error {
+	key, ok := m.publicKeys[msg.Source]
+	if !ok {
+		return fmt.Errorf("no signing key from %s", msg.Source)
+	}
+
+	return msg.Signed.Verify(key)
+}
+
+// Returns true if the signing keys of all the other seats are known
+func (m GameModel) allKeysKnown() bool {
+	for _, seat := range m.seats() {
+		if _, ok := m.publicKeys[seat]; !ok && seat != m.network.Me() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Rolls the clock back with the last `n` moves. The clock is left as it is if
+// it doesn't know them, as after a restore.
+func (m GameModel) undoClock(n int) {
+	if m.clock != nil {
+		m.clock.Undo(n, time.Now())
+	}
+}
+
+// Returns the local time when a received move has been played. Timestamps
+// which can't be verified are not trusted.
+func (m GameModel) moveTime(msg ChessMoveMsg) time.Time {
+	if _, ok := m.publicKeys[msg.Source]; !ok {
+		return msg.ReceivedAt
+	}
+
+	return multiplayer.CompensatedTime(
+		msg.Signed.SentAt,
+		msg.ReceivedAt,
+		m.network.ClockOffset(msg.Source),
+		m.network.RTT(msg.Source),
+	)
+}
+
+// Stops the clock of the team of `peer`, which moved at the time `at`
+func (m GameModel) punchClock(peer p2p.NetworkID, at time.Time) {
+	if m.clock != nil {
+		m.clock.Punch(m.seatColor(peer), at)
+	}
+}
+
+// Ends the game when a color runs out of time: its opponent wins, unless it
+// can't checkmate anymore. Nobody runs out of time while the game is paused.
+func (m GameModel) checkFlag(now time.Time) (GameModel, tea.Cmd) {
+	if m.clock == nil || m.flagFell || m.isPaused() {
+		return m, nil
+	}
+
+	color := m.clock.Flagged(now)
+	if color == chess.NoColor {
+		return m, nil
+	}
+
+	m.clock.Stop(now)
+	m.flagFell = true
+	m.notice = fmt.Sprintf("%s ran out of time", color.Name())
+
+	if !m.isReporter(m.network.Me()) {
+		return m, nil
+	}
+
+	outcome := chess.WhiteWon
+	if color == chess.White {
+		outcome = chess.BlackWon
+	}
+
+	if !multiplayer.HasMatingMaterial(m.chessGame.Position().Board(), color.Other()) {
+		outcome = chess.Draw
+	}
+
+	// Every peer sees the flag fall on its own clock, nobody abandoned
+	return m, m.endGame(outcome.String(), timeoutMethod, false)
+}
+
+// Returns the clocks of both colors, highlighting the running one
+func (m GameModel) renderClocks() string {
+	if m.clock == nil {
+		return ""
+	}
+
+	now := time.Now()
+	render := func(color chess.Color, symbol string) string {
+		left := m.clock.Remaining(color, now)
+		s := fmt.Sprintf("%s %02d:%02d", symbol, int(left.Minutes()), int(left.Seconds())%60)
+		if m.clock.Running() == color {
+			return lipgloss.NewStyle().Bold(true).Foreground(highlightColor).Render(s)
+		}
+		return s
+	}
+
+	return lipgloss.JoinHorizontal(lipgloss.Left, render(chess.White, "♔"), "   ", render(chess.Black, "♚"))
+}
@@ -0,0 +1,139 @@
+package views
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Time a team has to agree on a move before its votes are counted
+const consultationTimeout = 30 * time.Second
+
+// Catch for `ConsultGameMessage` message from multiplayer
+type ConsultVoteMsg struct {
+	Source p2p.NetworkID
+	Vote   multiplayer.ConsultVote
+}
+
+// Returns true if the teammates choose the moves together
+func (m GameModel) isConsulting() bool {
+	return m.game != nil && m.turnStrategy().TeamVotes()
+}
+
+// Starts collecting the votes for the current move, if not started yet
+func (m GameModel) startConsultation() GameModel {
+	ply := len(m.chessGame.Moves())
+	if !m.isConsulting() || m.consultation != nil && m.consultation.Ply == ply {
+		return m
+	}
+
+	m.consultation = multiplayer.NewConsultation(ply, time.Now().Add(consultationTimeout))
+	return m
+}
+
+// Returns true if `move` can be played in the current position
+func (m GameModel) isValidMove(move string) bool {
+	for _, valid := range m.chessGame.ValidMoves() {
+		if valid.String() == move {
+			return true
+		}
+	}
+	return false
+}
+
+// Votes a move and shares the vote with the teammates
+func (m GameModel) voteMove(move string) (GameModel, tea.Cmd) {
+	m = m.startConsultation()
+
+	payload, err := json.Marshal(multiplayer.ConsultVote{Ply: m.consultation.Ply, Move: move})
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	for _, teammate := range m.teammates() {
+		m.err = m.network.Send(teammate, []byte(string(multiplayer.ConsultGameMessage)), payload)
+	}
+
+	m.consultation.Vote(m.network.Me(), move)
+
+	return m.resolveConsultation(time.Now())
+}
+
+// For `ConsultGameMessage` from multiplayer it saves the vote of a teammate
+func (m GameModel) handleConsultVoteMsg(msg ConsultVoteMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves()}
+
+	if !m.isConsulting() || !m.isMyTurn() || !slices.Contains(m.teammates(), msg.Source) {
+		return m, tea.Batch(cmds...)
+	}
+
+	m = m.startConsultation()
+
+	// A vote for a previous move
+	if msg.Vote.Ply != m.consultation.Ply {
+		return m, tea.Batch(cmds...)
+	}
+
+	if !m.isValidMove(msg.Vote.Move) {
+		m.err = fmt.Errorf("ignored illegal vote `%s` from %s", msg.Vote.Move, msg.Source)
+		return m, tea.Batch(cmds...)
+	}
+
+	m.consultation.Vote(msg.Source, msg.Vote.Move)
+
+	var cmd tea.Cmd
+	m, cmd = m.resolveConsultation(time.Now())
+	cmds = append(cmds, cmd)
+
+	return m, tea.Batch(cmds...)
+}
+
+// The captain plays the move chosen by the team
+func (m GameModel) resolveConsultation(now time.Time) (GameModel, tea.Cmd) {
+	if !m.isConsulting() || m.consultation == nil || m.network.Me() != m.turn || m.isTakingBack() {
+		return m, nil
+	}
+
+	if m.consultation.Ply != len(m.chessGame.Moves()) {
+		return m, nil
+	}
+
+	members := append([]p2p.NetworkID{m.network.Me()}, m.teammates()...)
+	move := m.consultation.Resolve(members, m.network.Me(), now)
+	if move == "" {
+		return m, nil
+	}
+
+	return m.playMove(move)
+}
+
+// Returns the line with the votes of the team
+func (m GameModel) renderConsultation() string {
+	if !m.isConsulting() || !m.isMyTurn() || m.consultation == nil || m.consultation.Ply != len(m.chessGame.Moves()) {
+		return ""
+	}
+
+	var votes []string
+	for _, proposal := range m.consultation.Proposals() {
+		var names []string
+		for _, voter := range proposal.Voters {
+			names = append(names, m.peerName(voter))
+		}
+		votes = append(votes, fmt.Sprintf("%s (%s)", proposal.Move, strings.Join(names, ", ")))
+	}
+
+	if len(votes) == 0 {
+		votes = append(votes, "no votes yet")
+	}
+
+	remaining := max(time.Until(m.consultation.Deadline()), 0).Truncate(time.Second)
+
+	return altCodeStyle.Render(fmt.Sprintf("Team votes: %s · %s", strings.Join(votes, ", "), remaining))
+}
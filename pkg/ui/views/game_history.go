@@ -0,0 +1,70 @@
+package views
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
+)
+
+// Returns the number of moves played before the position shown: an earlier
+// one while reviewing the history, the live one otherwise
+func (m GameModel) shownPly() int {
+	if m.reviewing {
+		return min(m.reviewPly, len(m.chessGame.Moves()))
+	}
+	return len(m.chessGame.Moves())
+}
+
+// Shows the position before the one shown
+func (m GameModel) historyBack() (GameModel, tea.Cmd) {
+	if ply := m.shownPly(); ply > 0 && !m.isBughouse() {
+		m.reviewing = true
+		m.reviewPly = ply - 1
+	}
+
+	return m, nil
+}
+
+// Shows the position after the one shown, and the live game after the last
+// one
+func (m GameModel) historyForward() (GameModel, tea.Cmd) {
+	if !m.reviewing {
+		return m, nil
+	}
+
+	m.reviewPly++
+	if m.reviewPly >= len(m.chessGame.Moves()) {
+		return m.historyLive()
+	}
+
+	return m, nil
+}
+
+// Goes back to the live game
+func (m GameModel) historyLive() (GameModel, tea.Cmd) {
+	m.reviewing = false
+	m.reviewPly = 0
+
+	return m, m.updateMovesListCmd()
+}
+
+// Renders the position reviewed, with the move which led to it. The live game
+// goes on meanwhile.
+func (m GameModel) renderHistoryBoard() string {
+	ply := m.shownPly()
+	position := m.chessGame.Positions()[ply]
+
+	var lastMove *chess.Move
+	if ply > 0 {
+		lastMove = m.chessGame.Moves()[ply-1]
+	}
+
+	board := m.board.SetPosition(position.Board(), nil, m.seatColor(m.network.Me()))
+	view := board.SetMarked(nil).SetHighlights(lastMove, kingInCheck(position)).SetFocused(false).View()
+
+	return lipgloss.JoinVertical(lipgloss.Center, view,
+		altCodeStyle.Render(fmt.Sprintf("Move %d of %d, End to go live", ply, len(m.chessGame.Moves()))),
+	)
+}
@@ -0,0 +1,150 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Completions shown below the move input
+const maxCompletions = 8
+
+func createMoveInput(width int) textinput.Model {
+	moveInput := textinput.New()
+	moveInput.Prompt = "Move: "
+	moveInput.TextStyle = inputStyle
+	moveInput.Placeholder = "Nf3, exd5, e2e4, O-O"
+	moveInput.CharLimit = 10
+	moveInput.Width = getFormWidth(width) - 10
+
+	return moveInput
+}
+
+// Returns the game where the typed move is played: the current one on our
+// turn, the one after the premoves otherwise
+func (m GameModel) inputGame() (*chess960.Game, error) {
+	switch {
+	case m.isBughouse():
+		return nil, fmt.Errorf("moves can't be typed in bughouse")
+	case m.isMyTurn() && !m.isPaused() && !m.isTakingBack():
+		return m.chessGame, nil
+	case m.canPremove():
+		return m.premoveGame()
+	}
+
+	return nil, fmt.Errorf("wait your turn")
+}
+
+// Returns the legal moves, in SAN, which start as the typed text, in SAN or
+// in UCI
+func completeMove(game *chess960.Game, text string) []string {
+	var completions []string
+	for _, move := range game.ValidMoves() {
+		san := game.SAN(move)
+		if strings.HasPrefix(san, text) || strings.HasPrefix(move.String(), text) {
+			completions = append(completions, san)
+		}
+	}
+
+	return completions
+}
+
+// Opens the move input
+func (m GameModel) startTyping() (GameModel, tea.Cmd) {
+	if _, err := m.inputGame(); err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	m.typing = true
+	m.moveInput.Reset()
+
+	return m, m.moveInput.Focus()
+}
+
+func (m GameModel) stopTyping() GameModel {
+	m.typing = false
+	m.moveInput.Blur()
+	m.moveInput.Reset()
+
+	return m
+}
+
+func (m GameModel) handleMoveInputKeyMsg(msg tea.KeyMsg) (GameModel, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.MoveInputClose):
+		return m.stopTyping(), nil
+	case key.Matches(msg, m.keys.MoveInputComplete):
+		game, err := m.inputGame()
+		if err != nil {
+			return m.stopTyping(), nil
+		}
+
+		if completions := completeMove(game, m.moveInput.Value()); len(completions) > 0 {
+			m.moveInput.SetValue(completions[0])
+			m.moveInput.CursorEnd()
+		}
+		return m, nil
+	case key.Matches(msg, m.keys.MoveInputPlay):
+		game, err := m.inputGame()
+		if err != nil {
+			m.err = err
+			return m.stopTyping(), nil
+		}
+
+		move, err := game.ParseMove(m.moveInput.Value())
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+
+		m = m.stopTyping()
+		m.err = nil
+		return m.boardMove(move.String())
+	}
+
+	var cmd tea.Cmd
+	m.moveInput, cmd = m.moveInput.Update(msg)
+
+	return m, cmd
+}
+
+// Renders the move input, with the completions of the typed text or why it
+// can't be played
+func (m GameModel) renderMoveInput(width int) string {
+	lines := []string{m.moveInput.View()}
+
+	game, err := m.inputGame()
+	text := strings.TrimSpace(m.moveInput.Value())
+
+	switch {
+	case err != nil:
+		lines = append(lines, errorStyle.Render(err.Error()))
+	case text != "":
+		completions := completeMove(game, text)
+		if len(completions) > maxCompletions {
+			completions = append(completions[:maxCompletions], "…")
+		}
+
+		// Long algebraic or loose SAN complete nothing, but may be a move
+		if len(completions) == 0 {
+			move, err := game.ParseMove(text)
+			if err != nil {
+				lines = append(lines, errorStyle.Render(err.Error()))
+				break
+			}
+			completions = []string{game.SAN(move)}
+		}
+
+		lines = append(lines, altCodeStyle.Render(strings.Join(completions, "  ")))
+	}
+
+	return lipgloss.NewStyle().Width(width).Padding(0, 1).Render(
+		lipgloss.JoinVertical(lipgloss.Left, lines...),
+	)
+}
@@ -0,0 +1,19 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCompleteMove tests that the typed text completes both SAN and UCI moves.
+func TestCompleteMove(t *testing.T) {
+	game := chess960.NewStandardGame()
+
+	assert.ElementsMatch(t, []string{"Na3", "Nc3", "Nf3", "Nh3"}, completeMove(game, "N"))
+	assert.ElementsMatch(t, []string{"e3", "e4"}, completeMove(game, "e2"))
+	assert.Equal(t, []string{"e4"}, completeMove(game, "e2e4"))
+	assert.Equal(t, []string{"Nf3"}, completeMove(game, "Nf"))
+	assert.Empty(t, completeMove(game, "Qh5"))
+}
@@ -3,7 +3,10 @@ package views
 import (
 	"fmt"
 
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/notnil/chess"
@@ -11,9 +14,28 @@ import (
 
 // gameKeyMap defines the key bindings for the game view.
 type gameKeyMap struct {
-	Abandon key.Binding
-	Quit    key.Binding
-	Exit    key.Binding
+	Abandon           key.Binding
+	OfferDraw         key.Binding
+	Takeback          key.Binding
+	Accept            key.Binding
+	Decline           key.Binding
+	HandOver          key.Binding
+	Premoves          key.Binding
+	Board             key.Binding
+	MoveInput         key.Binding
+	Theme             key.Binding
+	HistoryBack       key.Binding
+	HistoryForward    key.Binding
+	HistoryLive       key.Binding
+	Chat              key.Binding
+	ChatChannel       key.Binding
+	ChatSend          key.Binding
+	ChatClose         key.Binding
+	MoveInputPlay     key.Binding
+	MoveInputComplete key.Binding
+	MoveInputClose    key.Binding
+	Quit              key.Binding
+	Exit              key.Binding
 }
 
 // defaultGameKeyMap provides the default key bindings for the game view.
@@ -22,6 +44,82 @@ var defaultGameKeyMap = gameKeyMap{
 		key.WithKeys("A", "a"),
 		key.WithHelp("     A", "Abandon"),
 	),
+	OfferDraw: key.NewBinding(
+		key.WithKeys("D", "d"),
+		key.WithHelp("     D", "Offer draw"),
+	),
+	Takeback: key.NewBinding(
+		key.WithKeys("T", "t"),
+		key.WithHelp("     T", "Request takeback"),
+	),
+	Accept: key.NewBinding(
+		key.WithKeys("Y", "y"),
+		key.WithHelp("     Y", "Accept"),
+	),
+	Decline: key.NewBinding(
+		key.WithKeys("N", "n"),
+		key.WithHelp("     N", "Decline"),
+	),
+	HandOver: key.NewBinding(
+		key.WithKeys("P", "p"),
+		key.WithHelp("     P", "Pass the move to teammate"),
+	),
+	Premoves: key.NewBinding(
+		key.WithKeys("X", "x"),
+		key.WithHelp("     X", "Cancel premoves"),
+	),
+	Board: key.NewBinding(
+		key.WithKeys("B", "b"),
+		key.WithHelp("     B", "Choose moves on the board"),
+	),
+	MoveInput: key.NewBinding(
+		key.WithKeys("M", "m"),
+		key.WithHelp("     M", "Type a move"),
+	),
+	Theme: key.NewBinding(
+		key.WithKeys("S", "s"),
+		key.WithHelp("     S", "Switch board theme"),
+	),
+	HistoryBack: key.NewBinding(
+		key.WithKeys("["),
+		key.WithHelp("     [", "Previous move"),
+	),
+	HistoryForward: key.NewBinding(
+		key.WithKeys("]"),
+		key.WithHelp("     ]", "Next move"),
+	),
+	HistoryLive: key.NewBinding(
+		key.WithKeys("end"),
+		key.WithHelp("   End", "Back to the live game"),
+	),
+	Chat: key.NewBinding(
+		key.WithKeys("C", "c"),
+		key.WithHelp("     C", "Chat"),
+	),
+	ChatChannel: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("   Tab", "Switch team/all chat"),
+	),
+	ChatSend: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp(" Enter", "Send"),
+	),
+	ChatClose: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("   Esc", "Close chat"),
+	),
+	MoveInputPlay: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp(" Enter", "Play"),
+	),
+	MoveInputComplete: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("   Tab", "Complete"),
+	),
+	MoveInputClose: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("   Esc", "Close move input"),
+	),
 	Quit: key.NewBinding(
 		key.WithKeys("Q", "q"),
 		key.WithHelp("     Q", "Quit"),
@@ -33,10 +131,20 @@ var defaultGameKeyMap = gameKeyMap{
 }
 
 func (m GameModel) handleKeyMsg(msg tea.KeyMsg) (GameModel, tea.Cmd) {
+	// While filtering the moves, keys are typed in the filter
+	if m.availableMovesList.FilterState() == list.Filtering {
+		return m, nil
+	}
+
 	switch {
 	case key.Matches(msg, m.keys.Abandon):
-		// Abandon game only if it is not finished
-		if m.game.Outcome == "*" {
+		// Abandon game only if it is not finished and we are playing it
+		if m.game.Outcome == "*" && !m.isSpectator() {
+			// In pair games the teammate must agree to resign
+			if m.game.Type.IsTeam() {
+				return m.propose(multiplayer.ResignProposal)
+			}
+
 			var outcome string
 			if m.network.Me() == m.playerPeer(1) || m.network.Me() == m.playerPeer(3) {
 				outcome = string(chess.BlackWon)
@@ -44,9 +152,42 @@ func (m GameModel) handleKeyMsg(msg tea.KeyMsg) (GameModel, tea.Cmd) {
 				outcome = string(chess.WhiteWon)
 			}
 
-			return m, m.endGame(outcome, true)
+			return m, m.endGame(outcome, chess.Resignation.String(), true)
+		}
+	case key.Matches(msg, m.keys.OfferDraw):
+		return m.propose(multiplayer.DrawProposal)
+	case key.Matches(msg, m.keys.Takeback):
+		return m.propose(multiplayer.TakebackProposal)
+	case key.Matches(msg, m.keys.Accept):
+		return m.answerProposal(true)
+	case key.Matches(msg, m.keys.Decline):
+		return m.answerProposal(false)
+	case key.Matches(msg, m.keys.HandOver):
+		return m.handOverTurn()
+	case key.Matches(msg, m.keys.Premoves):
+		return m.cancelPremoves()
+	case key.Matches(msg, m.keys.Board):
+		return m.toggleBoard()
+	case key.Matches(msg, m.keys.MoveInput):
+		return m.startTyping()
+	case key.Matches(msg, m.keys.Theme):
+		m.board = m.board.NextTheme()
+	case key.Matches(msg, m.keys.HistoryBack):
+		return m.historyBack()
+	case key.Matches(msg, m.keys.HistoryForward):
+		return m.historyForward()
+	case key.Matches(msg, m.keys.HistoryLive):
+		if m.reviewing {
+			return m.historyLive()
+		}
+	case key.Matches(msg, m.keys.Chat):
+		// Spectators can only read the chat
+		if !m.isSpectator() {
+			m.chatting = true
+			return m, m.chatInput.Focus()
 		}
 	case key.Matches(msg, m.keys.Quit):
+		m.closeBots()
 		return m, SwitchModelCmd(NewPlayModel(m.width, m.height))
 	}
 
@@ -55,12 +196,111 @@ func (m GameModel) handleKeyMsg(msg tea.KeyMsg) (GameModel, tea.Cmd) {
 
 func (m GameModel) renderNavigationButtons() string {
 	var abandonKey string
-	if m.game.Outcome == "*" {
+	if m.game.Outcome == "*" && !m.isSpectator() {
 		abandonKey = fmt.Sprintf("%s %s",
 			altCodeStyle.Render(m.keys.Abandon.Help().Key),
 			m.keys.Abandon.Help().Desc)
 	}
 
+	if m.chatting {
+		sendKey := fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.ChatSend.Help().Key),
+			m.keys.ChatSend.Help().Desc)
+
+		channelKey := fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.ChatChannel.Help().Key),
+			m.keys.ChatChannel.Help().Desc)
+
+		closeKey := fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.ChatClose.Help().Key),
+			m.keys.ChatClose.Help().Desc)
+
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			sendKey,
+			channelKey,
+			closeKey,
+		)
+	}
+
+	if m.typing {
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.MoveInputPlay.Help().Key), m.keys.MoveInputPlay.Help().Desc),
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.MoveInputComplete.Help().Key), m.keys.MoveInputComplete.Help().Desc),
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.MoveInputClose.Help().Key), m.keys.MoveInputClose.Help().Desc),
+		)
+	}
+
+	var proposalKeys []string
+	if m.isNegotiating() && m.negotiation.IsResponder(m.network.Me()) && !m.negotiation.Answered(m.network.Me()) {
+		proposalKeys = append(proposalKeys,
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Accept.Help().Key), m.keys.Accept.Help().Desc),
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Decline.Help().Key), m.keys.Decline.Help().Desc),
+		)
+	} else if m.isRunning() && !m.isSpectator() && !m.isNegotiating() {
+		proposalKeys = append(proposalKeys,
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.OfferDraw.Help().Key), m.keys.OfferDraw.Help().Desc),
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Takeback.Help().Key), m.keys.Takeback.Help().Desc),
+		)
+	}
+
+	var handOverKey string
+	if m.isRunning() && m.handOverTarget() != p2p.EmptyNetworkID {
+		handOverKey = fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.HandOver.Help().Key),
+			m.keys.HandOver.Help().Desc)
+	}
+
+	var premovesKey string
+	if len(m.premoves) > 0 {
+		premovesKey = fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.Premoves.Help().Key),
+			m.keys.Premoves.Help().Desc)
+	}
+
+	var boardKeys string
+	if !m.isSpectator() && !m.isBughouse() && m.isRunning() {
+		desc := m.keys.Board.Help().Desc
+		if m.boardFocused {
+			desc = "Choose moves from the list"
+		}
+		boardKeys = lipgloss.JoinVertical(lipgloss.Left,
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Board.Help().Key), desc),
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.MoveInput.Help().Key), m.keys.MoveInput.Help().Desc),
+		)
+
+		if m.usesBoard() {
+			boardKeys = lipgloss.JoinVertical(lipgloss.Left, boardKeys, m.board.renderNavigationButtons())
+		}
+	}
+
+	var historyKeys string
+	if !m.isBughouse() && len(m.chessGame.Moves()) > 0 {
+		historyKeys = lipgloss.JoinVertical(lipgloss.Left,
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.HistoryBack.Help().Key), m.keys.HistoryBack.Help().Desc),
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.HistoryForward.Help().Key), m.keys.HistoryForward.Help().Desc),
+		)
+
+		if m.reviewing {
+			historyKeys = lipgloss.JoinVertical(lipgloss.Left, historyKeys,
+				fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.HistoryLive.Help().Key), m.keys.HistoryLive.Help().Desc),
+			)
+		}
+	}
+
+	themeKey := fmt.Sprintf("%s %s (%s)",
+		altCodeStyle.Render(m.keys.Theme.Help().Key),
+		m.keys.Theme.Help().Desc,
+		m.board.ThemeName())
+
+	var chatKey string
+	if !m.isSpectator() {
+		chatKey = fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.Chat.Help().Key),
+			m.keys.Chat.Help().Desc)
+	}
+
 	quitKey := fmt.Sprintf("%s %s",
 		altCodeStyle.Render(m.keys.Quit.Help().Key),
 		m.keys.Quit.Help().Desc)
@@ -72,6 +312,13 @@ func (m GameModel) renderNavigationButtons() string {
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		abandonKey,
+		lipgloss.JoinVertical(lipgloss.Left, proposalKeys...),
+		handOverKey,
+		premovesKey,
+		boardKeys,
+		historyKeys,
+		themeKey,
+		chatKey,
 		quitKey,
 		exitKey,
 	)
@@ -0,0 +1,49 @@
+package views
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
+)
+
+// Renders the pieces captured by each player and who is ahead, as derived
+// from the moves of `game`. Pieces are drawn as on `board`.
+func renderMaterial(game *chess960.Game, board boardModel) string {
+	balance := game.MaterialBalance()
+
+	line := func(color chess.Color) string {
+		captured := slices.Clone(game.Captured(color))
+		slices.SortStableFunc(captured, func(a, b chess.PieceType) int {
+			return cmp.Compare(chess960.PieceValue(b), chess960.PieceValue(a))
+		})
+
+		pieces := make([]string, len(captured))
+		for i, pieceType := range captured {
+			pieces[i] = board.pieceString(chess.NewPiece(pieceType, color.Other()))
+		}
+
+		advantage := balance
+		if color == chess.Black {
+			advantage = -balance
+		}
+
+		var lead string
+		if advantage > 0 {
+			lead = altCodeStyle.Render(fmt.Sprintf(" +%d", advantage))
+		}
+
+		return fmt.Sprintf("%s %s%s", color.Name(), strings.Join(pieces, ""), lead)
+	}
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Background(highlightColor).Foreground(lipgloss.Color("230")).Padding(0, 1).MarginTop(1).MarginBottom(1).Render("Material"),
+		line(chess.White),
+		line(chess.Black),
+	)
+}
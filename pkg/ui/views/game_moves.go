@@ -1,13 +1,14 @@
 package views
 
 import (
+	"encoding/json"
 	"fmt"
-	"math/rand"
+	"slices"
+	"time"
 
-	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/bughouse"
 	"github.com/boozec/rahanna/pkg/p2p"
 	"github.com/boozec/rahanna/pkg/ui/multiplayer"
-	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/notnil/chess"
 )
@@ -15,14 +16,47 @@ import (
 // UpdateMovesListMsg is a message to update the moves list
 type UpdateMovesListMsg struct{}
 
-// ChessMoveMsg is a message containing a received chess move.
-type ChessMoveMsg string
+// ChessMoveMsg is a message containing a received chess move and its sender.
+type ChessMoveMsg struct {
+	Source     p2p.NetworkID
+	Move       string
+	Signed     multiplayer.SignedMove
+	ReceivedAt time.Time
+}
+
+// RejectedMoveMsg is a message containing a move refused by a peer.
+type RejectedMoveMsg multiplayer.MoveRejection
+
+// Catch for `RollbackGameMessage` message from multiplayer
+type RollbackMsg struct {
+	Source   p2p.NetworkID
+	Rollback multiplayer.MoveRollback
+}
+
+// Number of illegal moves after which a peer is considered a protocol
+// violator and its team loses by forfeit
+const maxIllegalMoves = 3
+
+// Number of moves kept while their turn is still on its way
+const maxQueuedMoves = 8
+
+// Method of the games lost by a team which broke the protocol or left
+const forfeitMethod = "Forfeit"
 
 type SendNewTurnMsg struct{}
-type SaveTurnMsg string
+
+// SaveTurnMsg is a message containing the turn assigned by `Source`.
+type SaveTurnMsg struct {
+	Source p2p.NetworkID
+	Turn   p2p.NetworkID
+}
 
 type item struct {
 	title string
+	claim chess.Method
+
+	// UCI notation of the move, when the title is not enough to rebuild it
+	move string
 }
 
 func (i item) Title() string       { return i.title }
@@ -32,9 +66,10 @@ func (i item) FilterValue() string { return i.title }
 func (m *GameModel) getMoves() tea.Cmd {
 	m.network.AddReceiveFunction(func(msg p2p.Message) {
 		gm := multiplayer.GameMove{
-			Source:  msg.Source,
-			Type:    msg.Type,
-			Payload: msg.Payload,
+			Source:     msg.Source,
+			Type:       msg.Type,
+			Payload:    msg.Payload,
+			ReceivedAt: time.Now(),
 		}
 		m.incomingMoves <- gm
 	})
@@ -46,13 +81,78 @@ func (m *GameModel) getMoves() tea.Cmd {
 		case multiplayer.AbandonGameMessage:
 			return EndGameMsg{abandoned: true}
 		case multiplayer.DefineTurnMessage:
-			return SaveTurnMsg(string(move.Payload))
+			return SaveTurnMsg{Source: move.Source, Turn: p2p.NetworkID(move.Payload)}
+		case multiplayer.ReadyGameMessage:
+			return PeerReadyMsg(move.Source)
+		case multiplayer.CommitGameMessage:
+			return PeerCommitMsg{Source: move.Source, Commitment: move.Payload}
+		case multiplayer.RevealGameMessage:
+			return PeerRevealMsg{Source: move.Source, Secret: move.Payload}
 		case multiplayer.RestoreGameMessage:
 			return SendRestoreMsg(move.Source)
 		case multiplayer.RestoreAckGameMessage:
-			return RestoreMoves(string(move.Payload))
+			var snapshot multiplayer.GameSnapshot
+			if err := json.Unmarshal(move.Payload, &snapshot); err != nil {
+				return err
+			}
+			return RestoreSnapshotMsg{Source: move.Source, Snapshot: snapshot}
+		case multiplayer.ChatGameMessage:
+			var chat multiplayer.ChatMessage
+			if err := json.Unmarshal(move.Payload, &chat); err != nil {
+				return err
+			}
+			chat.Author = move.Source
+			return ChatMsg(chat)
+		case multiplayer.BughouseMoveGameMessage:
+			var bughouseMove bughouse.Move
+			if err := json.Unmarshal(move.Payload, &bughouseMove); err != nil {
+				return err
+			}
+			return BughouseMoveMsg{Source: move.Source, Move: bughouseMove}
+		case multiplayer.ConsultGameMessage:
+			var vote multiplayer.ConsultVote
+			if err := json.Unmarshal(move.Payload, &vote); err != nil {
+				return err
+			}
+			return ConsultVoteMsg{Source: move.Source, Vote: vote}
+		case multiplayer.PublicKeyGameMessage:
+			return PeerKeyMsg{Source: move.Source, Key: move.Payload}
+		case multiplayer.ProposeGameMessage:
+			var proposal multiplayer.Proposal
+			if err := json.Unmarshal(move.Payload, &proposal); err != nil {
+				return err
+			}
+			return ProposalMsg{Source: move.Source, Proposal: proposal}
+		case multiplayer.AnswerGameMessage:
+			var answer multiplayer.ProposalAnswer
+			if err := json.Unmarshal(move.Payload, &answer); err != nil {
+				return err
+			}
+			return ProposalAnswerMsg{Source: move.Source, Answer: answer}
+		case multiplayer.ProposalResultGameMessage:
+			var result multiplayer.ProposalResult
+			if err := json.Unmarshal(move.Payload, &result); err != nil {
+				return err
+			}
+			return ProposalResultMsg{Source: move.Source, Result: result}
+		case multiplayer.RejectMoveGameMessage:
+			var rejection multiplayer.MoveRejection
+			if err := json.Unmarshal(move.Payload, &rejection); err != nil {
+				return err
+			}
+			return RejectedMoveMsg(rejection)
+		case multiplayer.RollbackGameMessage:
+			var rollback multiplayer.MoveRollback
+			if err := json.Unmarshal(move.Payload, &rollback); err != nil {
+				return err
+			}
+			return RollbackMsg{Source: move.Source, Rollback: rollback}
 		default:
-			return ChessMoveMsg(string(move.Payload))
+			var signed multiplayer.SignedMove
+			if err := json.Unmarshal(move.Payload, &signed); err != nil {
+				return err
+			}
+			return ChessMoveMsg{Source: move.Source, Move: signed.Move, Signed: signed, ReceivedAt: move.ReceivedAt}
 		}
 	}
 }
@@ -65,23 +165,27 @@ func (m *GameModel) updateMovesListCmd() tea.Cmd {
 
 func (m GameModel) handleUpdateMovesListMsg() GameModel {
 	if m.isMyTurn() && m.game != nil {
-		var items []list.Item
-		for _, move := range m.chessGame.ValidMoves() {
-			var promo string
-			if move.Promo().String() != "" {
-				promo = " " + move.Promo().String()
-			}
-			items = append(
-				items,
-				item{title: fmt.Sprintf("%s → %s%s", move.S1().String(), move.S2().String(), promo)},
-			)
+		m = m.startConsultation()
+
+		if m.isBughouse() {
+			m.availableMovesList.SetItems(m.bughouseItems())
+		} else {
+			m.availableMovesList.SetItems(append(m.claimItems(), moveItems(m.chessGame)...))
 		}
-		m.availableMovesList.SetItems(items)
+		m.board = m.board.SetPosition(m.chessGame.Position().Board(), m.chessGame.ValidMoves(), m.seatColor(m.network.Me())).SetMarked(nil)
 		m.availableMovesList.Title = "Choose a move"
 		m.availableMovesList.Select(0)
 		m.availableMovesList.SetShowFilter(true)
 		m.availableMovesList.SetFilteringEnabled(true)
 		m.availableMovesList.ResetFilter()
+	} else if m.canPremove() {
+		m = m.updatePremoveBoard()
+		m.availableMovesList.SetItems(m.premoveItems())
+		m.availableMovesList.Title = fmt.Sprintf("Queue a premove (%d)", len(m.premoves))
+		m.availableMovesList.Select(0)
+		m.availableMovesList.SetShowFilter(true)
+		m.availableMovesList.SetFilteringEnabled(true)
+		m.availableMovesList.ResetFilter()
 	}
 	return m
 }
@@ -89,44 +193,8 @@ func (m GameModel) handleUpdateMovesListMsg() GameModel {
 func (m GameModel) handleDefineTurnMsg() (GameModel, tea.Cmd) {
 	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd()}
 
-	switch m.game.Type {
-	case database.SingleGameType:
-		if m.network.Me() == m.playerPeer(1) {
-			m.turn = m.playerPeer(2)
-		} else {
-			m.turn = m.playerPeer(1)
-		}
-	case database.PairGameType:
-		switch m.game.MoveChoose {
-		case database.SequentialChooseType:
-			switch m.network.Me() {
-			case m.playerPeer(1):
-				m.turn = m.playerPeer(2)
-			case m.playerPeer(2):
-				m.turn = m.playerPeer(3)
-			case m.playerPeer(3):
-				m.turn = m.playerPeer(4)
-			case m.playerPeer(4):
-				m.turn = m.playerPeer(1)
-			}
-		case database.RandomChooseType:
-			var players []int
-			switch m.network.Me() {
-			case m.playerPeer(1):
-				players = []int{2, 4}
-			case m.playerPeer(3):
-				players = []int{2, 4}
-			case m.playerPeer(2):
-				players = []int{1, 3}
-			case m.playerPeer(4):
-				players = []int{1, 3}
-			}
-			m.turn = m.playerPeer(players[rand.Intn(len(players))])
-		default:
-			panic("should not be here")
-		}
-	}
-
+	m.turn = m.turnStrategy().PickTurn(m.turnContext(len(m.chessGame.Moves())))
+	m.recordTurn(m.network.Me(), m.turn)
 	m.network.SendAll([]byte(string(multiplayer.DefineTurnMessage)), []byte(string(m.turn)))
 
 	return m, tea.Batch(cmds...)
@@ -135,17 +203,159 @@ func (m GameModel) handleDefineTurnMsg() (GameModel, tea.Cmd) {
 func (m GameModel) handleSaveTurnMsg(msg SaveTurnMsg) (GameModel, tea.Cmd) {
 	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd()}
 
-	m.turn = p2p.NetworkID(msg)
+	m.turn = msg.Turn
+
+	if m.game == nil {
+		return m, tea.Batch(cmds...)
+	}
+
+	// Turns must follow the strategy of the game
+	strategy := m.turnStrategy()
+	m.recordTurn(msg.Source, msg.Turn)
+
+	if strategy.UsesSeed() && m.seed == nil {
+		m.err = fmt.Errorf("can't verify the turn assigned by %s: missing shared seed", msg.Source)
+	} else {
+		ctx := m.turnContext(len(m.chessGame.Moves()))
+		if err := strategy.CheckTurn(ctx, msg.Source, msg.Turn); err != nil {
+			m.err = err
+			m.turn = strategy.PickTurn(ctx)
+		}
+	}
+
+	if m.turn == m.network.Me() {
+		var cmd tea.Cmd
+		m, cmd = m.playPremove()
+		cmds = append(cmds, cmd)
+	}
+
+	var cmd tea.Cmd
+	m, cmd = m.playQueuedMoves()
+	cmds = append(cmds, cmd)
+
+	return m, tea.Batch(cmds...)
+}
+
+// Returns true if `msg` can wait for its turn: the move after the next one, or
+// the next one from another seat, as its turn and the moves before it come
+// from other peers on other links
+func (m GameModel) canQueue(msg ChessMoveMsg) bool {
+	ply := len(m.chessGame.Moves())
+	return msg.Signed.Ply > ply || msg.Signed.Ply == ply && msg.Source != m.turn
+}
+
+// Plays the queued moves whose turn is now known. The ones of the current ply
+// are played or refused, the later ones wait again.
+func (m GameModel) playQueuedMoves() (GameModel, tea.Cmd) {
+	var cmds []tea.Cmd
+	for {
+		i := slices.IndexFunc(m.queuedMoves, func(msg ChessMoveMsg) bool {
+			return msg.Signed.Ply <= len(m.chessGame.Moves())
+		})
+		if i < 0 {
+			break
+		}
+
+		msg := m.queuedMoves[i]
+		m.queuedMoves = slices.Delete(m.queuedMoves, i, i+1)
+
+		var cmd tea.Cmd
+		m, cmd = m.receiveMove(msg, false)
+		cmds = append(cmds, cmd)
+	}
 
 	return m, tea.Batch(cmds...)
 }
 
 func (m GameModel) handleChessMoveMsg(msg ChessMoveMsg) (GameModel, tea.Cmd) {
-	m.err = m.chessGame.MoveStr(string(msg))
+	return m.receiveMove(msg, true)
+}
+
+// Plays a move received from a seat, or refuses it. If `canWait`, a move whose
+// turn is not known yet is queued instead.
+func (m GameModel) receiveMove(msg ChessMoveMsg, canWait bool) (GameModel, tea.Cmd) {
 	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd()}
 
+	if !slices.Contains(m.seats(), msg.Source) {
+		m.err = fmt.Errorf("ignored move `%s` from %s: not a player", msg.Move, msg.Source)
+		return m, tea.Batch(cmds...)
+	}
+
+	if canWait && m.canQueue(msg) && len(m.queuedMoves) < maxQueuedMoves {
+		m.queuedMoves = append(m.queuedMoves, msg)
+		return m, tea.Batch(cmds...)
+	}
+
+	// Only the seat to move can move, a partner included
+	var err error
+	if ply := len(m.chessGame.Moves()); msg.Signed.Ply != ply {
+		err = fmt.Errorf("move %d sent at move %d", msg.Signed.Ply, ply)
+	} else if msg.Source != m.turn {
+		err = fmt.Errorf("not the turn of %s", msg.Source)
+	}
+	if err == nil {
+		err = m.verifyMove(msg)
+	}
+	if err == nil {
+		err = m.chessGame.MoveStr(msg.Move)
+	}
+
+	if err != nil {
+		m.err = fmt.Errorf("illegal move `%s` from %s: %v", msg.Move, msg.Source, err)
+
+		// Spectators just watch, only players refuse moves
+		if m.isSpectator() {
+			return m, tea.Batch(cmds...)
+		}
+
+		m.illegalMoves[msg.Source]++
+
+		payload, _ := json.Marshal(multiplayer.MoveRejection{Move: msg.Move, Reason: err.Error()})
+		m.network.Send(msg.Source, []byte(string(multiplayer.RejectMoveGameMessage)), payload)
+
+		if m.illegalMoves[msg.Source] == maxIllegalMoves {
+			cmds = append(cmds, m.forfeit(msg.Source, forfeitMethod))
+		}
+
+		return m, tea.Batch(cmds...)
+	}
+
+	m.err = nil
+	m.punchClock(msg.Source, m.moveTime(msg))
+
+	if msg.Source != m.network.Me() {
+		m = m.checkPremoves()
+	}
+
+	if m.chessGame.Outcome() != chess.NoOutcome && !m.isSpectator() {
+		cmds = append(cmds, m.endGame(m.chessGame.Outcome().String(), m.chessGame.Method().String(), false))
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// Plays a local move and sends it to everyone
+func (m GameModel) playMove(moveStr string) (GameModel, tea.Cmd) {
+	now := time.Now()
+	ply := len(m.chessGame.Moves())
+	err := m.chessGame.MoveStr(moveStr)
+	if err != nil {
+		m.err = err
+	} else {
+		payload, err := m.signMove(moveStr, ply, now)
+		if err != nil {
+			m.err = err
+		} else {
+			m.network.SendAll([]byte(string(multiplayer.MoveGameMessage)), payload)
+			m.pendingMove = moveStr
+			m.punchClock(m.network.Me(), now)
+			m.err = nil
+		}
+	}
+	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd(), m.sendNewTurnCmd()}
+
 	if m.chessGame.Outcome() != chess.NoOutcome {
-		cmds = append(cmds, m.endGame(m.chessGame.Outcome().String(), false))
+		cmds = append(cmds, m.endGame(m.chessGame.Outcome().String(), m.chessGame.Method().String(), false))
 	}
 
 	return m, tea.Batch(cmds...)
@@ -156,3 +366,102 @@ func (m GameModel) sendNewTurnCmd() tea.Cmd {
 		return SendNewTurnMsg{}
 	}
 }
+
+// A peer refused our last move: roll it back and take the turn again
+func (m GameModel) handleRejectedMoveMsg(msg RejectedMoveMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd()}
+
+	// The same rejection can arrive from every peer, so roll back only once
+	if m.pendingMove == "" || m.pendingMove != msg.Move {
+		return m, tea.Batch(cmds...)
+	}
+
+	moves := m.chessGame.Moves()
+	if len(moves) == 0 || moves[len(moves)-1].String() != msg.Move {
+		return m, tea.Batch(cmds...)
+	}
+
+	game, err := m.chessGame.Rollback(len(moves) - 1)
+	if err != nil {
+		m.err = err
+		return m, tea.Batch(cmds...)
+	}
+
+	m.chessGame = game
+	m.undoClock(1)
+	m.pendingMove = ""
+	m.premoves = nil
+	m.dropQueuedMoves(len(m.chessGame.Moves()))
+	m.turn = m.network.Me()
+	m.trimTurns(len(m.chessGame.Moves()))
+
+	// The peers which accepted the move roll it back too
+	payload, _ := json.Marshal(multiplayer.MoveRollback{Move: msg.Move, Ply: len(m.chessGame.Moves())})
+	m.network.SendAll([]byte(string(multiplayer.RollbackGameMessage)), payload)
+	m.network.SendAll([]byte(string(multiplayer.DefineTurnMessage)), []byte(string(m.turn)))
+	m.err = fmt.Errorf("move `%s` rejected: %s", msg.Move, msg.Reason)
+
+	return m, tea.Batch(cmds...)
+}
+
+// Forgets the queued moves played after the `ply`-th one, which are built on
+// a move rolled back
+func (m *GameModel) dropQueuedMoves(ply int) {
+	m.queuedMoves = slices.DeleteFunc(m.queuedMoves, func(msg ChessMoveMsg) bool {
+		return msg.Signed.Ply >= ply
+	})
+}
+
+// Another peer refused the last move of a seat, which rolled it back: roll it
+// back too. Its team must have played it, and the seat takes the turn again
+// with the `DefineTurnMessage` which follows.
+func (m GameModel) handleRollbackMsg(msg RollbackMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd()}
+
+	if !slices.Contains(m.seats(), msg.Source) {
+		return m, tea.Batch(cmds...)
+	}
+
+	ply := msg.Rollback.Ply
+	m.dropQueuedMoves(ply)
+
+	// We refused the move too, or never got it
+	moves := m.chessGame.Moves()
+	if ply < 0 || len(moves) != ply+1 || moves[ply].String() != msg.Rollback.Move {
+		return m, tea.Batch(cmds...)
+	}
+
+	if m.chessGame.Positions()[ply].Turn() != m.seatColor(msg.Source) {
+		m.err = fmt.Errorf("%s can't roll back a move of the other team", msg.Source)
+		return m, tea.Batch(cmds...)
+	}
+
+	game, err := m.chessGame.Rollback(ply)
+	if err != nil {
+		m.err = err
+		return m, tea.Batch(cmds...)
+	}
+
+	m.chessGame = game
+	m.undoClock(1)
+	m.premoves = nil
+	m.turn = msg.Source
+	m.trimTurns(ply)
+	m.notice = fmt.Sprintf("Move `%s` of %s rolled back", msg.Rollback.Move, m.peerName(msg.Source))
+
+	return m, tea.Batch(cmds...)
+}
+
+// End the game as lost for the team of `peer`. A peer without a seat, such
+// as a spectator, can't lose it.
+func (m GameModel) forfeit(peer p2p.NetworkID, method string) tea.Cmd {
+	outcome := chess.WhiteWon
+	switch m.seatColor(peer) {
+	case chess.NoColor:
+		return nil
+	case chess.White:
+		outcome = chess.BlackWon
+	}
+
+	return m.endGame(outcome.String(), method, true)
+}
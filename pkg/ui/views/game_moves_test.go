@@ -0,0 +1,93 @@
+package views
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the model of the third seat of a pair game, which knows the keys of
+// the returned signers of the other seats
+func newPairGameModel(t *testing.T) (GameModel, map[p2p.NetworkID]*multiplayer.Signer) {
+	m := newTestGameModel(t, "game-3", false)
+	m.game.Type = database.PairGameType
+	m.game.MoveChoose = database.SequentialChooseType
+
+	signers := make(map[p2p.NetworkID]*multiplayer.Signer)
+	for _, seat := range []p2p.NetworkID{"game-1", "game-2", "game-4"} {
+		signer, err := multiplayer.NewSigner()
+		assert.NoError(t, err)
+		signers[seat] = signer
+		m.publicKeys[seat] = signer.PublicKey()
+	}
+	m.turn = "game-1"
+
+	return m, signers
+}
+
+// Returns the message of the move `move` played by `seat` after `ply` moves
+func signedMove(signers map[p2p.NetworkID]*multiplayer.Signer, seat p2p.NetworkID, move string, ply int) ChessMoveMsg {
+	now := time.Now()
+	return ChessMoveMsg{Source: seat, Move: move, Signed: signers[seat].SignMove(move, ply, now), ReceivedAt: now}
+}
+
+// TestMoveWaitsForItsTurn tests that a move which arrives before its turn is
+// played once the turn arrives, and that the moves of the wrong seat are still
+// refused.
+func TestMoveWaitsForItsTurn(t *testing.T) {
+	m, signers := newPairGameModel(t)
+
+	m, _ = m.handleChessMoveMsg(signedMove(signers, "game-1", "e2e4", 0))
+	assert.NoError(t, m.err)
+
+	// The turn of game-2 is still on its way from game-1
+	m, _ = m.handleChessMoveMsg(signedMove(signers, "game-2", "e7e5", 1))
+	assert.NoError(t, m.err)
+	assert.Len(t, m.chessGame.Moves(), 1)
+	assert.Zero(t, m.illegalMoves["game-2"])
+
+	m, _ = m.handleSaveTurnMsg(SaveTurnMsg{Source: "game-1", Turn: "game-2"})
+	assert.NoError(t, m.err)
+	assert.Len(t, m.chessGame.Moves(), 2)
+	assert.Empty(t, m.queuedMoves)
+
+	// A move of a past ply is refused
+	m, _ = m.handleChessMoveMsg(signedMove(signers, "game-4", "g8f6", 1))
+	assert.Error(t, m.err)
+	assert.Len(t, m.chessGame.Moves(), 2)
+	assert.Equal(t, 1, m.illegalMoves["game-4"])
+}
+
+// TestRollbackMove tests that a move refused by another peer is rolled back by
+// the peers which accepted it, on request of its team only.
+func TestRollbackMove(t *testing.T) {
+	m, signers := newPairGameModel(t)
+
+	m, _ = m.handleChessMoveMsg(signedMove(signers, "game-1", "e2e4", 0))
+	assert.Len(t, m.chessGame.Moves(), 1)
+
+	m, _ = m.handleRollbackMsg(RollbackMsg{Source: "game-2", Rollback: multiplayer.MoveRollback{Move: "e2e4", Ply: 0}})
+	assert.Error(t, m.err)
+	assert.Len(t, m.chessGame.Moves(), 1)
+
+	m, _ = m.handleRollbackMsg(RollbackMsg{Source: "game-1", Rollback: multiplayer.MoveRollback{Move: "e2e4", Ply: 0}})
+	assert.Empty(t, m.chessGame.Moves())
+	assert.Equal(t, p2p.NetworkID("game-1"), m.turn)
+
+	// The same rollback from every peer is applied once
+	m, _ = m.handleRollbackMsg(RollbackMsg{Source: "game-1", Rollback: multiplayer.MoveRollback{Move: "e2e4", Ply: 0}})
+	assert.Empty(t, m.chessGame.Moves())
+}
+
+// TestForfeitWithoutSeat tests that only a seat can lose a game by forfeit.
+func TestForfeitWithoutSeat(t *testing.T) {
+	m, _ := newPairGameModel(t)
+
+	assert.Nil(t, m.forfeit("game-s1", forfeitMethod))
+	assert.Nil(t, m.forfeit(p2p.EmptyNetworkID, forfeitMethod))
+	assert.NotNil(t, m.forfeit("game-2", forfeitMethod))
+}
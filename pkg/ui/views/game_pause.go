@@ -0,0 +1,159 @@
+package views
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
+)
+
+const (
+	// Interval between two heartbeats sent to the peers
+	heartbeatInterval = time.Second
+
+	// A seat which does not send anything for this time is unreachable
+	peerTimeout = 5 * time.Second
+)
+
+// GameTickMsg is sent every second to check the peers reachability.
+type GameTickMsg time.Time
+
+func (m GameModel) gameTickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return GameTickMsg(t)
+	})
+}
+
+// Reads the grace period after which an unreachable seat loses by forfeit.
+// The game waits forever if `RAHANNA_FORFEIT_AFTER` is not set.
+func getForfeitGrace() time.Duration {
+	grace, err := time.ParseDuration(os.Getenv("RAHANNA_FORFEIT_AFTER"))
+	if err != nil || grace < 0 {
+		return 0
+	}
+
+	return grace
+}
+
+// Returns true if the game is running: the first turn is defined and nobody
+// won yet
+func (m GameModel) isRunning() bool {
+	return m.game != nil && m.turn != p2p.EmptyNetworkID && m.game.Outcome == chess.NoOutcome.String()
+}
+
+// Returns the seats which did not send anything recently
+func (m GameModel) unreachableSeats() []p2p.NetworkID {
+	var seats []p2p.NetworkID
+	for _, seat := range m.seats() {
+		if seat != m.network.Me() && !m.network.IsReachable(seat, peerTimeout) {
+			seats = append(seats, seat)
+		}
+	}
+
+	return seats
+}
+
+// Returns true if `peer` is the reachable seat with the lowest number. It is
+// the peer in charge of reporting the outcomes nobody else can agree on.
+func (m GameModel) isReporter(peer p2p.NetworkID) bool {
+	for _, seat := range m.seats() {
+		if seat == m.network.Me() || m.network.IsReachable(seat, peerTimeout) {
+			return seat == peer
+		}
+	}
+
+	return false
+}
+
+// Pauses the game when a seat becomes unreachable and resumes it when the seat
+// is back. After the grace period the unreachable team loses by forfeit.
+func (m GameModel) handleGameTickMsg(msg GameTickMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.gameTickCmd()}
+
+	now := time.Time(msg)
+
+	if !m.isRunning() {
+		m.pausedSeats = nil
+		m.negotiation = nil
+		if m.clock != nil {
+			m.clock.Stop(now)
+		}
+		return m, tea.Batch(cmds...)
+	}
+
+	unreachable := m.unreachableSeats()
+
+	var cmd tea.Cmd
+	m, cmd = m.checkProposal(now)
+	cmds = append(cmds, cmd)
+
+	if len(unreachable) == 0 {
+		m, cmd = m.resolveConsultation(now)
+		cmds = append(cmds, cmd)
+
+		if len(m.pausedSeats) > 0 {
+			m.pausedSeats = nil
+			if m.clock != nil {
+				m.clock.Resume(now)
+			}
+			cmds = append(cmds, m.updateMovesListCmd())
+		}
+
+		m, cmd = m.checkFlag(now)
+		cmds = append(cmds, cmd)
+
+		return m, tea.Batch(cmds...)
+	}
+
+	// The clocks stop while the game waits for the unreachable seats
+	if len(m.pausedSeats) == 0 {
+		m.pausedAt = now
+		if m.clock != nil {
+			m.clock.Pause(now)
+		}
+	}
+	m.pausedSeats = unreachable
+
+	if m.forfeitGrace > 0 && now.Sub(m.pausedAt) >= m.forfeitGrace && m.isReporter(m.network.Me()) {
+		m.pausedSeats = nil
+		cmds = append(cmds, m.forfeit(unreachable[0], forfeitMethod))
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+func (m GameModel) isPaused() bool {
+	return len(m.pausedSeats) > 0
+}
+
+// Renders the waiting message with the countdown to the forfeit
+func (m GameModel) renderPause(width, height int) string {
+	var names []string
+	for _, seat := range m.pausedSeats {
+		names = append(names, string(seat))
+	}
+
+	elapsed := time.Since(m.pausedAt).Truncate(time.Second)
+
+	var countdown string
+	if m.forfeitGrace > 0 {
+		remaining := max(m.forfeitGrace-elapsed, 0)
+		countdown = fmt.Sprintf("Forfeit in %s", remaining)
+	} else {
+		countdown = fmt.Sprintf("Waiting for %s", elapsed)
+	}
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			lipgloss.NewStyle().Bold(true).Render("Game paused"),
+			fmt.Sprintf("%s disconnected", strings.Join(names, ", ")),
+			altCodeStyle.Render(countdown),
+		),
+	)
+}
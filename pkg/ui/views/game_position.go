@@ -0,0 +1,90 @@
+package views
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Sets the starting position of a game with no moves yet
+func (m GameModel) setStartPosition(fen string) GameModel {
+	if fen == "" || len(m.chessGame.Moves()) > 0 || m.chessGame.StartFEN() == fen {
+		return m
+	}
+
+	game, err := chess960.NewGame(fen)
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	m.chessGame = game
+
+	return m
+}
+
+// Sets the Chess960 starting position drawn from the shared seed. The first
+// player saves it, so the game can be restored from it.
+func (m GameModel) drawStartPosition() (GameModel, tea.Cmd) {
+	if m.game == nil || !m.game.Chess960 || m.seed == nil {
+		return m, nil
+	}
+
+	fen, err := multiplayer.StartPositionFEN(m.seed)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	m = m.setStartPosition(fen)
+
+	if m.network.Me() != m.playerPeer(1) || m.game.StartFEN == fen {
+		return m, nil
+	}
+
+	return m, m.saveStartPositionCmd(fen)
+}
+
+// Saves the starting position of a Chess960 game
+func (m *GameModel) saveStartPositionCmd(fen string) tea.Cmd {
+	return func() tea.Msg {
+		// Get authorization token
+		authorization, err := getAuthorizationToken()
+		if err != nil {
+			return err
+		}
+
+		// Prepare request payload
+		payload, err := json.Marshal(map[string]string{
+			"start_fen": fen,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Send API request
+		url := fmt.Sprintf("%s/play/%d/start", os.Getenv("API_BASE"), m.currentGameID)
+		resp, err := sendAPIRequest("POST", url, payload, authorization)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			var response struct {
+				Error string `json:"error"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				return fmt.Errorf("HTTP error: %d, unable to decode body", resp.StatusCode)
+			}
+			return fmt.Errorf("can't save the starting position: %s", response.Error)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,150 @@
+package views
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/notnil/chess"
+)
+
+// Returns true if the player can queue moves while waiting for the opponent.
+// Team games share the moves of a color, so only single games have premoves.
+func (m GameModel) canPremove() bool {
+	return m.isRunning() && !m.isSpectator() && !m.isPaused() && !m.isMyTurn() &&
+		!m.game.Type.IsTeam() && !m.isBughouse()
+}
+
+// Returns the game where the next premove is chosen: the current position
+// with the queued premoves played, as if the opponent passed every time
+func (m GameModel) premoveGame() (*chess960.Game, error) {
+	game := m.chessGame
+	if game.Position().Turn() == m.seatColor(m.network.Me()) {
+		game = game.Clone()
+	} else {
+		var err error
+		if game, err = game.PassTurn(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, move := range m.premoves {
+		if err := game.MoveStr(move); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if game, err = game.PassTurn(); err != nil {
+			return nil, err
+		}
+	}
+
+	return game, nil
+}
+
+// Returns the items of the moves list to queue a premove
+func (m GameModel) premoveItems() []list.Item {
+	game, err := m.premoveGame()
+	if err != nil {
+		return nil
+	}
+
+	return moveItems(game)
+}
+
+// Adds `moveStr` at the end of the premoves
+func (m GameModel) queuePremove(moveStr string) (GameModel, tea.Cmd) {
+	m.premoves = append(slices.Clone(m.premoves), moveStr)
+	return m, m.updateMovesListCmd()
+}
+
+// Cancels every queued premove
+func (m GameModel) cancelPremoves() (GameModel, tea.Cmd) {
+	if len(m.premoves) == 0 {
+		return m, nil
+	}
+
+	m.premoves = nil
+	m.notice = "Premoves cancelled"
+
+	return m, m.updateMovesListCmd()
+}
+
+// Returns true if `moveStr` can be played in the current position
+func (m GameModel) isLegal(moveStr string) bool {
+	for _, move := range m.chessGame.ValidMoves() {
+		if move.String() == moveStr {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks the next premove once the opponent moved. If it became illegal, the
+// whole queue is discarded, since the next premoves were planned after it.
+func (m GameModel) checkPremoves() GameModel {
+	if len(m.premoves) == 0 || m.isLegal(m.premoves[0]) {
+		return m
+	}
+
+	m.notice = fmt.Sprintf("Premove `%s` is illegal now, %d premoves discarded", m.premoves[0], len(m.premoves))
+	m.premoves = nil
+
+	return m
+}
+
+// Plays the next premove. The turn assigned by the opponent follows its move,
+// so the premove is sent as soon as the turn is ours.
+func (m GameModel) playPremove() (GameModel, tea.Cmd) {
+	if len(m.premoves) == 0 || !m.isMyTurn() || m.isPaused() || m.isTakingBack() || !m.isRunning() {
+		return m, nil
+	}
+
+	m = m.checkPremoves()
+	if len(m.premoves) == 0 {
+		return m, nil
+	}
+
+	move := m.premoves[0]
+	m.premoves = m.premoves[1:]
+
+	return m.playMove(move)
+}
+
+// Returns the squares of the queued premoves
+func (m GameModel) premoveSquares() map[chess.Square]bool {
+	squares := make(map[chess.Square]bool)
+	for _, move := range m.premoves {
+		if len(move) < 4 {
+			continue
+		}
+
+		for _, name := range []string{move[:2], move[2:4]} {
+			for sq := chess.A1; sq <= chess.H8; sq++ {
+				if sq.String() == name {
+					squares[sq] = true
+				}
+			}
+		}
+	}
+
+	return squares
+}
+
+// Returns the line listing the queued premoves
+func (m GameModel) renderPremoves() string {
+	if len(m.premoves) == 0 {
+		return ""
+	}
+
+	moves := make([]string, len(m.premoves))
+	for i, move := range m.premoves {
+		moves[i] = fmt.Sprintf("%s → %s", move[:2], move[2:])
+	}
+
+	return altCodeStyle.Render("Premoves: " + strings.Join(moves, ", "))
+}
@@ -0,0 +1,337 @@
+package views
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/notnil/chess"
+)
+
+// Time the responders have to answer a proposal
+const proposalTimeout = 30 * time.Second
+
+var proposalDescriptions = map[multiplayer.ProposalKind]string{
+	multiplayer.DrawProposal:     "offers a draw",
+	multiplayer.TakebackProposal: "asks to take back the last move",
+	multiplayer.ResignProposal:   "wants to resign",
+	multiplayer.ClaimProposal:    "claims a draw",
+}
+
+// Catch for `ProposeGameMessage` message from multiplayer
+type ProposalMsg struct {
+	Source   p2p.NetworkID
+	Proposal multiplayer.Proposal
+}
+
+// Catch for `AnswerGameMessage` message from multiplayer
+type ProposalAnswerMsg struct {
+	Source p2p.NetworkID
+	Answer multiplayer.ProposalAnswer
+}
+
+// Catch for `ProposalResultGameMessage` message from multiplayer
+type ProposalResultMsg struct {
+	Source p2p.NetworkID
+	Result multiplayer.ProposalResult
+}
+
+// Returns the seats which must accept a proposal of `proposer`: the
+// teammates for a resignation, everyone for a claim, the opponents otherwise
+func (m GameModel) responders(kind multiplayer.ProposalKind, proposer p2p.NetworkID) []p2p.NetworkID {
+	var responders []p2p.NetworkID
+	color := m.seatColor(proposer)
+	for _, seat := range m.seats() {
+		if seat == proposer {
+			continue
+		}
+
+		if kind == multiplayer.ClaimProposal {
+			responders = append(responders, seat)
+		} else if sameTeam := m.seatColor(seat) == color; sameTeam == (kind == multiplayer.ResignProposal) {
+			responders = append(responders, seat)
+		}
+	}
+
+	return responders
+}
+
+// Returns the number of moves to undo to take back the last move of the team
+// of `proposer`, or 0 if that team did not move yet
+func (m GameModel) takebackPlies(proposer p2p.NetworkID) int {
+	return multiplayer.TakebackPlies(m.chessGame, m.seatColor(proposer))
+}
+
+// Returns the seat which moves at the `ply`-th move
+func (m GameModel) seatAtPly(ply int) p2p.NetworkID {
+	return m.turnStrategy().PickTurn(m.turnContext(ply))
+}
+
+func (m GameModel) isNegotiating() bool {
+	return m.negotiation != nil
+}
+
+func (m GameModel) isTakingBack() bool {
+	return m.isNegotiating() && m.negotiation.Proposal.Kind == multiplayer.TakebackProposal
+}
+
+// Proposes `kind` to the players who have to accept it
+func (m GameModel) propose(kind multiplayer.ProposalKind) (GameModel, tea.Cmd) {
+	return m.proposeMethod(kind, "")
+}
+
+func (m GameModel) proposeMethod(kind multiplayer.ProposalKind, method string) (GameModel, tea.Cmd) {
+	if !m.isRunning() || m.isSpectator() || m.isNegotiating() {
+		return m, nil
+	}
+
+	if kind == multiplayer.TakebackProposal && m.isBughouse() {
+		m.err = errors.New("takebacks are not supported in bughouse")
+		return m, nil
+	}
+
+	if kind == multiplayer.TakebackProposal && m.takebackPlies(m.network.Me()) == 0 {
+		m.err = errors.New("there is no move to take back")
+		return m, nil
+	}
+
+	ply := m.plyCount()
+	proposal := multiplayer.Proposal{
+		ID:       fmt.Sprintf("%s-%s-%d", m.network.Me(), kind, ply),
+		Kind:     kind,
+		Proposer: m.network.Me(),
+		Ply:      ply,
+		Method:   method,
+	}
+
+	payload, err := json.Marshal(proposal)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	m.negotiation = multiplayer.NewNegotiation(proposal, m.responders(kind, proposal.Proposer), time.Now().Add(proposalTimeout))
+	m.err = m.network.SendAll([]byte(string(multiplayer.ProposeGameMessage)), payload)
+
+	return m.resolveProposal(time.Now())
+}
+
+// Answers the pending proposal, if the local peer has to
+func (m GameModel) answerProposal(accepted bool) (GameModel, tea.Cmd) {
+	me := m.network.Me()
+	if !m.isNegotiating() || !m.negotiation.IsResponder(me) || m.negotiation.Answered(me) {
+		return m, nil
+	}
+
+	if err := m.negotiation.Answer(me, accepted); err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	payload, err := json.Marshal(multiplayer.ProposalAnswer{ID: m.negotiation.Proposal.ID, Accepted: accepted})
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	m.err = m.network.SendAll([]byte(string(multiplayer.AnswerGameMessage)), payload)
+
+	return m, nil
+}
+
+// For `ProposeGameMessage` from multiplayer it starts waiting for the answers.
+// Only one proposal at a time is allowed, about the current position.
+func (m GameModel) handleProposalMsg(msg ProposalMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves()}
+	proposal := msg.Proposal
+
+	if !slices.Contains(m.seats(), msg.Source) || proposal.Proposer != msg.Source {
+		m.err = fmt.Errorf("ignored %s proposal from %s: not a player", proposal.Kind, msg.Source)
+		return m, tea.Batch(cmds...)
+	}
+
+	if !m.isRunning() {
+		return m, tea.Batch(cmds...)
+	}
+
+	responders := m.responders(proposal.Kind, proposal.Proposer)
+
+	if m.isNegotiating() && m.negotiation.Proposal.ID == proposal.ID {
+		return m, tea.Batch(cmds...)
+	}
+
+	if m.isNegotiating() || proposal.Ply != m.plyCount() {
+		if slices.Contains(responders, m.network.Me()) {
+			payload, _ := json.Marshal(multiplayer.ProposalAnswer{ID: proposal.ID, Accepted: false})
+			m.network.Send(msg.Source, []byte(string(multiplayer.AnswerGameMessage)), payload)
+		}
+		return m, tea.Batch(cmds...)
+	}
+
+	// Who does not propose waits the result a bit longer than the proposer
+	m.negotiation = multiplayer.NewNegotiation(proposal, responders, time.Now().Add(proposalTimeout+peerTimeout))
+
+	// Claims are answered by the rules, not by the players
+	if proposal.Kind == multiplayer.ClaimProposal {
+		err := m.validateClaim(proposal.Method)
+		m, _ = m.answerProposal(err == nil)
+		if err != nil {
+			m.err = fmt.Errorf("refused claim of %s: %v", msg.Source, err)
+		}
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+func (m GameModel) handleProposalAnswerMsg(msg ProposalAnswerMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves()}
+
+	if !m.isNegotiating() || m.negotiation.Proposal.ID != msg.Answer.ID {
+		return m, tea.Batch(cmds...)
+	}
+
+	if err := m.negotiation.Answer(msg.Source, msg.Answer.Accepted); err != nil {
+		m.err = err
+		return m, tea.Batch(cmds...)
+	}
+
+	if m.negotiation.Proposal.Proposer == m.network.Me() {
+		var cmd tea.Cmd
+		m, cmd = m.resolveProposal(time.Now())
+		cmds = append(cmds, cmd)
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// For `ProposalResultGameMessage` from multiplayer it applies the result
+// decided by the proposer
+func (m GameModel) handleProposalResultMsg(msg ProposalResultMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves()}
+
+	if !m.isNegotiating() || m.negotiation.Proposal.ID != msg.Result.ID || m.negotiation.Proposal.Proposer != msg.Source {
+		return m, tea.Batch(cmds...)
+	}
+
+	var cmd tea.Cmd
+	m, cmd = m.closeProposal(msg.Result.Status)
+	cmds = append(cmds, cmd)
+
+	return m, tea.Batch(cmds...)
+}
+
+// Checks the pending proposal every game tick: the proposer closes it when the
+// time is over, the others forget it if the result never arrives
+func (m GameModel) checkProposal(now time.Time) (GameModel, tea.Cmd) {
+	if !m.isNegotiating() {
+		return m, nil
+	}
+
+	if m.negotiation.Proposal.Proposer == m.network.Me() {
+		return m.resolveProposal(now)
+	}
+
+	if m.negotiation.Status(now) == multiplayer.ExpiredProposal {
+		m.negotiation = nil
+	}
+
+	return m, nil
+}
+
+// The proposer decides the result once everyone answered or the time is over,
+// and tells it to the other peers
+func (m GameModel) resolveProposal(now time.Time) (GameModel, tea.Cmd) {
+	status := m.negotiation.Status(now)
+	if status == multiplayer.PendingProposal {
+		return m, nil
+	}
+
+	payload, err := json.Marshal(multiplayer.ProposalResult{ID: m.negotiation.Proposal.ID, Status: status})
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	m.network.SendAll([]byte(string(multiplayer.ProposalResultGameMessage)), payload)
+
+	return m.closeProposal(status)
+}
+
+// Closes the negotiation applying an accepted proposal. The proposer reports
+// the new outcome of the game.
+func (m GameModel) closeProposal(status multiplayer.ProposalStatus) (GameModel, tea.Cmd) {
+	proposal := m.negotiation.Proposal
+	m.negotiation = nil
+	m.notice = fmt.Sprintf("The %s proposal of %s has been %s", proposal.Kind, m.peerName(proposal.Proposer), status)
+
+	if status != multiplayer.AcceptedProposal {
+		return m, nil
+	}
+
+	isProposer := proposal.Proposer == m.network.Me()
+
+	switch proposal.Kind {
+	case multiplayer.DrawProposal:
+		if isProposer {
+			return m, m.endGame(chess.Draw.String(), chess.DrawOffer.String(), true)
+		}
+	case multiplayer.ResignProposal:
+		if isProposer {
+			return m, m.forfeit(proposal.Proposer, chess.Resignation.String())
+		}
+	case multiplayer.TakebackProposal:
+		return m.takeback(proposal), m.updateMovesListCmd()
+	case multiplayer.ClaimProposal:
+		return m.applyClaim(proposal)
+	}
+
+	return m, nil
+}
+
+// Undoes the last move of the team of the proposer and gives the turn back to
+// the seat which played it
+func (m GameModel) takeback(proposal multiplayer.Proposal) GameModel {
+	ply := len(m.chessGame.Moves())
+	if ply != proposal.Ply {
+		m.err = fmt.Errorf("can't take back: %d moves were played after the request", ply-proposal.Ply)
+		return m
+	}
+
+	plies := m.takebackPlies(proposal.Proposer)
+	game, err := m.chessGame.Rollback(ply - plies)
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	m.chessGame = game
+	m.undoClock(plies)
+	m.pendingMove = ""
+	m.premoves = nil
+	m.dropQueuedMoves(len(m.chessGame.Moves()))
+	m.turn = m.seatAtPly(len(m.chessGame.Moves()))
+	m.recordTurn(proposal.Proposer, m.turn)
+
+	return m
+}
+
+// Returns the line about the pending proposal or the result of the last one
+func (m GameModel) renderNegotiation() string {
+	if !m.isNegotiating() {
+		return altCodeStyle.Render(m.notice)
+	}
+
+	proposal := m.negotiation.Proposal
+	remaining := max(time.Until(m.negotiation.Deadline()), 0).Truncate(time.Second)
+
+	return altCodeStyle.Render(fmt.Sprintf("%s %s (%s)",
+		m.peerName(proposal.Proposer),
+		proposalDescriptions[proposal.Kind],
+		remaining,
+	))
+}
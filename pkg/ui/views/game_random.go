@@ -0,0 +1,146 @@
+package views
+
+import (
+	"slices"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/notnil/chess"
+)
+
+// Catch for `CommitGameMessage` message from multiplayer
+type PeerCommitMsg struct {
+	Source     p2p.NetworkID
+	Commitment []byte
+}
+
+// Catch for `RevealGameMessage` message from multiplayer
+type PeerRevealMsg struct {
+	Source p2p.NetworkID
+	Secret []byte
+}
+
+// Returns true if the turns or the Chess960 starting position are picked from
+// the shared seed
+func (m GameModel) usesSharedSeed() bool {
+	return m.game != nil && (m.turnStrategy().UsesSeed() || m.game.Chess960)
+}
+
+// Sends the commitment of the local secret to everyone
+func (m GameModel) sendCommitment() {
+	if m.entropy == nil {
+		return
+	}
+	m.network.SendAll([]byte(string(multiplayer.CommitGameMessage)), m.entropy.Commitment())
+}
+
+// Reveals the local secret once every seat has committed to its own
+func (m GameModel) revealSecret() GameModel {
+	if m.entropy == nil || !m.ready[m.network.Me()] {
+		return m
+	}
+
+	if err := m.entropy.RevealSecret(m.network, m.seats()); err != nil {
+		m.err = err
+	}
+
+	return m
+}
+
+func (m GameModel) handlePeerCommitMsg(msg PeerCommitMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves()}
+
+	if m.entropy == nil {
+		return m, tea.Batch(cmds...)
+	}
+
+	if err := m.entropy.ReceiveCommitment(m.network, msg.Source, msg.Commitment); err != nil {
+		m.err = err
+		return m, tea.Batch(cmds...)
+	}
+
+	return m.revealSecret(), tea.Batch(cmds...)
+}
+
+func (m GameModel) handlePeerRevealMsg(msg PeerRevealMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd()}
+
+	if m.entropy == nil {
+		return m, tea.Batch(cmds...)
+	}
+
+	seed, err := m.entropy.ReceiveSecret(msg.Source, msg.Secret, m.seats())
+	if err != nil {
+		m.err = err
+		return m, tea.Batch(cmds...)
+	}
+
+	if m.seed == nil && seed != nil {
+		m.seed = seed
+
+		var cmd tea.Cmd
+		m, cmd = m.drawStartPosition()
+		cmds = append(cmds, cmd)
+	}
+
+	return m.defineFirstTurn(), tea.Batch(cmds...)
+}
+
+// Returns the seats which can move at the `ply`-th move
+func (m GameModel) turnCandidates(ply int) []p2p.NetworkID {
+	color := m.chessGame.Positions()[0].Turn()
+	if ply%2 == 1 {
+		color = color.Other()
+	}
+
+	var candidates []p2p.NetworkID
+	for _, seat := range m.seats() {
+		if m.seatColor(seat) == color {
+			candidates = append(candidates, seat)
+		}
+	}
+
+	return candidates
+}
+
+// Saves the turn assigned by `source` for the current ply, so it can be
+// audited at the end of the game
+func (m *GameModel) recordTurn(source p2p.NetworkID, seat p2p.NetworkID) {
+	if !m.usesSharedSeed() {
+		return
+	}
+
+	record := multiplayer.TurnRecord{Ply: len(m.chessGame.Moves()), Seat: seat, Source: source}
+	if n := len(m.turnRecords); n > 0 && m.turnRecords[n-1] == record {
+		return
+	}
+
+	m.turnRecords = append(m.turnRecords, record)
+}
+
+// Forgets the turns assigned after the `ply`-th move, which has been rolled
+// back. The turn of the `ply`-th move is already recorded.
+func (m *GameModel) trimTurns(ply int) {
+	m.turnRecords = slices.DeleteFunc(m.turnRecords, func(record multiplayer.TurnRecord) bool {
+		return record.Ply > ply
+	})
+}
+
+// Checks the full turn sequence against the shared seed
+func (m GameModel) auditTurns() error {
+	return multiplayer.AuditTurns(m.turnStrategy(), m.turnRecords, m.turnContext)
+}
+
+// Returns a line with the result of the turns audit
+func (m GameModel) renderTurnsAudit() string {
+	if !m.usesSharedSeed() || m.game.Outcome == chess.NoOutcome.String() {
+		return ""
+	}
+
+	if err := m.auditTurns(); err != nil {
+		return errorStyle.Render("Turns audit failed: " + err.Error())
+	}
+
+	return "Turns audit: verified"
+}
@@ -0,0 +1,122 @@
+package views
+
+import (
+	"errors"
+	"time"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// Interval used to check if all the peer links are up
+	readyPollInterval = 200 * time.Millisecond
+
+	// Interval used to announce again the readiness until the first turn is
+	// defined
+	readyAnnounceInterval = time.Second
+
+	// Time to wait for all the peer links before giving up
+	readyTimeout = 2 * time.Minute
+)
+
+// Catch for `ReadyGameMessage` message from multiplayer
+type PeerReadyMsg p2p.NetworkID
+
+// PeersConnectedMsg is sent when all the links to the other peers are up.
+type PeersConnectedMsg struct{}
+
+// AnnounceReadyMsg is sent to announce the readiness again.
+type AnnounceReadyMsg struct{}
+
+// Waits until all the links to the other peers are up
+func (m GameModel) waitPeersCmd() tea.Cmd {
+	return func() tea.Msg {
+		deadline := time.Now().Add(readyTimeout)
+		for !m.network.AllPeersConnected() {
+			if time.Now().After(deadline) {
+				return errors.New("can't reach all the players")
+			}
+			time.Sleep(readyPollInterval)
+		}
+
+		return PeersConnectedMsg{}
+	}
+}
+
+func (m GameModel) announceReadyCmd() tea.Cmd {
+	return tea.Tick(readyAnnounceInterval, func(time.Time) tea.Msg {
+		return AnnounceReadyMsg{}
+	})
+}
+
+// All the links of this peer are up: tell everyone that this seat is ready.
+func (m GameModel) handlePeersConnectedMsg() (GameModel, tea.Cmd) {
+	m.ready[m.network.Me()] = true
+	m.network.SendAll([]byte(string(multiplayer.ReadyGameMessage)), []byte(m.network.Me()))
+	m.sendPublicKey()
+	m.sendCommitment()
+
+	return m.revealSecret().defineFirstTurn(), m.announceReadyCmd()
+}
+
+// Messages can be lost if a peer is not listening yet, so the readiness is
+// announced until the first turn arrives and, in random mode, until the shared
+// seed is known.
+func (m GameModel) handleAnnounceReadyMsg() (GameModel, tea.Cmd) {
+	if m.turn != p2p.EmptyNetworkID && (!m.usesSharedSeed() || m.seed != nil) {
+		return m, nil
+	}
+
+	m.network.SendAll([]byte(string(multiplayer.ReadyGameMessage)), []byte(m.network.Me()))
+	m.sendPublicKey()
+	m.sendCommitment()
+
+	return m, m.announceReadyCmd()
+}
+
+func (m GameModel) handlePeerReadyMsg(source p2p.NetworkID) (GameModel, tea.Cmd) {
+	m.ready[source] = true
+
+	// The peer has probably lost the first turn definition
+	if m.network.Me() == m.playerPeer(1) && m.turn != p2p.EmptyNetworkID {
+		m.err = m.network.Send(source, []byte(string(multiplayer.DefineTurnMessage)), []byte(string(m.turn)))
+	}
+
+	return m.defineFirstTurn(), tea.Batch(m.getMoves(), m.updateMovesListCmd())
+}
+
+// Returns true if every seat of the game is ready
+func (m GameModel) allSeatsReady() bool {
+	for _, seat := range m.seats() {
+		if !m.ready[seat] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// The first player decides the first turn only after every seat is ready and
+// has sent its signing key
+func (m GameModel) defineFirstTurn() GameModel {
+	if m.network.Me() != m.playerPeer(1) || m.turn != p2p.EmptyNetworkID || !m.allSeatsReady() {
+		return m
+	}
+
+	// Moves are only accepted with the key of their sender
+	if !m.allKeysKnown() {
+		return m
+	}
+
+	if m.usesSharedSeed() && m.seed == nil {
+		return m
+	}
+
+	m.turn = m.turnStrategy().PickTurn(m.turnContext(0))
+	m.recordTurn(m.network.Me(), m.turn)
+	m.network.SendAll([]byte(string(multiplayer.DefineTurnMessage)), []byte(string(m.turn)))
+
+	return m
+}
@@ -1,78 +1,190 @@
 package views
 
 import (
+	"encoding/json"
 	"fmt"
-	"strings"
 	"time"
 
-	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/bughouse"
 	"github.com/boozec/rahanna/pkg/p2p"
 	"github.com/boozec/rahanna/pkg/ui/multiplayer"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// Time to wait for the snapshots of all the peers before restoring the game
+// with the received ones
+const restoreTimeout = 5 * time.Second
+
 // Catch for `RestoreGameMessage` message from multiplayer
 type SendRestoreMsg p2p.NetworkID
 
 // Catch for `RestoreAckGameMessage` message from multiplayer
-type RestoreMoves string
+type RestoreSnapshotMsg struct {
+	Source   p2p.NetworkID
+	Snapshot multiplayer.GameSnapshot
+}
+
+// RestoreTimeoutMsg is sent when the peers took too long to send their snapshot.
+type RestoreTimeoutMsg struct{}
+
+// Returns the full state of the local game
+func (m GameModel) snapshot() multiplayer.GameSnapshot {
+	snapshot := multiplayer.NewSnapshot(m.chessGame, m.turn, m.seed)
+	snapshot.Turns = m.turnRecords
+
+	if m.bughouse != nil {
+		snapshot.Bughouse = m.bughouse.History()
+	}
+
+	if m.clock != nil {
+		state := m.clock.State(time.Now())
+		snapshot.Clock = &state
+	}
+
+	if m.signer != nil {
+		snapshot.Key = m.signer.PublicKey()
+	}
+
+	return snapshot
+}
 
 // For `RestoreGameMessage` from multiplayer it fixes the peer with the new
-// address and sends back an ACK to the peer' sender
-func (m GameModel) handleSendRestoreMsg(source p2p.NetworkID) tea.Cmd {
+// address and sends back the game snapshot to the peer' sender
+func (m GameModel) handleSendRestoreMsg(source p2p.NetworkID) (GameModel, tea.Cmd) {
 	_ = m.getGame()()
 
-	peers := map[int]string{
-		1: m.game.IP1,
-		2: m.game.IP2,
+	m.connectPeers()
+
+	// The restoring peer signs its moves with a new key
+	delete(m.publicKeys, source)
+
+	payload, err := json.Marshal(m.snapshot())
+	if err != nil {
+		m.err = err
+		return m, m.getMoves()
 	}
 
-	if m.game.Type == database.PairGameType {
-		peers[3] = m.game.IP3
-		peers[4] = m.game.IP4
+	// Wait for the new link to the restoring peer instead of losing the message
+	sendSnapshot := func() tea.Msg {
+		deadline := time.Now().Add(readyTimeout)
+		for !m.network.IsConnected(source) {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("can't reach %s to restore its game", source)
+			}
+			time.Sleep(readyPollInterval)
+		}
+
+		return m.network.Send(source, []byte(string(multiplayer.RestoreAckGameMessage)), payload)
 	}
 
-	myPlayerNum := -1
-	switch m.network.Me() {
-	case m.playerPeer(1):
-		myPlayerNum = 1
-	case m.playerPeer(2):
-		myPlayerNum = 2
-	case m.playerPeer(3):
-		myPlayerNum = 3
-	case m.playerPeer(4):
-		myPlayerNum = 4
+	return m, tea.Batch(m.getMoves(), sendSnapshot)
+}
+
+// Asks every peer for its snapshot of the game
+func (m GameModel) handleRestoreGameMsg() (GameModel, tea.Cmd) {
+	m.restore = false
+	m.restoring = true
+	m.restoreSnapshots = make(map[p2p.NetworkID]multiplayer.GameSnapshot)
+	m.network.SendAll([]byte(string(multiplayer.RestoreGameMessage)), []byte(m.network.Me()))
+	m.sendPublicKey()
+
+	return m, tea.Tick(restoreTimeout, func(time.Time) tea.Msg {
+		return RestoreTimeoutMsg{}
+	})
+}
+
+// Verifies a received snapshot and restores the game once every other seat has
+// answered
+func (m GameModel) handleRestoreSnapshotMsg(msg RestoreSnapshotMsg) (GameModel, tea.Cmd) {
+	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd()}
+
+	if !m.restoring {
+		return m, tea.Batch(cmds...)
 	}
 
-	// Add all peers to every other peer
-	for playerNum, ip := range peers {
-		if playerNum != myPlayerNum && ip != "" {
-			m.network.AddPeer(m.playerPeer(playerNum), ip)
-		}
+	if _, err := msg.Snapshot.Replay(); err != nil {
+		m.err = fmt.Errorf("invalid snapshot from %s: %v", msg.Source, err)
+		return m, tea.Batch(cmds...)
 	}
 
-	// FIXME: add a loading modal
-	time.Sleep(2 * time.Second)
+	m.restoreSnapshots[msg.Source] = msg.Snapshot
 
-	payload := ""
+	// Spectators which joined late know the seats only from their snapshots
+	if len(msg.Snapshot.Key) > 0 {
+		m, _ = m.storePublicKey(msg.Source, msg.Snapshot.Key)
+	}
 
-	for _, move := range m.chessGame.Moves() {
-		payload += fmt.Sprintf("%s\n", move.String())
+	// Spectators wait for every seat, players for every other seat
+	expected := len(m.seats())
+	if !m.isSpectator() {
+		expected--
 	}
 
-	m.err = m.network.Send(source, []byte(string(multiplayer.RestoreAckGameMessage)), []byte(payload))
-	m.err = m.network.Send(source, []byte(string(multiplayer.DefineTurnMessage)), []byte(string(m.turn)))
+	if len(m.restoreSnapshots) == expected {
+		m = m.applySnapshots()
+	}
 
-	return nil
+	return m, tea.Batch(cmds...)
 }
 
-// Restores the moves for `m.chessGame`
-func (m *GameModel) handleRestoreMoves(msg RestoreMoves) tea.Cmd {
-	moves := strings.Split(string(msg), "\n")
-	for _, move := range moves {
-		m.chessGame.MoveStr(move)
+// Restores the game with the snapshots received so far, or asks for them again
+// if none arrived
+func (m GameModel) handleRestoreTimeoutMsg() (GameModel, tea.Cmd) {
+	if !m.restoring {
+		return m, nil
 	}
 
-	cmds := []tea.Cmd{m.getMoves(), m.updateMovesListCmd()}
-	return tea.Batch(cmds...)
+	if len(m.restoreSnapshots) == 0 {
+		return m.handleRestoreGameMsg()
+	}
+
+	return m.applySnapshots(), m.updateMovesListCmd()
+}
+
+// Restores `m.chessGame` from the longest history consistent with all the
+// received snapshots
+func (m GameModel) applySnapshots() GameModel {
+	var snapshots []multiplayer.GameSnapshot
+	for _, snapshot := range m.restoreSnapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+
+	m.restoring = false
+
+	snapshot, err := multiplayer.SelectSnapshot(snapshots)
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	game, err := snapshot.Replay()
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	if m.isBughouse() {
+		match, err := bughouse.Replay(snapshot.Bughouse)
+		if err != nil {
+			m.err = err
+			return m
+		}
+		m.bughouse = match
+	}
+
+	m.chessGame = game
+	m.queuedMoves = nil
+	m.turn = snapshot.Turn
+	m.seed = snapshot.Seed
+	m.turnRecords = snapshot.Turns
+
+	if m.clock != nil && snapshot.Clock != nil {
+		m.clock.Restore(*snapshot.Clock, time.Now())
+	}
+
+	if m.seatColor(m.turn) != m.chessGame.Position().Turn() {
+		m.err = fmt.Errorf("restored turn of %s does not match the side to move", m.turn)
+	}
+
+	return m
 }
@@ -0,0 +1,68 @@
+package views
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// Returns the model of `me` in the single game `game`, whose network never
+// dials anyone
+func newTestGameModel(t *testing.T, me p2p.NetworkID, spectator bool) GameModel {
+	t.Chdir(t.TempDir())
+
+	network := multiplayer.NewGameNetwork(string(me), "127.0.0.1:0", p2p.DefaultHandshake, p2p.DefaultHandshake, zap.NewNop())
+	if spectator {
+		network = multiplayer.NewSpectatorNetwork(string(me), "127.0.0.1:0", zap.NewNop())
+	}
+
+	m := NewGameModel(80, 24, 1, network, false)
+	m.game = &database.Game{ID: 1, Name: "game", Type: database.SingleGameType}
+
+	return m
+}
+
+// TestSpectatorJoinsLate tests that a spectator which joins after the first
+// move gets the signing keys of the seats with their snapshots and accepts the
+// next moves.
+func TestSpectatorJoinsLate(t *testing.T) {
+	seats := []GameModel{newTestGameModel(t, "game-1", false), newTestGameModel(t, "game-2", false)}
+	for i := range seats {
+		assert.NoError(t, seats[i].chessGame.MoveStr("e2e4"))
+		seats[i].turn = "game-2"
+	}
+
+	spectator := newTestGameModel(t, "game-s1", true)
+	spectator, _ = spectator.handleRestoreGameMsg()
+	for _, seat := range seats {
+		spectator, _ = spectator.handleRestoreSnapshotMsg(RestoreSnapshotMsg{Source: seat.network.Me(), Snapshot: seat.snapshot()})
+	}
+	assert.NoError(t, spectator.err)
+	assert.Len(t, spectator.chessGame.Moves(), 1)
+
+	now := time.Now()
+	spectator, _ = spectator.handleChessMoveMsg(ChessMoveMsg{
+		Source:     "game-2",
+		Move:       "e7e5",
+		Signed:     seats[1].signer.SignMove("e7e5", 1, now),
+		ReceivedAt: now,
+	})
+	assert.NoError(t, spectator.err)
+	assert.Len(t, spectator.chessGame.Moves(), 2)
+
+	// A move signed with another key is still refused
+	spectator.turn = "game-1"
+	spectator, _ = spectator.handleChessMoveMsg(ChessMoveMsg{
+		Source:     "game-1",
+		Move:       "g1f3",
+		Signed:     seats[1].signer.SignMove("g1f3", 2, now),
+		ReceivedAt: now,
+	})
+	assert.Error(t, spectator.err)
+	assert.Len(t, spectator.chessGame.Moves(), 2)
+}
@@ -0,0 +1,79 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Returns the strategy which picks the seat of each move. Unknown strategies
+// fall back to the sequential one.
+func (m GameModel) turnStrategy() multiplayer.TurnStrategy {
+	if m.game != nil {
+		if strategy, err := multiplayer.NewStrategy(string(m.game.MoveChoose)); err == nil {
+			return strategy
+		}
+	}
+
+	return multiplayer.AlternateStrategy{Every: 1}
+}
+
+// Returns what the strategy knows about the `ply`-th move
+func (m GameModel) turnContext(ply int) multiplayer.TurnContext {
+	return multiplayer.TurnContext{
+		Ply:        ply,
+		Candidates: m.turnCandidates(ply),
+		Seed:       m.seed,
+		Ratings:    m.seatRatings(),
+	}
+}
+
+// Returns the rating of the player behind each seat when it took the seat, so
+// that every peer weights the turns with the same ratings
+func (m GameModel) seatRatings() map[p2p.NetworkID]int {
+	ratings := make(map[p2p.NetworkID]int)
+	if m.game == nil {
+		return ratings
+	}
+
+	for i, rating := range []int{m.game.Rating1, m.game.Rating2, m.game.Rating3, m.game.Rating4} {
+		if rating > 0 {
+			ratings[m.playerPeer(i+1)] = rating
+		}
+	}
+
+	return ratings
+}
+
+// Returns the teammate who can get the move from us, if any
+func (m GameModel) handOverTarget() p2p.NetworkID {
+	if m.game == nil || !m.turnStrategy().CanHandOver() || m.turn != m.network.Me() || m.isNegotiating() {
+		return p2p.EmptyNetworkID
+	}
+
+	ctx := m.turnContext(len(m.chessGame.Moves()))
+	for _, teammate := range m.teammates() {
+		if m.turnStrategy().CheckTurn(ctx, m.network.Me(), teammate) == nil {
+			return teammate
+		}
+	}
+
+	return p2p.EmptyNetworkID
+}
+
+// Hands the current move over to a teammate
+func (m GameModel) handOverTurn() (GameModel, tea.Cmd) {
+	teammate := m.handOverTarget()
+	if teammate == p2p.EmptyNetworkID || !m.isRunning() {
+		return m, nil
+	}
+
+	m.turn = teammate
+	m.recordTurn(m.network.Me(), m.turn)
+	m.network.SendAll([]byte(string(multiplayer.DefineTurnMessage)), []byte(string(m.turn)))
+	m.notice = fmt.Sprintf("Move handed over to %s", m.peerName(teammate))
+
+	return m, m.updateMovesListCmd()
+}
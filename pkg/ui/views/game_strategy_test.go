@@ -0,0 +1,26 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSeatRatings tests that the turns are weighted with the ratings the
+// players had when they took their seats.
+func TestSeatRatings(t *testing.T) {
+	m := newTestGameModel(t, "game-1", false)
+	m.game = &database.Game{
+		Name:    "game",
+		Type:    database.PairGameType,
+		Player1: database.User{Rating: 1200},
+		Player2: &database.User{Rating: 1300},
+		Rating1: 1800,
+		Rating2: 1600,
+		Rating3: 1400,
+	}
+
+	assert.Equal(t, map[p2p.NetworkID]int{"game-1": 1800, "game-2": 1600, "game-3": 1400}, m.seatRatings())
+}
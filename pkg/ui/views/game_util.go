@@ -2,10 +2,17 @@ package views
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/chess960"
 	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/rules"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
 )
 
 func (m GameModel) handleWindowSizeMsg(msg tea.WindowSizeMsg) (GameModel, tea.Cmd) {
@@ -13,6 +20,10 @@ func (m GameModel) handleWindowSizeMsg(msg tea.WindowSizeMsg) (GameModel, tea.Cm
 	m.height = msg.Height
 	listWidth := m.width / 4
 	m.availableMovesList.SetSize(listWidth, m.height/2)
+	m.chatView.Width = getFormWidth(m.width) - 4
+	m.chatInput.Width = getFormWidth(m.width) - 6
+	m.moveInput.Width = getFormWidth(m.width) - 10
+	m.refreshChat()
 	return m, m.updateMovesListCmd()
 }
 
@@ -31,12 +42,209 @@ func (m GameModel) isMyTurn() bool {
 		return false
 	}
 
+	// Nobody moves before the starting position is drawn
+	if m.usesSharedSeed() && m.seed == nil {
+		return false
+	}
+
+	// Every player moves on its own board in bughouse
+	if m.isBughouse() {
+		return m.isMyBughouseTurn()
+	}
+
+	// Both teammates choose the move in consultation
+	if m.isConsulting() {
+		return m.turn != p2p.EmptyNetworkID && m.seatColor(m.network.Me()) == m.seatColor(m.turn)
+	}
+
 	return m.network.Me() == m.turn
 }
 
+// Returns the number of moves played, on every board
+func (m GameModel) plyCount() int {
+	if m.bughouse != nil {
+		return len(m.bughouse.History())
+	}
+	return len(m.chessGame.Moves())
+}
+
 func (m GameModel) playerPeer(n int) p2p.NetworkID {
 	if m.game == nil {
 		return p2p.EmptyNetworkID
 	}
 	return p2p.NetworkID(fmt.Sprintf("%s-%d", m.game.Name, n))
 }
+
+// Returns the network ID of a spectator of the game
+func (m GameModel) spectatorPeer(spectator database.Spectator) p2p.NetworkID {
+	if m.game == nil {
+		return p2p.EmptyNetworkID
+	}
+	return p2p.NetworkID(fmt.Sprintf("%s-s%d", m.game.Name, spectator.ID))
+}
+
+// Returns true if the local peer only watches the game
+func (m GameModel) isSpectator() bool {
+	return m.network.Role() == multiplayer.SpectatorRole
+}
+
+// Adds every other seat to the local network. Players also add the
+// spectators, so they receive moves and turns.
+func (m GameModel) connectPeers() {
+	peers := map[int]string{
+		1: m.game.IP1,
+		2: m.game.IP2,
+	}
+
+	if m.game.Type.IsTeam() {
+		peers[3] = m.game.IP3
+		peers[4] = m.game.IP4
+	}
+
+	for playerNum, ip := range peers {
+		if m.playerPeer(playerNum) != m.network.Me() && ip != "" {
+			m.network.AddPeer(m.playerPeer(playerNum), ip)
+		}
+	}
+
+	if m.isSpectator() {
+		return
+	}
+
+	for _, spectator := range m.game.Spectators {
+		if spectator.IP != "" {
+			m.network.AddSpectator(m.spectatorPeer(spectator), spectator.IP)
+		}
+	}
+}
+
+// Returns the network IDs of all the seats of the game
+func (m GameModel) seats() []p2p.NetworkID {
+	seats := []p2p.NetworkID{m.playerPeer(1), m.playerPeer(2)}
+	if m.game != nil && m.game.Type.IsTeam() {
+		seats = append(seats, m.playerPeer(3), m.playerPeer(4))
+	}
+	return seats
+}
+
+// Returns the color played by the team of `peer`. Players 1 and 3 are white,
+// players 2 and 4 are black.
+func (m GameModel) seatColor(peer p2p.NetworkID) chess.Color {
+	switch peer {
+	case m.playerPeer(1), m.playerPeer(3):
+		return chess.White
+	case m.playerPeer(2), m.playerPeer(4):
+		return chess.Black
+	}
+	return chess.NoColor
+}
+
+// Returns the last move of `game` and the square of the king in check, or
+// `chess.NoSquare`
+func gameHighlights(game *chess960.Game) (*chess.Move, chess.Square) {
+	var lastMove *chess.Move
+	if moves := game.Moves(); len(moves) > 0 {
+		lastMove = moves[len(moves)-1]
+	}
+
+	return lastMove, kingInCheck(game.Position())
+}
+
+// Returns the square of the king of the player to move if it is in check, or
+// `chess.NoSquare`
+func kingInCheck(position *chess.Position) chess.Square {
+	squares := position.Board().SquareMap()
+	if !rules.InCheck(squares, position.Turn()) {
+		return chess.NoSquare
+	}
+
+	for sq, piece := range squares {
+		if piece == chess.NewPiece(chess.King, position.Turn()) {
+			return sq
+		}
+	}
+
+	return chess.NoSquare
+}
+
+// Creates the list of the moves which can be played
+func createMovesList(width, height int) list.Model {
+	listDelegate := list.NewDefaultDelegate()
+	listDelegate.ShowDescription = false
+	listDelegate.Styles.SelectedTitle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(highlightColor).
+		Foreground(highlightColor).
+		Padding(0, 0, 0, 1)
+
+	moveList := list.New([]list.Item{}, listDelegate, width/4, height/2)
+	moveList.Styles.Title = lipgloss.NewStyle().
+		Background(highlightColor).
+		Foreground(lipgloss.Color("230")).
+		Padding(0, 1)
+	moveList.DisableQuitKeybindings()
+
+	return moveList
+}
+
+// Returns the items of the moves list for the legal moves of `game`
+func moveItems(game *chess960.Game) []list.Item {
+	var items []list.Item
+	for _, move := range game.ValidMoves() {
+		var promo string
+		if move.Promo().String() != "" {
+			promo = " " + move.Promo().String()
+		}
+		title := fmt.Sprintf("%s → %s%s", move.S1().String(), move.S2().String(), promo)
+		if castling := game.CastlingName(move); castling != "" {
+			title = fmt.Sprintf("%s (%s)", castling, title)
+		}
+		items = append(items, item{title: title, move: move.String()})
+	}
+
+	return items
+}
+
+// Lines of the notation pane
+const notationLines = 10
+
+// Returns the moves played in SAN, two per line. The lines around the first
+// `ply` moves are shown, and the last of them is highlighted if `selected`.
+func renderNotation(notation []string, ply int, selected bool) string {
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("230")).Background(highlightColor)
+
+	var lines []string
+	for i, move := range notation {
+		if selected && i == ply-1 {
+			move = selectedStyle.Render(move)
+		}
+
+		if i%2 == 0 {
+			lines = append(lines, altCodeStyle.Render(fmt.Sprintf("[%d]", i/2))+" "+move)
+		} else {
+			lines[len(lines)-1] += ", " + move
+		}
+	}
+
+	// The line of the last move shown stays in the middle while scrolling,
+	// the first lines are shown before any move
+	end := len(lines)
+	if ply < len(notation) {
+		end = min(max((ply+1)/2+notationLines/2, notationLines), len(lines))
+	}
+	start := max(end-notationLines, 0)
+
+	return strings.Join(lines[start:end], "\n")
+}
+
+// Returns the last lines of the notation, the ones which fit the view
+func lastLines(notation string) string {
+	// TODO: a faster solution withoout strings.Split and strings.Join
+	moves := strings.Split(notation, "\n")
+	start := 0
+	if len(moves) > 10 {
+		start = len(moves) - 10 - 1
+	}
+
+	return strings.Join(moves[start:], "\n")
+}
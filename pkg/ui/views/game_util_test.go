@@ -0,0 +1,37 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRenderNotation tests that the lines around the position shown fit the
+// notation pane.
+func TestRenderNotation(t *testing.T) {
+	var notation []string
+	for i := range 60 {
+		notation = append(notation, fmt.Sprintf("m%d", i))
+	}
+
+	lines := func(ply int, selected bool) []string {
+		return strings.Split(ansi.Strip(renderNotation(notation, ply, selected)), "\n")
+	}
+
+	// Before any move the first lines are shown
+	start := lines(0, true)
+	assert.Len(t, start, notationLines)
+	assert.Equal(t, "[0] m0, m1", start[0])
+
+	// The live game shows the last lines
+	live := lines(len(notation), false)
+	assert.Len(t, live, notationLines)
+	assert.Equal(t, "[29] m58, m59", live[notationLines-1])
+
+	// The line of the move shown stays in the middle
+	middle := lines(31, true)
+	assert.Equal(t, "[15] m30, m31", middle[notationLines/2-1])
+}
@@ -0,0 +1,375 @@
+package views
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/ui/local"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
+)
+
+// The computer chose its move
+type localEngineMoveMsg struct {
+	move string
+	err  error
+}
+
+// LocalGameModel plays an offline game on this terminal: no API, no network.
+type LocalGameModel struct {
+	// UI dimensions
+	width  int
+	height int
+
+	// UI state
+	err  error
+	keys localGameKeyMap
+
+	// Game state
+	record             *local.Game
+	chessGame          *chess960.Game
+	availableMovesList list.Model
+	engine             multiplayer.Engine
+	thinking           bool
+	board              boardModel
+	boardFocused       bool
+}
+
+func NewLocalGameModel(width, height int, record *local.Game) LocalGameModel {
+	m := LocalGameModel{
+		width:              width,
+		height:             height,
+		keys:               defaultLocalGameKeyMap,
+		record:             record,
+		availableMovesList: createMovesList(width, height),
+		board:              newBoardModel(),
+	}
+
+	m.chessGame, m.err = record.Replay()
+	if m.err != nil {
+		m.chessGame = chess960.NewStandardGame()
+	}
+
+	if record.Mode == local.ComputerMode && record.IsOngoing() {
+		if engine, err := newBotEngine(); err != nil {
+			m.err = err
+		} else {
+			m.engine = engine
+		}
+	}
+
+	return m.updateMovesList()
+}
+
+func (m LocalGameModel) Init() tea.Cmd {
+	ClearScreen()
+	return m.thinkCmd()
+}
+
+func (m LocalGameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if exit := handleExit(msg); exit != nil {
+		return m, exit
+	}
+
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.availableMovesList.SetSize(m.width/4, m.height/2)
+	case localEngineMoveMsg:
+		m, cmd = m.handleEngineMoveMsg(msg)
+		cmds = append(cmds, cmd)
+	case tea.KeyMsg:
+		// While filtering the moves, keys are typed in the filter
+		if m.availableMovesList.FilterState() != list.Filtering {
+			var handled bool
+			if m, cmd, handled = m.handleKeyMsg(msg); handled {
+				return m, cmd
+			}
+		}
+
+		// On the board, keys move the cursor instead of the list
+		if m.usesBoard() {
+			var moveStr string
+			if m.board, moveStr = m.board.Update(msg); moveStr != "" {
+				m, cmd = m.playMove(moveStr)
+				cmds = append(cmds, cmd)
+			}
+			return m, tea.Batch(cmds...)
+		}
+	}
+
+	if m.isHumanTurn() {
+		m.availableMovesList, cmd = m.availableMovesList.Update(msg)
+		cmds = append(cmds, cmd)
+
+		if msg, ok := msg.(tea.KeyMsg); ok && msg.Type == tea.KeyEnter && m.availableMovesList.FilterState() != list.Filtering {
+			if selectedItem, ok := m.availableMovesList.SelectedItem().(item); ok {
+				if selectedItem.claim != chess.NoMethod {
+					m = m.claimDraw(selectedItem.claim)
+				} else {
+					m, cmd = m.playMove(selectedItem.move)
+					cmds = append(cmds, cmd)
+				}
+			}
+		}
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// Returns true if a player at the terminal has to move
+func (m LocalGameModel) isHumanTurn() bool {
+	return m.record.IsOngoing() && !m.thinking && m.chessGame.Position().Turn() != m.record.ComputerColor()
+}
+
+// Returns true if the move is chosen on the board instead of the list
+func (m LocalGameModel) usesBoard() bool {
+	return m.boardFocused && m.isHumanTurn()
+}
+
+// Returns the color of the player at the terminal who is acting: the player
+// to move in hotseat, the one facing the computer otherwise
+func (m LocalGameModel) humanColor() chess.Color {
+	if computer := m.record.ComputerColor(); computer != chess.NoColor {
+		return computer.Other()
+	}
+	return m.chessGame.Position().Turn()
+}
+
+func (m LocalGameModel) updateMovesList() LocalGameModel {
+	m.availableMovesList.SetItems(append(claimItems(m.chessGame), moveItems(m.chessGame)...))
+	m.board = m.board.SetPosition(m.chessGame.Position().Board(), m.chessGame.ValidMoves(), m.humanColor())
+	m.availableMovesList.Title = fmt.Sprintf("%s to move", m.chessGame.Position().Turn().Name())
+	m.availableMovesList.Select(0)
+	m.availableMovesList.SetShowFilter(true)
+	m.availableMovesList.SetFilteringEnabled(true)
+	m.availableMovesList.ResetFilter()
+
+	return m
+}
+
+// Saves the game after a change. A failure is shown, but the game goes on.
+func (m LocalGameModel) save() LocalGameModel {
+	m.record.Record(m.chessGame)
+	if err := m.record.Save(); err != nil {
+		m.err = err
+	}
+
+	return m
+}
+
+func (m LocalGameModel) playMove(moveStr string) (LocalGameModel, tea.Cmd) {
+	if err := m.chessGame.MoveStr(moveStr); err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	m.err = nil
+	m = m.save().updateMovesList()
+	cmd := m.thinkCmd()
+
+	return m, cmd
+}
+
+// Asks the computer for its move, if it has to play
+func (m *LocalGameModel) thinkCmd() tea.Cmd {
+	if m.engine == nil || !m.record.IsOngoing() || m.chessGame.Position().Turn() != m.record.ComputerColor() {
+		return nil
+	}
+
+	m.thinking = true
+	engine := m.engine
+	game := m.chessGame.Clone()
+
+	return func() tea.Msg {
+		move, err := engine.BestMove(game)
+		return localEngineMoveMsg{move: move, err: err}
+	}
+}
+
+func (m LocalGameModel) handleEngineMoveMsg(msg localEngineMoveMsg) (LocalGameModel, tea.Cmd) {
+	m.thinking = false
+
+	// The game has been resigned or taken back while the computer was thinking
+	if !m.record.IsOngoing() || m.chessGame.Position().Turn() != m.record.ComputerColor() {
+		return m, nil
+	}
+
+	if msg.err != nil {
+		m.err = msg.err
+		return m, nil
+	}
+
+	return m.playMove(msg.move)
+}
+
+func (m LocalGameModel) claimDraw(method chess.Method) LocalGameModel {
+	if err := m.chessGame.Draw(method); err != nil {
+		m.err = err
+		return m
+	}
+
+	return m.save()
+}
+
+// Ends the game as lost by the player at the terminal
+func (m LocalGameModel) resign() LocalGameModel {
+	outcome := chess.WhiteWon
+	if m.humanColor() == chess.White {
+		outcome = chess.BlackWon
+	}
+
+	m.record.End(outcome, chess.Resignation)
+	if err := m.record.Save(); err != nil {
+		m.err = err
+	}
+
+	return m
+}
+
+// Ends the game with a draw agreed by the players at the terminal
+func (m LocalGameModel) agreeDraw() LocalGameModel {
+	m.record.End(chess.Draw, chess.DrawOffer)
+	if err := m.record.Save(); err != nil {
+		m.err = err
+	}
+
+	return m
+}
+
+// Takes back the last move of the player at the terminal, and the reply of the
+// computer
+func (m LocalGameModel) takeback() LocalGameModel {
+	n := len(m.chessGame.Moves()) - 1
+	if m.record.Mode == local.ComputerMode && m.chessGame.Position().Turn() == m.humanColor() {
+		n--
+	}
+
+	if n < 0 {
+		m.err = fmt.Errorf("no move to take back")
+		return m
+	}
+
+	game, err := m.chessGame.Rollback(n)
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	m.chessGame = game
+	m.err = nil
+
+	return m.save().updateMovesList()
+}
+
+// Stops the engine of the computer, if it runs in its own process
+func (m LocalGameModel) closeEngine() {
+	if closer, ok := m.engine.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+func (m LocalGameModel) View() string {
+	formWidth := getFormWidth(m.width)
+
+	listWidth := formWidth / 4
+	boardWidth := formWidth / 2
+	notationWidth := formWidth - listWidth - boardWidth - 2
+
+	listHeight := m.height / 3
+	boardHeight := m.height / 3
+	notationHeight := m.height - listHeight - boardHeight - 2
+
+	listStyle := lipgloss.NewStyle().Width(listWidth).Height(listHeight).Padding(0, 1)
+	boardStyle := lipgloss.NewStyle().Width(boardWidth).Height(boardHeight).Align(lipgloss.Center).Padding(0, 1)
+	notationStyle := lipgloss.NewStyle().Width(notationWidth).Height(notationHeight).Padding(0, 1)
+
+	var availableMovesListView string
+
+	switch {
+	case !m.record.IsOngoing():
+		outcome := "Draw"
+		switch m.record.Outcome {
+		case chess.WhiteWon.String():
+			outcome = "White won"
+		case chess.BlackWon.String():
+			outcome = "Black won"
+		}
+
+		availableMovesListView = listStyle.Render(
+			lipgloss.JoinVertical(
+				lipgloss.Left,
+				lipgloss.NewStyle().Background(highlightColor).Foreground(lipgloss.Color("230")).Padding(0, 1).MarginBottom(1).Render("Result"),
+				outcome,
+				m.record.Outcome,
+				altCodeStyle.Render(m.record.Method),
+			),
+		)
+	case m.isHumanTurn():
+		m.availableMovesList.SetSize(listWidth, listHeight-2)
+		availableMovesListView = listStyle.Render(m.availableMovesList.View())
+	default:
+		availableMovesListView = listStyle.Render(lipgloss.Place(listWidth, listHeight, lipgloss.Center, lipgloss.Center, "The computer is thinking..."))
+	}
+
+	board := m.board
+	if !m.usesBoard() {
+		board = board.SetPosition(m.chessGame.Position().Board(), nil, m.humanColor())
+	}
+	boardView := board.SetHighlights(gameHighlights(m.chessGame)).SetFocused(m.usesBoard()).View()
+
+	header := "♔ White vs ♚ Black (hotseat)"
+	switch m.record.ComputerColor() {
+	case chess.White:
+		header = "♔ Computer vs ♚ You"
+	case chess.Black:
+		header = "♔ You vs ♚ Computer"
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Center,
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#f1c40f")).Render(header),
+		lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			availableMovesListView,
+			boardStyle.Render(boardView),
+			notationStyle.Render(
+				lipgloss.JoinVertical(
+					lipgloss.Left,
+					lipgloss.NewStyle().Background(highlightColor).Foreground(lipgloss.Color("230")).Padding(0, 1).MarginBottom(1).Render("Moves"),
+					renderNotation(m.chessGame.Notation(), len(m.chessGame.Moves()), false),
+					renderMaterial(m.chessGame, m.board),
+				),
+			),
+		),
+	)
+
+	var errorStr string
+	if m.err != nil {
+		errorStr = m.err.Error()
+	}
+
+	centeredContent := lipgloss.JoinVertical(
+		lipgloss.Center,
+		getLogo(m.width),
+		windowStyle.Width(formWidth).Render(content),
+		errorStyle.Width(formWidth/2).Render(errorStr),
+		lipgloss.NewStyle().MarginTop(2).Render(m.renderNavigationButtons()),
+	)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		centeredContent,
+	)
+}
@@ -0,0 +1,129 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/boozec/rahanna/pkg/ui/local"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// localGameKeyMap defines the key bindings for the offline game view.
+type localGameKeyMap struct {
+	Resign   key.Binding
+	Draw     key.Binding
+	Takeback key.Binding
+	Board    key.Binding
+	Theme    key.Binding
+	Quit     key.Binding
+	Exit     key.Binding
+}
+
+var defaultLocalGameKeyMap = localGameKeyMap{
+	Resign: key.NewBinding(
+		key.WithKeys("A", "a"),
+		key.WithHelp("     A", "Abandon"),
+	),
+	Draw: key.NewBinding(
+		key.WithKeys("D", "d"),
+		key.WithHelp("     D", "Agree a draw"),
+	),
+	Takeback: key.NewBinding(
+		key.WithKeys("T", "t"),
+		key.WithHelp("     T", "Take back"),
+	),
+	Board: key.NewBinding(
+		key.WithKeys("B", "b"),
+		key.WithHelp("     B", "Choose moves on the board"),
+	),
+	Theme: key.NewBinding(
+		key.WithKeys("S", "s"),
+		key.WithHelp("     S", "Switch board theme"),
+	),
+	Quit: key.NewBinding(
+		key.WithKeys("Q", "q"),
+		key.WithHelp("     Q", "Quit"),
+	),
+	Exit: key.NewBinding(
+		key.WithKeys("ctrl+c", "ctrl+C"),
+		key.WithHelp("Ctrl+C", "Exit"),
+	),
+}
+
+// Handles the keys of the view. Returns false for the keys of the moves list.
+func (m LocalGameModel) handleKeyMsg(msg tea.KeyMsg) (LocalGameModel, tea.Cmd, bool) {
+	switch {
+	case key.Matches(msg, m.keys.Resign):
+		if m.record.IsOngoing() {
+			return m.resign(), nil, true
+		}
+	case key.Matches(msg, m.keys.Draw):
+		// The computer never agrees to a draw
+		if m.record.IsOngoing() && m.record.Mode == local.HotseatMode {
+			return m.agreeDraw(), nil, true
+		}
+	case key.Matches(msg, m.keys.Takeback):
+		if m.record.IsOngoing() {
+			return m.takeback(), nil, true
+		}
+	case key.Matches(msg, m.keys.Board):
+		m.boardFocused = !m.boardFocused
+		return m, nil, true
+	case key.Matches(msg, m.keys.Theme):
+		m.board = m.board.NextTheme()
+		return m, nil, true
+	case key.Matches(msg, m.keys.Quit):
+		m.closeEngine()
+		return m, SwitchModelCmd(NewLocalPlayModel(m.width, m.height)), true
+	}
+
+	return m, nil, false
+}
+
+func (m LocalGameModel) renderNavigationButtons() string {
+	var gameKeys []string
+	if m.record.IsOngoing() {
+		gameKeys = append(gameKeys,
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Resign.Help().Key), m.keys.Resign.Help().Desc),
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Takeback.Help().Key), m.keys.Takeback.Help().Desc),
+		)
+
+		if m.record.Mode == local.HotseatMode {
+			gameKeys = append(gameKeys,
+				fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Draw.Help().Key), m.keys.Draw.Help().Desc),
+			)
+		}
+
+		boardDesc := m.keys.Board.Help().Desc
+		if m.boardFocused {
+			boardDesc = "Choose moves from the list"
+		}
+		gameKeys = append(gameKeys, fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Board.Help().Key), boardDesc))
+
+		if m.usesBoard() {
+			gameKeys = append(gameKeys, m.board.renderNavigationButtons())
+		}
+	}
+
+	themeKey := fmt.Sprintf("%s %s (%s)",
+		altCodeStyle.Render(m.keys.Theme.Help().Key),
+		m.keys.Theme.Help().Desc,
+		m.board.ThemeName())
+
+	quitKey := fmt.Sprintf("%s %s",
+		altCodeStyle.Render(m.keys.Quit.Help().Key),
+		m.keys.Quit.Help().Desc)
+
+	exitKey := fmt.Sprintf("%s %s",
+		altCodeStyle.Render(m.keys.Exit.Help().Key),
+		m.keys.Exit.Help().Desc)
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.JoinVertical(lipgloss.Left, gameKeys...),
+		themeKey,
+		quitKey,
+		exitKey,
+	)
+}
@@ -0,0 +1,261 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/ui/local"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
+)
+
+// Offline games shown in the list
+const localGamesShown = 10
+
+// The offline games have been loaded from the local storage
+type localGamesMsg []local.Game
+
+// LocalPlayModel lists the offline games and starts new ones, without the API
+type LocalPlayModel struct {
+	// UI dimensions
+	width  int
+	height int
+
+	// UI state
+	err  error
+	keys localPlayKeyMap
+
+	// Color played by the computer in the new games
+	computer chess.Color
+
+	// The new games start from a random Chess960 position
+	chess960 bool
+
+	games []local.Game
+}
+
+// Keyboard controls
+type localPlayKeyMap struct {
+	StartHotseatGame    key.Binding
+	StartComputerGame   key.Binding
+	ChangeComputerColor key.Binding
+	ChangeStartPosition key.Binding
+	ResumeGame          key.Binding
+	Back                key.Binding
+	Exit                key.Binding
+}
+
+var defaultLocalPlayKeyMap = localPlayKeyMap{
+	StartHotseatGame: key.NewBinding(
+		key.WithKeys("alt+h", "alt+H"),
+		key.WithHelp("Alt+H", "Start a new hotseat play"),
+	),
+	StartComputerGame: key.NewBinding(
+		key.WithKeys("alt+v", "alt+V"),
+		key.WithHelp("Alt+V", "Start a new play vs computer"),
+	),
+	ChangeComputerColor: key.NewBinding(
+		key.WithKeys("alt+c", "alt+C"),
+		key.WithHelp("Alt+C", "Change computer color"),
+	),
+	ChangeStartPosition: key.NewBinding(
+		key.WithKeys("alt+f", "alt+F"),
+		key.WithHelp("Alt+F", "Change starting position"),
+	),
+	ResumeGame: key.NewBinding(
+		key.WithKeys("0", "1", "2", "3", "4", "5", "6", "7", "8", "9"),
+		key.WithHelp("[0-9]", "Resume a game"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("alt+q", "alt+Q"),
+		key.WithHelp("Alt+Q", "Back"),
+	),
+	Exit: key.NewBinding(
+		key.WithKeys("ctrl+c", "ctrl+C"),
+		key.WithHelp("Ctrl+C", "Exit"),
+	),
+}
+
+func NewLocalPlayModel(width, height int) LocalPlayModel {
+	return LocalPlayModel{
+		width:    width,
+		height:   height,
+		keys:     defaultLocalPlayKeyMap,
+		computer: chess.Black,
+	}
+}
+
+// Returns the first model of the online flow: the play model if the user is
+// logged in, the auth model otherwise
+func onlineModel(width, height int) tea.Model {
+	if _, err := os.Stat(".rahannarc"); !errors.Is(err, os.ErrNotExist) {
+		return NewPlayModel(width, height)
+	}
+	return NewAuthModel(width, height)
+}
+
+func (m LocalPlayModel) Init() tea.Cmd {
+	ClearScreen()
+	return loadLocalGames
+}
+
+func loadLocalGames() tea.Msg {
+	games, err := local.List()
+	if err != nil {
+		return err
+	}
+	return localGamesMsg(games)
+}
+
+func (m LocalPlayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if exit := handleExit(msg); exit != nil {
+		return m, exit
+	}
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case localGamesMsg:
+		m.games = msg
+		if len(m.games) > localGamesShown {
+			m.games = m.games[:localGamesShown]
+		}
+	case tea.KeyMsg:
+		return m.handleKeyMsg(msg)
+	case error:
+		m.err = msg
+	}
+
+	return m, nil
+}
+
+func (m LocalPlayModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.StartHotseatGame):
+		return m.startGame(local.HotseatMode)
+	case key.Matches(msg, m.keys.StartComputerGame):
+		return m.startGame(local.ComputerMode)
+	case key.Matches(msg, m.keys.ChangeComputerColor):
+		m.computer = m.computer.Other()
+	case key.Matches(msg, m.keys.ChangeStartPosition):
+		m.chess960 = !m.chess960
+	case key.Matches(msg, m.keys.ResumeGame):
+		idx, _ := strconv.Atoi(msg.String())
+		if idx < len(m.games) {
+			return m, SwitchModelCmd(NewLocalGameModel(m.width, m.height, &m.games[idx]))
+		}
+	case key.Matches(msg, m.keys.Back):
+		return m, SwitchModelCmd(onlineModel(m.width, m.height))
+	}
+
+	return m, nil
+}
+
+func (m LocalPlayModel) startGame(mode local.Mode) (tea.Model, tea.Cmd) {
+	fen := chess960.StandardFEN
+	if m.chess960 {
+		var err error
+		if fen, err = chess960.StartingFEN(rand.IntN(chess960.Positions)); err != nil {
+			m.err = err
+			return m, nil
+		}
+	}
+
+	game, err := local.New(mode, fen, m.computer)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	return m, SwitchModelCmd(NewLocalGameModel(m.width, m.height, game))
+}
+
+// Returns the line of the `i`-th offline game in the list
+func localGameTitle(i int, game local.Game) string {
+	title := "hotseat"
+	if game.Mode == local.ComputerMode {
+		title = fmt.Sprintf("vs computer (%s)", game.ComputerColor().Other().Name())
+	}
+
+	outcome := fmt.Sprintf("%d moves", len(game.Moves))
+	if !game.IsOngoing() {
+		outcome = fmt.Sprintf("%s %s", game.Outcome, game.Method)
+	}
+
+	return fmt.Sprintf("%s%-22s %-28s %s",
+		altCodeStyle.Render(fmt.Sprintf("[%d] ", i)), title, outcome,
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#d35400")).Render(game.UpdatedAt.Format("2006-01-02 15:04")))
+}
+
+func (m LocalPlayModel) View() string {
+	formWidth := getFormWidth(m.width)
+	base := lipgloss.NewStyle().Align(lipgloss.Center).Width(formWidth)
+
+	content := chessBoard
+	if len(m.games) > 0 {
+		lines := []string{"Offline games"}
+		for i, game := range m.games {
+			lines = append(lines, localGameTitle(i, game))
+		}
+		content = strings.Join(lines, "\n")
+	}
+
+	if m.err != nil {
+		content = lipgloss.JoinVertical(
+			lipgloss.Center,
+			errorStyle.Align(lipgloss.Center).Width(formWidth-4).Render(fmt.Sprintf("Error: %v", m.err.Error())),
+			content,
+		)
+	}
+
+	windowContent := windowStyle.Width(formWidth).Render(base.Render(content))
+
+	centeredContent := lipgloss.JoinVertical(
+		lipgloss.Center,
+		getLogo(formWidth),
+		windowContent,
+		lipgloss.NewStyle().MarginTop(2).Render(m.renderNavigationButtons()),
+	)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		centeredContent,
+	)
+}
+
+func (m LocalPlayModel) renderNavigationButtons() string {
+	render := func(binding key.Binding, value string) string {
+		s := fmt.Sprintf("%s %s", altCodeStyle.Render(binding.Help().Key), binding.Help().Desc)
+		if value != "" {
+			s += fmt.Sprintf(" (%s)", value)
+		}
+		return s
+	}
+
+	startPosition := "standard"
+	if m.chess960 {
+		startPosition = "Chess960"
+	}
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		render(m.keys.StartHotseatGame, ""),
+		render(m.keys.StartComputerGame, ""),
+		render(m.keys.ChangeComputerColor, m.computer.Name()),
+		render(m.keys.ChangeStartPosition, startPosition),
+		render(m.keys.ResumeGame, ""),
+		render(m.keys.Back, ""),
+		render(m.keys.Exit, ""),
+	)
+}
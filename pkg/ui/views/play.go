@@ -26,6 +26,34 @@ A B C D E F G H
 `
 )
 
+// Time controls which can be chosen for a new game
+var timeControlPresets = []database.TimeControl{
+	{},
+	{Base: 3 * 60, Increment: 2},
+	{Base: 5 * 60},
+	{Base: 5 * 60, Increment: 3},
+	{Base: 5 * 60, Delay: 5},
+	{Base: 10 * 60, Increment: 5},
+	{Base: 15 * 60, Increment: 10},
+}
+
+// Strategies which can be chosen for a new co-op game
+var moveChoosePresets = []database.MoveChooseType{
+	database.SequentialChooseType,
+	database.AlternateChooseType(2),
+	database.AlternateChooseType(3),
+	database.WeightedChooseType,
+	database.CaptainChooseType,
+}
+
+// Starting positions which can be chosen for a new game
+const (
+	standardStart = iota
+	chess960Start
+	customStart
+	startPositions
+)
+
 type PlayModel struct {
 	// UI dimensions
 	width  int
@@ -35,9 +63,21 @@ type PlayModel struct {
 	err        error
 	keys       playKeyMap
 	namePrompt textinput.Model
+	fenPrompt  textinput.Model
 	page       PlayModelPage
 	isLoading  bool
 	paginator  paginator.Model
+	watching   bool
+
+	// Index of the time control of the new games in `timeControlPresets`
+	timeControl int
+
+	// Index of the strategy of the new co-op games in `moveChoosePresets`
+	moveChoose int
+
+	// Starting position of the new games, with its FEN if it is a custom one
+	startPosition int
+	startFEN      string
 
 	// Game state
 	userID        int
@@ -45,6 +85,17 @@ type PlayModel struct {
 	currentGameId int
 	game          *database.Game
 	network       *multiplayer.GameNetwork
+
+	// Type and strategy of the game created by the user
+	gameType       database.GameType
+	moveChooseType database.MoveChooseType
+
+	// The new game is played against the computer
+	vsComputer bool
+
+	// Computer players seated in the game, which run in this process
+	bots []*multiplayer.VirtualPeer
+
 	games         []database.Game
 	gameToRestore *database.Game
 }
@@ -62,6 +113,7 @@ func NewPlayModel(width, height int) PlayModel {
 		height:     height,
 		keys:       defaultPlayKeyMap,
 		namePrompt: namePrompt,
+		fenPrompt:  createFENPrompt(width),
 		page:       LandingPage,
 		paginator:  p,
 	}
@@ -86,11 +138,17 @@ func (m PlayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handlePlayResponse(msg)
 	case database.Game:
 		return m.handleGameResponse(msg)
+	case botSeatedMsg:
+		return m.handleBotSeated(msg)
+	case watchedGame:
+		return m.handleWatchedGame(msg)
 	case []database.Game:
 		m.userID, m.err = getUserID()
 		return m.handleGamesResponse(msg)
 	case StartGameMsg:
-		return m, SwitchModelCmd(NewGameModel(m.width, m.height+1, m.currentGameId, m.network, m.gameToRestore != nil))
+		game := NewGameModel(m.width, m.height+1, m.currentGameId, m.network, m.gameToRestore != nil)
+		game.bots = m.bots
+		return m, SwitchModelCmd(game)
 	case error:
 		return m.handleError(msg)
 	}
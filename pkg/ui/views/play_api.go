@@ -2,6 +2,7 @@ package views
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -34,6 +35,9 @@ type playResponse struct {
 
 type StartGameMsg struct{}
 
+// A game joined as spectator
+type watchedGame database.Game
+
 func (m *PlayModel) handlePlayResponse(msg playResponse) (tea.Model, tea.Cmd) {
 	m.isLoading = false
 	m.err = nil
@@ -46,6 +50,8 @@ func (m *PlayModel) handlePlayResponse(msg playResponse) (tea.Model, tea.Cmd) {
 	} else {
 		m.playName = msg.Ok.Name
 		m.currentGameId = msg.Ok.GameID
+		m.gameType = database.GameType(msg.Ok.Type)
+		m.moveChooseType = database.MoveChooseType(msg.Ok.MoveChoose)
 		logger, _ := logger.GetLogger()
 
 		var wg sync.WaitGroup
@@ -54,7 +60,7 @@ func (m *PlayModel) handlePlayResponse(msg playResponse) (tea.Model, tea.Cmd) {
 		switch msg.Ok.Type {
 		case string(database.SingleGameType):
 			expectedPeers = 1
-		case string(database.PairGameType):
+		case string(database.PairGameType), string(database.BughouseGameType):
 			expectedPeers = 3
 		default:
 			logger.Fatal("Type not recognized")
@@ -70,11 +76,18 @@ func (m *PlayModel) handlePlayResponse(msg playResponse) (tea.Model, tea.Cmd) {
 			return nil
 		}, p2p.DefaultHandshake, logger)
 
-		return m, func() tea.Msg {
+		waitPeers := func() tea.Msg {
 			wg.Wait()
 
 			return StartGameMsg{}
 		}
+
+		if m.vsComputer {
+			m.isLoading = true
+			return m, tea.Batch(waitPeers, m.addBotCmd())
+		}
+
+		return m, waitPeers
 	}
 
 	return m, nil
@@ -97,7 +110,7 @@ func (m *PlayModel) handleGameResponse(msg database.Game) (tea.Model, tea.Cmd) {
 		switch m.game.Type {
 		case database.SingleGameType:
 			expectedPeers = 1
-		case database.PairGameType:
+		case database.PairGameType, database.BughouseGameType:
 			expectedPeers = 3
 		}
 
@@ -107,7 +120,7 @@ func (m *PlayModel) handleGameResponse(msg database.Game) (tea.Model, tea.Cmd) {
 		switch m.game.Type {
 		case database.SingleGameType:
 			expectedPeers = 0
-		case database.PairGameType:
+		case database.PairGameType, database.BughouseGameType:
 			expectedPeers = 2
 		}
 
@@ -152,6 +165,33 @@ func (m *PlayModel) handleGameResponse(msg database.Game) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m *PlayModel) handleWatchedGame(msg watchedGame) (tea.Model, tea.Cmd) {
+	m.isLoading = false
+	m.err = nil
+
+	game := database.Game(msg)
+
+	userID, err := getUserID()
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	for _, spectator := range game.Spectators {
+		if spectator.User.ID == userID {
+			logger, _ := logger.GetLogger()
+			localID := fmt.Sprintf("%s-s%d", game.Name, spectator.ID)
+			network := multiplayer.NewSpectatorNetwork(localID, spectator.IP, logger)
+
+			// A spectator restores the game from the players' snapshots
+			return m, SwitchModelCmd(NewGameModel(m.width, m.height+1, game.ID, network, true))
+		}
+	}
+
+	m.err = errors.New("can't watch this game")
+	return m, nil
+}
+
 func (m *PlayModel) handleGamesResponse(msg []database.Game) (tea.Model, tea.Cmd) {
 	m.isLoading = false
 	m.games = msg
@@ -177,10 +217,13 @@ func (m *PlayModel) newGameCallback(gameType database.GameType, moveChooseType d
 		ip := p2p.GetOutboundIP().String()
 
 		// Prepare request payload
-		payload, err := json.Marshal(map[string]string{
+		payload, err := json.Marshal(map[string]any{
 			"ip":               fmt.Sprintf("%s:%d", ip, port),
 			"type":             string(gameType),
 			"move_choose_type": string(moveChooseType),
+			"time_control":     timeControlPresets[m.timeControl],
+			"chess960":         m.startPosition == chess960Start,
+			"start_fen":        m.customStartFEN(),
 		})
 		if err != nil {
 			return playResponse{Error: err.Error()}
@@ -214,7 +257,7 @@ func (m *PlayModel) newGameCallback(gameType database.GameType, moveChooseType d
 			return playResponse{Error: fmt.Sprintf("Error decoding JSON: %v", err)}
 		}
 
-		return playResponse{Ok: responseOk{Name: response.Name, Type: response.Type, GameID: response.ID, IP: ip, Port: port}}
+		return playResponse{Ok: responseOk{Name: response.Name, Type: response.Type, MoveChoose: string(moveChooseType), GameID: response.ID, IP: ip, Port: port}}
 	}
 }
 
@@ -270,6 +313,58 @@ func (m PlayModel) enterGame() tea.Cmd {
 	}
 }
 
+func (m PlayModel) watchGame() tea.Cmd {
+	return func() tea.Msg {
+		// Get authorization token
+		authorization, err := getAuthorizationToken()
+		if err != nil {
+			return playResponse{Error: err.Error()}
+		}
+
+		// Set up network connection
+		port, err := p2p.GetRandomAvailablePort()
+		if err != nil {
+			return playResponse{Error: err.Error()}
+		}
+
+		ip := p2p.GetOutboundIP().String()
+
+		// Prepare request payload
+		payload, err := json.Marshal(map[string]string{
+			"name": m.namePrompt.Value(),
+			"ip":   fmt.Sprintf("%s:%d", ip, port),
+		})
+		if err != nil {
+			return playResponse{Error: err.Error()}
+		}
+
+		// Send API request
+		url := os.Getenv("API_BASE") + "/watch-game"
+		resp, err := sendAPIRequest("POST", url, payload, authorization)
+		if err != nil {
+			return playResponse{Error: err.Error()}
+		}
+		defer resp.Body.Close()
+
+		// Handle response
+		if resp.StatusCode != http.StatusOK {
+			var response playResponse
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				return playResponse{Error: fmt.Sprintf("HTTP error: %d, unable to decode body", resp.StatusCode)}
+			}
+			return playResponse{Error: response.Error}
+		}
+
+		// Decode successful response
+		var response database.Game
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return playResponse{Error: fmt.Sprintf("Error decoding JSON: %v", err)}
+		}
+
+		return watchedGame(response)
+	}
+}
+
 func (m *PlayModel) fetchGames() tea.Cmd {
 	return func() tea.Msg {
 		var games []database.Game
@@ -0,0 +1,142 @@
+package views
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/internal/logger"
+	"github.com/boozec/rahanna/pkg/engine"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/uci"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The computer player has been seated in the game
+type botSeatedMsg struct {
+	game    database.Game
+	address string
+}
+
+// Returns the options of the built-in engine. The depth and the time budget
+// of each move can be set with `RAHANNA_ENGINE_DEPTH` and
+// `RAHANNA_ENGINE_MOVETIME`.
+func getEngineOptions() engine.Options {
+	var opts engine.Options
+
+	if depth, err := strconv.Atoi(os.Getenv("RAHANNA_ENGINE_DEPTH")); err == nil && depth > 0 {
+		opts.Depth = depth
+	}
+
+	if moveTime, err := time.ParseDuration(os.Getenv("RAHANNA_ENGINE_MOVETIME")); err == nil && moveTime > 0 {
+		opts.MoveTime = moveTime
+	}
+
+	return opts
+}
+
+// Returns the engine of a computer player: the UCI engine at
+// `RAHANNA_ENGINE_PATH` if set, the built-in one otherwise
+func newBotEngine() (multiplayer.Engine, error) {
+	opts := getEngineOptions()
+
+	path := os.Getenv("RAHANNA_ENGINE_PATH")
+	if path == "" {
+		return engine.New(opts), nil
+	}
+
+	external, err := uci.Start(uci.Options{Path: path, Depth: opts.Depth, MoveTime: opts.MoveTime})
+	if err != nil {
+		return nil, err
+	}
+
+	return external, nil
+}
+
+// Returns true if the computer can take a seat of the game the user is
+// waiting for
+func (m PlayModel) canAddBot() bool {
+	return m.page == StartGamePage && m.network != nil && !m.isLoading && m.moveChooseType != database.ConsultationChooseType && m.gameType != database.BughouseGameType
+}
+
+// Seats the computer in the next free seat of the current game. Its peer runs
+// in this process, on a new port.
+func (m PlayModel) addBotCmd() tea.Cmd {
+	return func() tea.Msg {
+		authorization, err := getAuthorizationToken()
+		if err != nil {
+			return playResponse{Error: err.Error()}
+		}
+
+		port, err := p2p.GetRandomAvailablePort()
+		if err != nil {
+			return playResponse{Error: err.Error()}
+		}
+
+		address := fmt.Sprintf("%s:%d", p2p.GetOutboundIP().String(), port)
+
+		payload, err := json.Marshal(map[string]string{
+			"ip": address,
+		})
+		if err != nil {
+			return playResponse{Error: err.Error()}
+		}
+
+		url := fmt.Sprintf("%s/play/%d/bot", os.Getenv("API_BASE"), m.currentGameId)
+		resp, err := sendAPIRequest("POST", url, payload, authorization)
+		if err != nil {
+			return playResponse{Error: err.Error()}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			var response playResponse
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				return playResponse{Error: fmt.Sprintf("HTTP error: %d, unable to decode body", resp.StatusCode)}
+			}
+			return playResponse{Error: response.Error}
+		}
+
+		var game database.Game
+		if err := json.NewDecoder(resp.Body).Decode(&game); err != nil {
+			return playResponse{Error: fmt.Sprintf("Error decoding JSON: %v", err)}
+		}
+
+		return botSeatedMsg{game: game, address: address}
+	}
+}
+
+// Starts the peer of the computer and connects it to the seats already taken,
+// which are waiting for the other players
+func (m *PlayModel) handleBotSeated(msg botSeatedMsg) (tea.Model, tea.Cmd) {
+	m.isLoading = false
+	m.err = nil
+
+	logger, _ := logger.GetLogger()
+
+	botEngine, err := newBotEngine()
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+
+	seat := msg.game.LastPlayer
+	network := multiplayer.NewGameNetwork(fmt.Sprintf("%s-%d", msg.game.Name, seat), msg.address, p2p.DefaultHandshake, p2p.DefaultHandshake, logger)
+	bot := multiplayer.NewVirtualPeer(network, botEngine)
+
+	ips := []string{msg.game.IP1, msg.game.IP2, msg.game.IP3, msg.game.IP4}
+	for i := 1; i < seat; i++ {
+		if ips[i-1] != "" {
+			bot.Connect(p2p.NetworkID(fmt.Sprintf("%s-%d", msg.game.Name, i)), ips[i-1])
+		}
+	}
+
+	m.bots = append(m.bots, bot)
+
+	return m, nil
+}
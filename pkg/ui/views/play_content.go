@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/boozec/rahanna/pkg/chess960"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -21,6 +22,19 @@ func createNamePrompt(width int) textinput.Model {
 	return namePrompt
 }
 
+// Create and configure the FEN input prompt
+func createFENPrompt(width int) textinput.Model {
+	fenPrompt := textinput.New()
+	fenPrompt.Prompt = " "
+	fenPrompt.TextStyle = inputStyle
+	fenPrompt.Placeholder = chess960.StandardFEN
+	fenPrompt.Focus()
+	fenPrompt.CharLimit = 90
+	fenPrompt.Width = getFormWidth(width)
+
+	return fenPrompt
+}
+
 func (m PlayModel) renderPageContent(base lipgloss.Style) string {
 	switch m.page {
 	case LandingPage:
@@ -36,6 +50,9 @@ func (m PlayModel) renderPageContent(base lipgloss.Style) string {
 	case InsertCodePage:
 		return m.renderInsertCodeContent(base)
 
+	case InsertFENPage:
+		return m.renderInsertFENContent(base)
+
 	case StartGamePage:
 		return m.renderStartGameContent(base)
 	}
@@ -54,10 +71,15 @@ func (m PlayModel) renderInsertCodeContent(base lipgloss.Style) string {
 		)
 	}
 
+	label := "Insert play code:"
+	if m.watching {
+		label = "Insert play code to watch:"
+	}
+
 	// Default: show input prompt
 	return base.Render(
 		lipgloss.JoinVertical(lipgloss.Left,
-			lipgloss.NewStyle().Render("Insert play code:"),
+			lipgloss.NewStyle().Render(label),
 			m.namePrompt.View(),
 			lipgloss.NewStyle().
 				Align(lipgloss.Center).
@@ -69,6 +91,33 @@ func (m PlayModel) renderInsertCodeContent(base lipgloss.Style) string {
 	)
 }
 
+func (m PlayModel) renderInsertFENContent(base lipgloss.Style) string {
+	return base.Render(
+		lipgloss.JoinVertical(lipgloss.Left,
+			lipgloss.NewStyle().Render("Insert the FEN of the starting position:"),
+			m.fenPrompt.View(),
+			lipgloss.NewStyle().
+				Align(lipgloss.Center).
+				PaddingTop(2).
+				Bold(true).
+				Render(fmt.Sprintf("Press %s to confirm",
+					lipgloss.NewStyle().Italic(true).Render("Enter"))),
+		),
+	)
+}
+
+// Returns the starting position of the new games as shown in the help
+func (m PlayModel) startPositionName() string {
+	switch m.startPosition {
+	case chess960Start:
+		return "Chess960"
+	case customStart:
+		return m.startFEN
+	default:
+		return "standard"
+	}
+}
+
 func (m PlayModel) renderStartGameContent(base lipgloss.Style) string {
 	var statusMsg string
 
@@ -81,6 +130,11 @@ func (m PlayModel) renderStartGameContent(base lipgloss.Style) string {
 			Render(m.playName)
 
 		statusMsg = fmt.Sprintf("Share `%s` to your friend", gameCode)
+		if m.vsComputer {
+			statusMsg = "Waiting for the computer..."
+		} else if len(m.bots) > 0 {
+			statusMsg += fmt.Sprintf("\n%d computer player(s) seated", len(m.bots))
+		}
 	}
 
 	return base.Render(statusMsg)
@@ -1,11 +1,12 @@
 package views
 
 import (
-	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/chess960"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -17,15 +18,25 @@ type PlayModelPage int
 const (
 	LandingPage PlayModelPage = iota
 	InsertCodePage
+	InsertFENPage
 	StartGamePage
 )
 
 // Keyboard controls
 type playKeyMap struct {
 	EnterNewGame           key.Binding
+	WatchGame              key.Binding
 	StartNewSingleGame     key.Binding
 	StartNewPairGame       key.Binding
 	StartNewPairRandomGame key.Binding
+	StartNewConsultGame    key.Binding
+	StartNewBughouseGame   key.Binding
+	StartNewComputerGame   key.Binding
+	PlayOffline            key.Binding
+	AddComputer            key.Binding
+	ChangeTimeControl      key.Binding
+	ChangeMoveChoose       key.Binding
+	ChangeStartPosition    key.Binding
 	RestoreGame            key.Binding
 	GoLogout               key.Binding
 	NextPage               key.Binding
@@ -39,6 +50,10 @@ var defaultPlayKeyMap = playKeyMap{
 		key.WithKeys("alt+E", "alt+e"),
 		key.WithHelp("Alt+E", "Enter a play using code"),
 	),
+	WatchGame: key.NewBinding(
+		key.WithKeys("alt+W", "alt+w"),
+		key.WithHelp("Alt+W", "Watch a play using code"),
+	),
 	StartNewSingleGame: key.NewBinding(
 		key.WithKeys("alt+s", "alt+S"),
 		key.WithHelp("Alt+S", "Start a new single play"),
@@ -51,9 +66,41 @@ var defaultPlayKeyMap = playKeyMap{
 		key.WithKeys("alt+r", "alt+R"),
 		key.WithHelp("Alt+R", "Start a new co-op play (random choose)"),
 	),
+	StartNewConsultGame: key.NewBinding(
+		key.WithKeys("alt+c", "alt+C"),
+		key.WithHelp("Alt+C", "Start a new co-op play (consultation)"),
+	),
+	StartNewBughouseGame: key.NewBinding(
+		key.WithKeys("alt+b", "alt+B"),
+		key.WithHelp("Alt+B", "Start a new bughouse play"),
+	),
+	StartNewComputerGame: key.NewBinding(
+		key.WithKeys("alt+v", "alt+V"),
+		key.WithHelp("Alt+V", "Start a new play vs computer"),
+	),
+	PlayOffline: key.NewBinding(
+		key.WithKeys("alt+o", "alt+O"),
+		key.WithHelp("Alt+O", "Play offline"),
+	),
+	AddComputer: key.NewBinding(
+		key.WithKeys("alt+a", "alt+A"),
+		key.WithHelp("Alt+A", "Add a computer player"),
+	),
+	ChangeTimeControl: key.NewBinding(
+		key.WithKeys("alt+t", "alt+T"),
+		key.WithHelp("Alt+T", "Change time control"),
+	),
+	ChangeMoveChoose: key.NewBinding(
+		key.WithKeys("alt+m", "alt+M"),
+		key.WithHelp("Alt+M", "Change co-op move strategy"),
+	),
+	ChangeStartPosition: key.NewBinding(
+		key.WithKeys("alt+f", "alt+F"),
+		key.WithHelp("Alt+F", "Change starting position"),
+	),
 	RestoreGame: key.NewBinding(
 		key.WithKeys("0", "1", "2", "3", "4", "5", "6", "7", "8", "9"),
-		key.WithHelp("[0-9]", "Restore a game"),
+		key.WithHelp("[0-9]", "Restore or review a game"),
 	),
 	GoLogout: key.NewBinding(
 		key.WithKeys("alt+Q", "alt+q"),
@@ -80,6 +127,14 @@ func (m PlayModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case key.Matches(msg, m.keys.EnterNewGame):
 		if m.page == LandingPage {
 			m.page = InsertCodePage
+			m.watching = false
+			return m, cmd
+		}
+
+	case key.Matches(msg, m.keys.WatchGame):
+		if m.page == LandingPage {
+			m.page = InsertCodePage
+			m.watching = true
 			return m, cmd
 		}
 
@@ -98,7 +153,7 @@ func (m PlayModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.page = StartGamePage
 			if !m.isLoading {
 				m.isLoading = true
-				return m, m.newGameCallback(database.PairGameType, database.SequentialChooseType)
+				return m, m.newGameCallback(database.PairGameType, moveChoosePresets[m.moveChoose])
 			}
 
 			return m, cmd
@@ -115,20 +170,96 @@ func (m PlayModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
-	case key.Matches(msg, m.keys.RestoreGame):
+	case key.Matches(msg, m.keys.StartNewConsultGame):
+		if m.page == LandingPage {
+			m.page = StartGamePage
+			if !m.isLoading {
+				m.isLoading = true
+				return m, m.newGameCallback(database.PairGameType, database.ConsultationChooseType)
+			}
+
+			return m, cmd
+		}
+
+	case key.Matches(msg, m.keys.StartNewBughouseGame):
+		if m.page == LandingPage {
+			m.page = StartGamePage
+			if !m.isLoading {
+				m.isLoading = true
+				return m, m.newGameCallback(database.BughouseGameType, database.SequentialChooseType)
+			}
+
+			return m, cmd
+		}
+
+	case key.Matches(msg, m.keys.StartNewComputerGame):
+		if m.page == LandingPage {
+			m.page = StartGamePage
+			if !m.isLoading {
+				m.isLoading = true
+				m.vsComputer = true
+				return m, m.newGameCallback(database.SingleGameType, database.SequentialChooseType)
+			}
+
+			return m, cmd
+		}
+
+	case key.Matches(msg, m.keys.PlayOffline):
+		if m.page == LandingPage {
+			return m, SwitchModelCmd(NewLocalPlayModel(m.width, m.height))
+		}
+
+	case key.Matches(msg, m.keys.AddComputer):
+		if m.canAddBot() {
+			m.isLoading = true
+			return m, m.addBotCmd()
+		}
+
+	case key.Matches(msg, m.keys.ChangeTimeControl):
+		if m.page == LandingPage {
+			m.timeControl = (m.timeControl + 1) % len(timeControlPresets)
+			return m, cmd
+		}
+
+	case key.Matches(msg, m.keys.ChangeMoveChoose):
+		if m.page == LandingPage {
+			m.moveChoose = (m.moveChoose + 1) % len(moveChoosePresets)
+			return m, cmd
+		}
+
+	case key.Matches(msg, m.keys.ChangeStartPosition):
+		// The FEN prompt is left by choosing the next starting position
+		if m.page == LandingPage || m.page == InsertFENPage {
+			m.startPosition = (m.startPosition + 1) % startPositions
+			m.page = LandingPage
+			if m.startPosition == customStart {
+				m.page = InsertFENPage
+				m.fenPrompt.SetValue(m.startFEN)
+			}
+			return m, cmd
+		}
+
+	case key.Matches(msg, m.keys.RestoreGame) && m.page != InsertFENPage:
 		idx, err := strconv.Atoi(msg.String())
 		m.err = err
 		if err == nil {
 			gameIndex := m.paginator.Page*m.paginator.PerPage + idx
 			if gameIndex < len(m.games) {
-				m.gameToRestore = &m.games[gameIndex]
-				if m.gameToRestore.Outcome != chess.NoOutcome.String() {
-					m.err = errors.New("this game is closed")
-				} else {
-					m.err = nil
-					m.namePrompt.SetValue(m.gameToRestore.Name)
-					return m, m.enterGame()
+				// Finished games are reviewed instead, but the moves of
+				// bughouse games are not stored
+				if m.games[gameIndex].Outcome != chess.NoOutcome.String() {
+					if m.games[gameIndex].Type == database.BughouseGameType {
+						m.err = fmt.Errorf("bughouse games can't be reviewed")
+						return m, nil
+					}
+
+					return m, SwitchModelCmd(NewReviewModel(m.width, m.height, m.userID, m.games[gameIndex]))
 				}
+
+				m.gameToRestore = &m.games[gameIndex]
+				m.err = nil
+				m.namePrompt.SetValue(m.gameToRestore.Name)
+				return m, m.enterGame()
 			}
 		}
 
@@ -136,8 +267,24 @@ func (m PlayModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, logout(m.width, m.height+1)
 
 	case msg.Type == tea.KeyEnter:
+		if m.page == InsertFENPage {
+			fen := strings.TrimSpace(m.fenPrompt.Value())
+			if _, err := chess960.NewGame(fen); err != nil {
+				m.err = err
+				return m, cmd
+			}
+
+			m.err = nil
+			m.startFEN = fen
+			m.page = LandingPage
+			return m, cmd
+		}
+
 		if m.page == InsertCodePage && !m.isLoading {
 			m.isLoading = true
+			if m.watching {
+				return m, m.watchGame()
+			}
 			return m, m.enterGame()
 		}
 	}
@@ -149,6 +296,11 @@ func (m PlayModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	}
 
+	if m.page == InsertFENPage {
+		m.fenPrompt, cmd = m.fenPrompt.Update(msg)
+		return m, cmd
+	}
+
 	return m, nil
 }
 
@@ -166,6 +318,15 @@ func (m PlayModel) renderNavigationButtons() string {
 			altCodeStyle.Render(m.keys.EnterNewGame.Help().Key),
 			m.keys.EnterNewGame.Help().Desc)
 
+		watchKey := fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.WatchGame.Help().Key),
+			m.keys.WatchGame.Help().Desc)
+
+		startPositionKey := fmt.Sprintf("%s %s (%s)",
+			altCodeStyle.Render(m.keys.ChangeStartPosition.Help().Key),
+			m.keys.ChangeStartPosition.Help().Desc,
+			m.startPositionName())
+
 		restoreKey := fmt.Sprintf("%s %s",
 			altCodeStyle.Render(m.keys.RestoreGame.Help().Key),
 			m.keys.RestoreGame.Help().Desc)
@@ -182,6 +343,32 @@ func (m PlayModel) renderNavigationButtons() string {
 			altCodeStyle.Render(m.keys.StartNewPairRandomGame.Help().Key),
 			m.keys.StartNewPairRandomGame.Help().Desc)
 
+		startConsultKey := fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.StartNewConsultGame.Help().Key),
+			m.keys.StartNewConsultGame.Help().Desc)
+
+		startBughouseKey := fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.StartNewBughouseGame.Help().Key),
+			m.keys.StartNewBughouseGame.Help().Desc)
+
+		startComputerKey := fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.StartNewComputerGame.Help().Key),
+			m.keys.StartNewComputerGame.Help().Desc)
+
+		offlineKey := fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.PlayOffline.Help().Key),
+			m.keys.PlayOffline.Help().Desc)
+
+		timeControlKey := fmt.Sprintf("%s %s (%s)",
+			altCodeStyle.Render(m.keys.ChangeTimeControl.Help().Key),
+			m.keys.ChangeTimeControl.Help().Desc,
+			timeControlPresets[m.timeControl])
+
+		moveChooseKey := fmt.Sprintf("%s %s (%s)",
+			altCodeStyle.Render(m.keys.ChangeMoveChoose.Help().Key),
+			m.keys.ChangeMoveChoose.Help().Desc,
+			moveChoosePresets[m.moveChoose])
+
 		nextPageKey := fmt.Sprintf("%s %s",
 			altCodeStyle.Render(m.keys.NextPage.Help().Key),
 			m.keys.NextPage.Help().Desc)
@@ -193,9 +380,17 @@ func (m PlayModel) renderNavigationButtons() string {
 		return lipgloss.JoinVertical(
 			lipgloss.Left,
 			enterKey,
+			watchKey,
 			startSingleKey,
 			startPairKey,
 			startPairRandomKey,
+			startConsultKey,
+			startBughouseKey,
+			startComputerKey,
+			offlineKey,
+			timeControlKey,
+			moveChooseKey,
+			startPositionKey,
 			restoreKey,
 			lipgloss.JoinHorizontal(lipgloss.Left, prevPageKey, " | ", nextPageKey),
 			logoutKey,
@@ -203,6 +398,19 @@ func (m PlayModel) renderNavigationButtons() string {
 		)
 	}
 
+	if m.canAddBot() {
+		addComputerKey := fmt.Sprintf("%s %s",
+			altCodeStyle.Render(m.keys.AddComputer.Help().Key),
+			m.keys.AddComputer.Help().Desc)
+
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			addComputerKey,
+			logoutKey,
+			exitKey,
+		)
+	}
+
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		logoutKey,
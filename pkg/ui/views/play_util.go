@@ -101,3 +101,11 @@ func (m PlayModel) buildWindowContent(content string, formWidth int) string {
 		)),
 	)
 }
+
+// Returns the FEN of the custom starting position, empty for the standard one
+func (m PlayModel) customStartFEN() string {
+	if m.startPosition != customStart {
+		return ""
+	}
+	return m.startFEN
+}
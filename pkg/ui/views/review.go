@@ -0,0 +1,377 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/uci"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	"github.com/boozec/rahanna/pkg/ui/storage"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/notnil/chess"
+)
+
+const (
+	// File of the moves of a finished game in the local directory of the game
+	historyFile = "history.json"
+
+	// File where a reviewed game is exported, in the local directory of the game
+	exportFile = "game.pgn"
+)
+
+// Saves the moves of the game once it is over, to review it later
+func (m GameModel) saveHistory() error {
+	snapshot := m.snapshot()
+
+	// A game opened after its end has no moves to save
+	if len(snapshot.Moves) == 0 && len(snapshot.Bughouse) == 0 {
+		return nil
+	}
+
+	return storage.Write(storage.GameDir(m.currentGameID), historyFile, snapshot)
+}
+
+// Returns the moves of a finished game, from the local history if this
+// terminal played it or from the API otherwise
+func loadReviewGame(game database.Game) (*chess960.Game, error) {
+	if game.Type == database.BughouseGameType {
+		return nil, errors.New("bughouse games can't be reviewed")
+	}
+
+	snapshot, err := storage.Read[multiplayer.GameSnapshot](storage.GameDir(game.ID), historyFile)
+	if err == nil {
+		return snapshot.Replay()
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return multiplayer.ReplayMoves(game.StartFEN, game.Moves)
+}
+
+// The evaluations of the positions of the reviewed game
+type reviewAnalysisMsg struct {
+	evaluations []uci.Evaluation
+	err         error
+}
+
+// ReviewModel replays a finished game, one move at a time.
+type ReviewModel struct {
+	// UI dimensions
+	width  int
+	height int
+
+	// UI state
+	err    error
+	notice string
+	keys   reviewKeyMap
+	board  boardModel
+
+	// Game state
+	userID    int
+	game      database.Game
+	chessGame *chess960.Game
+	ply       int
+	flipped   bool
+
+	// Evaluations of the positions, by ply, once the game is analysed
+	evaluations []uci.Evaluation
+	analysing   bool
+}
+
+func NewReviewModel(width, height int, userID int, game database.Game) ReviewModel {
+	m := ReviewModel{
+		width:     width,
+		height:    height,
+		keys:      defaultReviewKeyMap,
+		board:     newBoardModel(),
+		userID:    userID,
+		game:      game,
+		chessGame: chess960.NewStandardGame(),
+	}
+
+	chessGame, err := loadReviewGame(game)
+	if err != nil {
+		m.err = err
+	} else {
+		m.chessGame = chessGame
+	}
+
+	if len(m.chessGame.Moves()) == 0 && m.err == nil {
+		m.notice = "No move has been saved for this game"
+	}
+
+	return m
+}
+
+func (m ReviewModel) Init() tea.Cmd {
+	ClearScreen()
+	return nil
+}
+
+func (m ReviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if exit := handleExit(msg); exit != nil {
+		return m, exit
+	}
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		return m.handleKeyMsg(msg)
+	case reviewAnalysisMsg:
+		m.analysing = false
+		m.evaluations = msg.evaluations
+		m.err = msg.err
+		if msg.err == nil {
+			m.notice = "Analysis completed"
+		}
+	}
+
+	return m, nil
+}
+
+// Analyses every position of the game with the UCI engine at
+// `RAHANNA_ENGINE_PATH`, with the depth and the time budget of the computer
+// players
+func (m ReviewModel) analyseCmd() tea.Cmd {
+	game := m.chessGame
+
+	return func() tea.Msg {
+		path := os.Getenv("RAHANNA_ENGINE_PATH")
+		if path == "" {
+			return reviewAnalysisMsg{err: errors.New("set RAHANNA_ENGINE_PATH to analyse the game")}
+		}
+
+		opts := getEngineOptions()
+		external, err := uci.Start(uci.Options{Path: path, Depth: opts.Depth, MoveTime: opts.MoveTime})
+		if err != nil {
+			return reviewAnalysisMsg{err: err}
+		}
+		defer external.Close()
+
+		evaluations, err := external.Analyse(game)
+		return reviewAnalysisMsg{evaluations: evaluations, err: err}
+	}
+}
+
+// Returns the evaluation of the position shown, from the point of view of
+// white, with the best move in SAN
+func (m ReviewModel) renderEvaluation(shown *chess960.Game) string {
+	if m.analysing {
+		return altCodeStyle.Render("Analysing...")
+	}
+
+	if m.ply >= len(m.evaluations) {
+		return ""
+	}
+
+	evaluation := m.evaluations[m.ply]
+
+	var score string
+	switch {
+	case evaluation.Mate != 0:
+		mate := evaluation.Mate
+		if evaluation.Turn == chess.Black {
+			mate = -mate
+		}
+		score = fmt.Sprintf("#%d", mate)
+	case shown.Outcome() != chess.NoOutcome:
+		score = shown.Outcome().String()
+	default:
+		score = fmt.Sprintf("%+.2f", float64(evaluation.WhiteScore())/100)
+	}
+
+	if move, err := shown.ParseMove(evaluation.BestMove); evaluation.BestMove != "" && err == nil {
+		score += ", best " + shown.SAN(move)
+	}
+
+	return altCodeStyle.Render("Evaluation " + score)
+}
+
+// Moves to the position after `ply` moves, within the game
+func (m ReviewModel) goTo(ply int) ReviewModel {
+	m.ply = min(max(ply, 0), len(m.chessGame.Moves()))
+	return m
+}
+
+// Returns the side the board is seen from: the color of the user, white for
+// the others, unless the board is flipped
+func (m ReviewModel) orientation() chess.Color {
+	color := chess.White
+	for _, player := range []*database.User{m.game.Player2, m.game.Player4} {
+		if player != nil && player.ID == m.userID {
+			color = chess.Black
+		}
+	}
+
+	if m.flipped {
+		return color.Other()
+	}
+	return color
+}
+
+// Returns the players of each color, separated by commas
+func (m ReviewModel) players() (white string, black string) {
+	whites := []string{m.game.Player1.Username}
+	var blacks []string
+	if m.game.Player2 != nil {
+		blacks = append(blacks, m.game.Player2.Username)
+	}
+	if m.game.Player3 != nil {
+		whites = append(whites, m.game.Player3.Username)
+	}
+	if m.game.Player4 != nil {
+		blacks = append(blacks, m.game.Player4.Username)
+	}
+
+	return strings.Join(whites, ", "), strings.Join(blacks, ", ")
+}
+
+// Writes the game in PGN in its local directory
+func (m ReviewModel) export() ReviewModel {
+	white, black := m.players()
+
+	event := "Rahanna"
+	if m.game.Name != "" {
+		event += " " + m.game.Name
+	}
+
+	pgn := m.chessGame.PGN([]chess960.Tag{
+		{Name: "Event", Value: event},
+		{Name: "Site", Value: "Rahanna"},
+		{Name: "Date", Value: m.game.CreatedAt.Format("2006.01.02")},
+		{Name: "Round", Value: "-"},
+		{Name: "White", Value: white},
+		{Name: "Black", Value: black},
+		{Name: "Result", Value: m.game.Outcome},
+		{Name: "Termination", Value: m.game.Method},
+	})
+
+	dir := storage.GameDir(m.game.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		m.err = err
+		return m
+	}
+
+	path := filepath.Join(dir, exportFile)
+	if err := os.WriteFile(path, []byte(pgn), 0644); err != nil {
+		m.err = err
+		return m
+	}
+
+	m.err = nil
+	m.notice = fmt.Sprintf("Exported to %s", path)
+
+	return m
+}
+
+// Renders the result of the game and the players of each color
+func (m ReviewModel) renderResult() string {
+	outcome := "Draw"
+	switch m.game.Outcome {
+	case chess.WhiteWon.String():
+		outcome = "White won"
+	case chess.BlackWon.String():
+		outcome = "Black won"
+	}
+
+	white, black := m.players()
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Background(highlightColor).Foreground(lipgloss.Color("230")).Padding(0, 1).MarginBottom(1).Render("Result"),
+		outcome,
+		m.game.Outcome,
+		altCodeStyle.Render(m.game.Method),
+		"",
+		lipgloss.NewStyle().Background(highlightColor).Foreground(lipgloss.Color("230")).Padding(0, 1).MarginBottom(1).Render("Players"),
+		fmt.Sprintf("♔ White  %s", white),
+		fmt.Sprintf("♚ Black  %s", black),
+	)
+}
+
+// Renders the position after the moves reviewed, with the last of them
+func (m ReviewModel) renderBoard() string {
+	position := m.chessGame.Positions()[m.ply]
+
+	var lastMove *chess.Move
+	if m.ply > 0 {
+		lastMove = m.chessGame.Moves()[m.ply-1]
+	}
+
+	board := m.board.SetPosition(position.Board(), nil, m.orientation())
+	view := board.SetHighlights(lastMove, kingInCheck(position)).View()
+
+	return lipgloss.JoinVertical(lipgloss.Center, view,
+		altCodeStyle.Render(fmt.Sprintf("Move %d of %d", m.ply, len(m.chessGame.Moves()))),
+	)
+}
+
+func (m ReviewModel) View() string {
+	formWidth := getFormWidth(m.width)
+
+	resultWidth := formWidth / 4
+	boardWidth := formWidth / 2
+	notationWidth := formWidth - resultWidth - boardWidth - 2
+
+	height := m.height / 3
+
+	resultStyle := lipgloss.NewStyle().Width(resultWidth).Height(height).Padding(0, 1)
+	boardStyle := lipgloss.NewStyle().Width(boardWidth).Height(height).Align(lipgloss.Center).Padding(0, 1)
+	notationStyle := lipgloss.NewStyle().Width(notationWidth).Height(height).Padding(0, 1)
+
+	// The material is counted in the position shown
+	shown, err := m.chessGame.Rollback(m.ply)
+	if err != nil {
+		shown = m.chessGame
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Center,
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#f1c40f")).Render(fmt.Sprintf("%s (review)", m.game.Name)),
+		lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			resultStyle.Render(m.renderResult()),
+			boardStyle.Render(m.renderBoard()),
+			notationStyle.Render(
+				lipgloss.JoinVertical(
+					lipgloss.Left,
+					lipgloss.NewStyle().Background(highlightColor).Foreground(lipgloss.Color("230")).Padding(0, 1).MarginBottom(1).Render("Moves"),
+					renderNotation(m.chessGame.Notation(), m.ply, true),
+					renderMaterial(shown, m.board),
+					m.renderEvaluation(shown),
+				),
+			),
+		),
+	)
+
+	var errorStr string
+	if m.err != nil {
+		errorStr = m.err.Error()
+	}
+
+	centeredContent := lipgloss.JoinVertical(
+		lipgloss.Center,
+		getLogo(m.width),
+		windowStyle.Width(formWidth).Render(content),
+		altCodeStyle.Render(m.notice),
+		errorStyle.Width(formWidth/2).Render(errorStr),
+		lipgloss.NewStyle().MarginTop(2).Render(m.renderNavigationButtons()),
+	)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		centeredContent,
+	)
+}
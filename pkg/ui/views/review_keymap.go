@@ -0,0 +1,144 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// reviewKeyMap defines the key bindings for the review of a finished game.
+type reviewKeyMap struct {
+	Back    key.Binding
+	Forward key.Binding
+	Start   key.Binding
+	End     key.Binding
+	Flip    key.Binding
+	Theme   key.Binding
+	Export  key.Binding
+	Analyse key.Binding
+	Quit    key.Binding
+	Exit    key.Binding
+}
+
+var defaultReviewKeyMap = reviewKeyMap{
+	Back: key.NewBinding(
+		key.WithKeys("left", "h", "["),
+		key.WithHelp("     ←", "Previous move"),
+	),
+	Forward: key.NewBinding(
+		key.WithKeys("right", "l", "]"),
+		key.WithHelp("     →", "Next move"),
+	),
+	Start: key.NewBinding(
+		key.WithKeys("home", "up", "k"),
+		key.WithHelp("  Home", "Starting position"),
+	),
+	End: key.NewBinding(
+		key.WithKeys("end", "down", "j"),
+		key.WithHelp("   End", "Final position"),
+	),
+	Flip: key.NewBinding(
+		key.WithKeys("F", "f"),
+		key.WithHelp("     F", "Flip the board"),
+	),
+	Theme: key.NewBinding(
+		key.WithKeys("S", "s"),
+		key.WithHelp("     S", "Switch board theme"),
+	),
+	Export: key.NewBinding(
+		key.WithKeys("E", "e"),
+		key.WithHelp("     E", "Export to PGN"),
+	),
+	Analyse: key.NewBinding(
+		key.WithKeys("A", "a"),
+		key.WithHelp("     A", "Analyse with the engine"),
+	),
+	Quit: key.NewBinding(
+		key.WithKeys("Q", "q"),
+		key.WithHelp("     Q", "Quit"),
+	),
+	Exit: key.NewBinding(
+		key.WithKeys("ctrl+c", "ctrl+C"),
+		key.WithHelp("Ctrl+C", "Exit"),
+	),
+}
+
+func (m ReviewModel) handleKeyMsg(msg tea.KeyMsg) (ReviewModel, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back):
+		m = m.goTo(m.ply - 1)
+	case key.Matches(msg, m.keys.Forward):
+		m = m.goTo(m.ply + 1)
+	case key.Matches(msg, m.keys.Start):
+		m = m.goTo(0)
+	case key.Matches(msg, m.keys.End):
+		m = m.goTo(len(m.chessGame.Moves()))
+	case key.Matches(msg, m.keys.Flip):
+		m.flipped = !m.flipped
+	case key.Matches(msg, m.keys.Theme):
+		m.board = m.board.NextTheme()
+	case key.Matches(msg, m.keys.Export):
+		m = m.export()
+	case key.Matches(msg, m.keys.Analyse):
+		if m.analysing || len(m.chessGame.Moves()) == 0 {
+			return m, nil
+		}
+		m.analysing = true
+		m.notice = ""
+		return m, m.analyseCmd()
+	case key.Matches(msg, m.keys.Quit):
+		return m, SwitchModelCmd(NewPlayModel(m.width, m.height))
+	}
+
+	return m, nil
+}
+
+func (m ReviewModel) renderNavigationButtons() string {
+	var moveKeys []string
+	if len(m.chessGame.Moves()) > 0 {
+		moveKeys = append(moveKeys,
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Back.Help().Key), m.keys.Back.Help().Desc),
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Forward.Help().Key), m.keys.Forward.Help().Desc),
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.Start.Help().Key), m.keys.Start.Help().Desc),
+			fmt.Sprintf("%s %s", altCodeStyle.Render(m.keys.End.Help().Key), m.keys.End.Help().Desc),
+		)
+	}
+
+	flipKey := fmt.Sprintf("%s %s",
+		altCodeStyle.Render(m.keys.Flip.Help().Key),
+		m.keys.Flip.Help().Desc)
+
+	themeKey := fmt.Sprintf("%s %s (%s)",
+		altCodeStyle.Render(m.keys.Theme.Help().Key),
+		m.keys.Theme.Help().Desc,
+		m.board.ThemeName())
+
+	exportKey := fmt.Sprintf("%s %s",
+		altCodeStyle.Render(m.keys.Export.Help().Key),
+		m.keys.Export.Help().Desc)
+
+	analyseKey := fmt.Sprintf("%s %s",
+		altCodeStyle.Render(m.keys.Analyse.Help().Key),
+		m.keys.Analyse.Help().Desc)
+
+	quitKey := fmt.Sprintf("%s %s",
+		altCodeStyle.Render(m.keys.Quit.Help().Key),
+		m.keys.Quit.Help().Desc)
+
+	exitKey := fmt.Sprintf("%s %s",
+		altCodeStyle.Render(m.keys.Exit.Help().Key),
+		m.keys.Exit.Help().Desc)
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.JoinVertical(lipgloss.Left, moveKeys...),
+		flipKey,
+		themeKey,
+		exportKey,
+		analyseKey,
+		quitKey,
+		exitKey,
+	)
+}
@@ -0,0 +1,60 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/boozec/rahanna/internal/api/database"
+	"github.com/boozec/rahanna/pkg/chess960"
+	"github.com/boozec/rahanna/pkg/p2p"
+	"github.com/boozec/rahanna/pkg/ui/multiplayer"
+	"github.com/boozec/rahanna/pkg/ui/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoadReviewGameAPI tests that a game played elsewhere is replayed from the
+// moves stored by the API.
+func TestLoadReviewGameAPI(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	game, err := loadReviewGame(database.Game{ID: 1, Moves: "e2e4 e7e5"})
+	assert.NoError(t, err)
+	assert.Len(t, game.Moves(), 2)
+	assert.Equal(t, chess960.StandardFEN, game.StartFEN())
+
+	fen, err := chess960.StartingFEN(0)
+	assert.NoError(t, err)
+	game, err = loadReviewGame(database.Game{ID: 2, StartFEN: fen})
+	assert.NoError(t, err)
+	assert.Equal(t, fen, game.StartFEN())
+
+	_, err = loadReviewGame(database.Game{ID: 3, Moves: "e2e5"})
+	assert.Error(t, err)
+
+	_, err = loadReviewGame(database.Game{ID: 4, Type: database.BughouseGameType})
+	assert.Error(t, err)
+}
+
+// TestLoadReviewGameHistory tests that the local history of a game played on
+// this terminal wins over the moves stored by the API.
+func TestLoadReviewGameHistory(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	played := chess960.NewStandardGame()
+	for _, move := range []string{"d2d4", "d7d5", "c2c4"} {
+		assert.NoError(t, played.MoveStr(move))
+	}
+	snapshot := multiplayer.NewSnapshot(played, p2p.EmptyNetworkID, nil)
+	assert.NoError(t, storage.Write(storage.GameDir(1), historyFile, snapshot))
+
+	game, err := loadReviewGame(database.Game{ID: 1, Moves: "e2e4"})
+	assert.NoError(t, err)
+	assert.Equal(t, played.FEN(), game.FEN())
+	assert.Len(t, game.Moves(), 3)
+
+	// A history which doesn't lead to its position is refused
+	snapshot.FEN = chess960.StandardFEN
+	assert.NoError(t, storage.Write(storage.GameDir(2), historyFile, snapshot))
+
+	_, err = loadReviewGame(database.Game{ID: 2})
+	assert.Error(t, err)
+}
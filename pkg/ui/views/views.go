@@ -95,12 +95,14 @@ type switchModel struct {
 	model tea.Model
 }
 
+// Switches to `model`. The mouse is released first: the models which use it
+// capture it again in their `Init`.
 func SwitchModelCmd(model tea.Model) tea.Cmd {
 	s := switchModel{
 		model: model,
 	}
 
-	return tea.Batch(func() tea.Msg { return s }, s.model.Init())
+	return tea.Sequence(tea.DisableMouse, tea.Batch(func() tea.Msg { return s }, s.model.Init()))
 }
 
 func (m RahannaModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {